@@ -4,25 +4,33 @@ import (
 	"fmt"
 	"github.com/msvens/mimage/metadata"
 	"github.com/spf13/cobra"
+	"os"
 	"strings"
 )
 
 var editCommand = &cobra.Command{
 	Use:   "edit [flags] filename",
 	Short: "edit image metadata",
-	Long:  `Edit title, keywords and rating of your image`,
+	Long:  `Edit title, description, keywords, rating and rights of your image`,
 	Args:  cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		source := args[0]
 		dest, _ := cmd.Flags().GetString("dest")
-		if dest == "" {
-			dest = source
-		}
-		je, err := metadata.NewJpegEditorFile(source)
+		je, err := metadata.NewJpegEditorFileHeader(source)
 		if err != nil {
 			return err
 		}
+		if charset, _ := cmd.Flags().GetString("iptc-charset"); charset != "" {
+			if err = je.Iptc().SetFallbackCharset(charset); err != nil {
+				return err
+			}
+		}
 		changed := false
+		if transcode, _ := cmd.Flags().GetBool("transcode-iptc"); transcode && je.Iptc().Charset() != metadata.IptcCharsetUTF8 {
+			fmt.Printf("transcoding iptc from %s to %s\n", je.Iptc().Charset(), metadata.IptcCharsetUTF8)
+			je.Iptc().SetDirty()
+			changed = true
+		}
 		if cmd.Flags().Lookup("keywords").Changed {
 			newKeywords, _ := cmd.Flags().GetStringSlice("keywords")
 			fmt.Println("setting new keywords: ", strings.Join(newKeywords, ","))
@@ -31,10 +39,45 @@ var editCommand = &cobra.Command{
 			}
 			changed = true
 		}
+		if cmd.Flags().Lookup("add-keyword").Changed {
+			addKeywords, _ := cmd.Flags().GetStringSlice("add-keyword")
+			fmt.Println("adding keywords: ", strings.Join(addKeywords, ","))
+			if err = je.AddKeywords(addKeywords); err != nil {
+				return err
+			}
+			changed = true
+		}
+		if cmd.Flags().Lookup("remove-keyword").Changed {
+			removeKeywords, _ := cmd.Flags().GetStringSlice("remove-keyword")
+			fmt.Println("removing keywords: ", strings.Join(removeKeywords, ","))
+			if err = je.RemoveKeywords(removeKeywords); err != nil {
+				return err
+			}
+			changed = true
+		}
+		if cmd.Flags().Lookup("hierarchical-keywords").Changed {
+			newKeywords, _ := cmd.Flags().GetStringSlice("hierarchical-keywords")
+			flatten, _ := cmd.Flags().GetBool("flatten")
+			fmt.Println("setting new hierarchical keywords: ", strings.Join(newKeywords, ","))
+			if err = je.SetHierarchicalKeywords(newKeywords, flatten); err != nil {
+				return err
+			}
+			changed = true
+		}
 		if cmd.Flags().Lookup("title").Changed {
 			newTitle, _ := cmd.Flags().GetString("title")
 			fmt.Println("setting newTitle", newTitle)
-			if err = je.SetTitle(newTitle); err != nil {
+			lang, _ := cmd.Flags().GetString("lang")
+			if err = je.SetTitleLang(lang, newTitle); err != nil {
+				return err
+			}
+			changed = true
+		}
+		if cmd.Flags().Lookup("description").Changed {
+			newDescription, _ := cmd.Flags().GetString("description")
+			fmt.Println("setting new description", newDescription)
+			lang, _ := cmd.Flags().GetString("lang")
+			if err = je.SetDescriptionLang(lang, newDescription); err != nil {
 				return err
 			}
 			changed = true
@@ -49,22 +92,109 @@ var editCommand = &cobra.Command{
 			changed = true
 
 		}
-		if changed {
-			fmt.Println("Writing changes to ", dest)
-			err = je.WriteFile(dest)
-			if err != nil {
+		if cmd.Flags().Lookup("copyright").Changed || cmd.Flags().Lookup("creator").Changed {
+			rights := metadata.Rights{}
+			rights.Copyright, _ = cmd.Flags().GetString("copyright")
+			rights.Creator, _ = cmd.Flags().GetStringSlice("creator")
+			fmt.Println("setting new rights: ", rights.Copyright, strings.Join(rights.Creator, ","))
+			if err = je.SetRights(rights); err != nil {
 				return err
 			}
+			changed = true
+		}
+		if cmd.Flags().Lookup("xmp").Changed {
+			namespaces, _ := cmd.Flags().GetStringArray("xmp-namespace")
+			for _, ns := range namespaces {
+				prefix, uri, found := strings.Cut(ns, "=")
+				if !found {
+					return fmt.Errorf("xmp namespace should be prefix=uri: %s", ns)
+				}
+				if err = metadata.RegisterXmpNamespace(prefix, uri); err != nil {
+					return err
+				}
+			}
+			values, _ := cmd.Flags().GetStringArray("xmp")
+			for _, v := range values {
+				path, value, found := strings.Cut(v, "=")
+				if !found {
+					return fmt.Errorf("xmp value should be path=value: %s", v)
+				}
+				fmt.Println("setting xmp ", path, value)
+				if err = je.Xmp().Set(path, value); err != nil {
+					return err
+				}
+			}
+			changed = true
+		}
+		if changed {
+			return writeEditor(je, source, dest, cmd)
 		}
 		return nil
 
 	},
 }
 
+// writeEditor writes je to dest or, if dest is not set, updates the source image in place. With the backup flag set
+// the file being replaced is first kept as file_original
+func writeEditor(je *metadata.JpegEditor, source, dest string, cmd *cobra.Command) error {
+	target := dest
+	if target == "" {
+		target = source
+	}
+	opts := metadata.WriterOptions{}
+	opts.Agent, _ = cmd.Flags().GetString("agent")
+	opts.KeepSoftware, _ = cmd.Flags().GetBool("keep-software")
+	opts.MaxHistory, _ = cmd.Flags().GetInt("max-history")
+	opts.CoalesceHistory, _ = cmd.Flags().GetBool("coalesce-history")
+	je.SetWriterOptions(opts)
+	if backup, _ := cmd.Flags().GetBool("backup"); backup {
+		if _, err := os.Stat(target); err == nil {
+			fmt.Println("Keeping backup ", metadata.BackupFileName(target))
+			if err = metadata.BackupFile(target); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Println("Writing changes to ", target)
+	if target != source {
+		return je.WriteFile(target)
+	}
+	keepModTime, _ := cmd.Flags().GetBool("keep-mtime")
+	return je.UpdateFile(keepModTime)
+}
+
+// addWriteFlags adds the flags used by writeEditor including the destination file
+func addWriteFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("dest", "d", "", "destination file. If not set the source image will be modified")
+	addUpdateFlags(cmd)
+}
+
+// addUpdateFlags adds the flags used by writeEditor for commands that always modify the source image
+func addUpdateFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("keep-mtime", false, "keep the modification time when the source image is modified")
+	cmd.Flags().BoolP("backup", "b", false, "keep the replaced image as <file>"+metadata.BackupSuffix)
+	cmd.Flags().String("agent", "", "software name written to Exif Software and xmp:CreatorTool")
+	cmd.Flags().Bool("keep-software", false, "keep the existing Exif Software and xmp:CreatorTool")
+	cmd.Flags().Int("max-history", 0, "max number of xmp history events (0 unlimited, -1 no new events)")
+	cmd.Flags().Bool("coalesce-history", false, "update the last xmp history event instead of adding a new one if it was saved by the same agent")
+}
+
 func init() {
 	rootCmd.AddCommand(editCommand)
-	editCommand.Flags().StringP("dest", "d", "", "destination file. If not set the source image will be modified")
+	addWriteFlags(editCommand)
 	editCommand.Flags().StringSliceP("keywords", "k", nil, "--keywords=\"k1,k2\"")
-	editCommand.Flags().StringP("title", "t", "", "image title/description")
+	editCommand.Flags().StringSlice("add-keyword", nil, "keyword to add to the existing keywords (can be repeated)")
+	editCommand.Flags().StringSlice("remove-keyword", nil, "keyword to remove from the existing keywords (can be repeated)")
+	editCommand.Flags().StringSlice("hierarchical-keywords", nil, "--hierarchical-keywords=\"Places|Europe|Sweden,People|Anna\"")
+	editCommand.Flags().Bool("flatten", true, "add all levels of the hierarchical keywords to the flat keywords")
+	editCommand.Flags().StringP("title", "t", "", "image title")
+	editCommand.Flags().String("description", "", "image description/caption")
+	editCommand.Flags().String("lang", "", "language (BCP 47, e.g. sv or en-GB) of title and description. Other languages than the default are only written to xmp")
 	editCommand.Flags().Uint16P("rating", "r", 0, "rating (1-5)")
+	editCommand.Flags().String("copyright", "", "copyright notice")
+	editCommand.Flags().StringSlice("creator", nil, "--creator=\"c1,c2\"")
+	editCommand.Flags().StringArray("xmp", nil, "set an xmp property, e.g. --xmp \"dc:title[x-default]=My title\" (can be repeated)")
+	editCommand.Flags().String("iptc-charset", "", "character set of legacy iptc without a CodedCharacterSet (default ISO-8859-1 or "+metadata.DefaultIptcCharset+" if detected)")
+	editCommand.Flags().Bool("transcode-iptc", false, "rewrite legacy iptc as UTF-8 even if it is not edited")
+	editCommand.Flags().StringArray("xmp-namespace", nil, "register a custom xmp namespace used by --xmp, e.g. --xmp-namespace \"wf=http://example.com/wf/1.0/\"")
 }
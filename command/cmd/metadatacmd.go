@@ -15,14 +15,22 @@ var metadataCommand = &cobra.Command{
 		if len(args) < 1 {
 			return fmt.Errorf("No file specified")
 		}
-		md, err := metadata.NewMetaDataFromFile(args[0])
+		sidecar, _ := cmd.Flags().GetBool("sidecar")
+		lenient, _ := cmd.Flags().GetBool("lenient")
+		iptcCharset, _ := cmd.Flags().GetString("iptc-charset")
+		md, err := metadata.NewMetaDataFromFileOpts(args[0], metadata.ReadOptions{Sidecar: sidecar, Lenient: lenient, IptcCharset: iptcCharset})
 		if err != nil {
 			return err
 		}
+		for _, e := range md.ParseErrors() {
+			fmt.Printf("Skipped %v\n", e)
+		}
 		summary, _ := cmd.Flags().GetBool("summary")
 		exif, _ := cmd.Flags().GetBool("exif")
 		xmp, _ := cmd.Flags().GetBool("xmp")
 		iptc, _ := cmd.Flags().GetBool("iptc")
+		conflicts, _ := cmd.Flags().GetBool("conflicts")
+		makerNote, _ := cmd.Flags().GetBool("makernote")
 		json, _ := cmd.Flags().GetBool("json")
 		if json {
 			fmt.Println("output as json...not yet implemented")
@@ -39,6 +47,19 @@ var metadataCommand = &cobra.Command{
 			if xmp {
 				fmt.Println(md.Xmp().String())
 			}
+			if makerNote {
+				if mn, err := md.Exif().MakerNote(); err == nil {
+					fmt.Println(mn)
+				} else {
+					fmt.Printf("MakerNote: %v\n", err)
+				}
+			}
+			if conflicts {
+				fmt.Printf("Iptc digest: %v\n", md.IptcDigestState())
+				for _, c := range md.Conflicts() {
+					fmt.Println(c)
+				}
+			}
 		}
 		return nil
 	},
@@ -52,7 +73,12 @@ func init() {
 	metadataCommand.Flags().BoolP("exif", "e", false, "Extract Exif data")
 	metadataCommand.Flags().BoolP("xmp", "x", false, "Extract Xmp data")
 	metadataCommand.Flags().BoolP("iptc", "i", false, "Extract Iptc data")
+	metadataCommand.Flags().BoolP("makernote", "m", false, "Extract the camera maker note")
+	metadataCommand.Flags().BoolP("conflicts", "c", false, "List fields where exif, iptc and xmp disagree")
 	metadataCommand.Flags().BoolP("json", "j", false, "Output as Json")
+	metadataCommand.Flags().Bool("sidecar", false, "Merge the xmp sidecar file (if any)")
+	metadataCommand.Flags().String("iptc-charset", "", "character set of legacy iptc without a CodedCharacterSet (default ISO-8859-1 or "+metadata.DefaultIptcCharset+" if detected)")
+	metadataCommand.Flags().Bool("lenient", false, "Skip corrupt segments, exif ifds and tags instead of failing")
 	//rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 
 }
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/msvens/mimage/metadata"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var repairCommand = &cobra.Command{
+	Use:   "repair [flags] filename...",
+	Short: "Repair corrupt image metadata",
+	Long: `Rewrite images keeping only the metadata that can be read. Broken segments, duplicate exif/xmp segments
+and unreadable exif tags are removed and exif ifd offset loops are fixed. Images without problems are left untouched`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dest, _ := cmd.Flags().GetString("dest")
+		if dest != "" && len(args) > 1 {
+			return fmt.Errorf("--dest can only be used with a single file")
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		backup, _ := cmd.Flags().GetBool("backup")
+		for _, fileName := range args {
+			data, err := os.ReadFile(fileName)
+			if err != nil {
+				return err
+			}
+			_, errs, err := metadata.Repair(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", fileName, err)
+			}
+			if len(errs) == 0 {
+				fmt.Printf("%s: nothing to repair\n", fileName)
+				continue
+			}
+			for _, e := range errs {
+				fmt.Printf("%s: %v\n", fileName, e)
+			}
+			if dryRun {
+				continue
+			}
+			target := dest
+			if target == "" {
+				target = fileName
+			}
+			if backup {
+				if _, err = os.Stat(target); err == nil {
+					fmt.Println("Keeping backup ", metadata.BackupFileName(target))
+					if err = metadata.BackupFile(target); err != nil {
+						return err
+					}
+				}
+			}
+			if _, err = metadata.RepairFile(fileName, target); err != nil {
+				return fmt.Errorf("%s: %w", fileName, err)
+			}
+			fmt.Println("Repaired ", target)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(repairCommand)
+	repairCommand.Flags().StringP("dest", "d", "", "destination file. If not set the source image will be modified")
+	repairCommand.Flags().BoolP("backup", "b", false, "keep the replaced image as <file>"+metadata.BackupSuffix)
+	repairCommand.Flags().BoolP("dry-run", "n", false, "only list the problems that would be repaired")
+}
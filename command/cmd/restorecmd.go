@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/msvens/mimage/metadata"
+	"github.com/spf13/cobra"
+)
+
+var restoreCommand = &cobra.Command{
+	Use:   "restore filename...",
+	Short: "restore images from backup",
+	Long:  `Replace images with the backup (<file>` + metadata.BackupSuffix + `) kept by edit and shift-time --backup`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, fileName := range args {
+			if err := metadata.RestoreFile(fileName); err != nil {
+				return fmt.Errorf("%s: %w", fileName, err)
+			}
+			fmt.Println("Restored ", fileName)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCommand)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/msvens/mimage/metadata"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var shiftTimeCommand = &cobra.Command{
+	Use:   "shift-time [flags] filename...",
+	Short: "shift image dates",
+	Long: `Shift all capture and modify dates (exif, iptc and xmp) of your images by an offset and
+optionally move them to a new time zone, e.g. --offset=7h --zone=Asia/Tokyo`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		offsetStr, _ := cmd.Flags().GetString("offset")
+		zoneStr, _ := cmd.Flags().GetString("zone")
+		dest, _ := cmd.Flags().GetString("dest")
+		if dest != "" && len(args) > 1 {
+			return fmt.Errorf("dest can only be used with a single file")
+		}
+		offset := time.Duration(0)
+		if offsetStr != "" {
+			var err error
+			if offset, err = time.ParseDuration(offsetStr); err != nil {
+				return err
+			}
+		}
+		var zone *time.Location
+		if zoneStr != "" {
+			var err error
+			if zone, err = time.LoadLocation(zoneStr); err != nil {
+				return err
+			}
+		}
+		if offset == 0 && zone == nil {
+			return fmt.Errorf("Either offset or zone has to be specified")
+		}
+		for _, source := range args {
+			je, err := metadata.NewJpegEditorFileHeader(source)
+			if err != nil {
+				return err
+			}
+			if err = je.ShiftDates(offset, zone); err != nil {
+				return err
+			}
+			if err = writeEditor(je, source, dest, cmd); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(shiftTimeCommand)
+	shiftTimeCommand.Flags().StringP("offset", "o", "", "offset to add to all dates, e.g. 7h or -1h30m")
+	shiftTimeCommand.Flags().StringP("zone", "z", "", "new time zone (IANA name), e.g. Asia/Tokyo")
+	addWriteFlags(shiftTimeCommand)
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/msvens/mimage/metadata"
+	"github.com/spf13/cobra"
+)
+
+var sidecarCommand = &cobra.Command{
+	Use:   "sidecar",
+	Short: "work with xmp sidecar files",
+	Long:  `Work with xmp sidecar files (IMG_1234.xmp or IMG_1234.jpg.xmp) stored next to your images`,
+}
+
+var sidecarSyncCommand = &cobra.Command{
+	Use:   "sync [flags] filename...",
+	Short: "sync embedded xmp with sidecar files",
+	Long: `Copy the embedded xmp of your images to their sidecar files. An existing sidecar is replaced, otherwise
+IMG_1234.xmp is created. With --to-image the sidecar xmp is instead copied into the image`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		toImage, _ := cmd.Flags().GetBool("to-image")
+		for _, source := range args {
+			var err error
+			if toImage {
+				err = sidecarToImage(source, cmd)
+			} else {
+				err = imageToSidecar(source)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", source, err)
+			}
+		}
+		return nil
+	},
+}
+
+func imageToSidecar(source string) error {
+	je, err := metadata.NewJpegEditorFileHeader(source)
+	if err != nil {
+		return err
+	}
+	sidecar, err := metadata.FindSidecar(source)
+	if err == metadata.ErrNoSidecar {
+		sidecar = metadata.SidecarFileNames(source)[0]
+	} else if err != nil {
+		return err
+	}
+	fmt.Println("Writing xmp to ", sidecar)
+	return je.Xmp().WriteSidecar(sidecar)
+}
+
+func sidecarToImage(source string, cmd *cobra.Command) error {
+	sidecar, err := metadata.FindSidecar(source)
+	if err != nil {
+		return err
+	}
+	xe, err := metadata.NewXmpEditorFromFile(sidecar)
+	if err != nil {
+		return err
+	}
+	je, err := metadata.NewJpegEditorFileHeader(source)
+	if err != nil {
+		return err
+	}
+	je.Xmp().SetDocument(xe.Document(), true)
+	return writeEditor(je, source, "", cmd)
+}
+
+func init() {
+	rootCmd.AddCommand(sidecarCommand)
+	sidecarCommand.AddCommand(sidecarSyncCommand)
+	sidecarSyncCommand.Flags().Bool("to-image", false, "copy the sidecar xmp into the image")
+	addUpdateFlags(sidecarSyncCommand)
+}
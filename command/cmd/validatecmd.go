@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/msvens/mimage/metadata"
+	"github.com/spf13/cobra"
+)
+
+var validateCommand = &cobra.Command{
+	Use:     "validate [flags] filename...",
+	Aliases: []string{"lint"},
+	Short:   "Validate image metadata",
+	Long: `Check the jpeg structure, exif, iptc and xmp of the provided images and list all issues found.
+Exits with an error if any issue is at or above the --fail-on severity`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		failOn, err := severityFlag(cmd, "fail-on")
+		if err != nil {
+			return err
+		}
+		minSeverity, err := severityFlag(cmd, "min-severity")
+		if err != nil {
+			return err
+		}
+		asJson, _ := cmd.Flags().GetBool("json")
+		result := map[string][]metadata.Issue{}
+		failed := 0
+		for _, fileName := range args {
+			issues, err := metadata.ValidateFile(fileName)
+			if err != nil {
+				return fmt.Errorf("%s: %w", fileName, err)
+			}
+			if metadata.MaxSeverity(issues) >= failOn {
+				failed++
+			}
+			filtered := []metadata.Issue{}
+			for _, i := range issues {
+				if i.Severity >= minSeverity {
+					filtered = append(filtered, i)
+				}
+			}
+			if asJson {
+				result[fileName] = filtered
+				continue
+			}
+			for _, i := range filtered {
+				fmt.Printf("%s: %v\n", fileName, i)
+			}
+		}
+		if asJson {
+			b, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(b))
+		}
+		if failed > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d of %d files failed validation", failed, len(args))
+		}
+		return nil
+	},
+}
+
+func severityFlag(cmd *cobra.Command, name string) (metadata.Severity, error) {
+	s, _ := cmd.Flags().GetString(name)
+	return metadata.ParseSeverity(s)
+}
+
+func init() {
+	rootCmd.AddCommand(validateCommand)
+
+	validateCommand.Flags().String("fail-on", "error", "Fail if any issue has this severity or higher (info, warning, error)")
+	validateCommand.Flags().String("min-severity", "info", "Only list issues with this severity or higher")
+	validateCommand.Flags().BoolP("json", "j", false, "Output as Json")
+}
@@ -8,6 +8,7 @@ github.com/dsoprea/go-exif/v3 v3.0.0-20210428042052-dca55bf8ca15
 github.com/dsoprea/go-iptc v0.0.0-20200609062250-162ae6b44feb
 github.com/dsoprea/go-jpeg-image-structure/v2 v2.0.0-20210512043942-b434301c6836
 golang.org/x/net v0.0.0-20210813160813-60bc85c4be6d
+golang.org/x/text v0.13.0
 trimmer.io/go-xmp v0.0.0-20200923092433-f9b6ca6c4a87
 )
 
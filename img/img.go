@@ -91,6 +91,8 @@ type Options struct {
 	Y         int
 	Angle     int
 	CopyExif  bool
+	//FocusRegion is the name (or type, e.g. "Face") of an image region to use as crop center
+	FocusRegion string
 }
 
 func resampleFiler(strategy ResampleStrategy) imaging.ResampleFilter {
@@ -193,7 +195,7 @@ func openForExifCopy(sourceFile string) (image.Image, []byte, error) {
 	return srcImg, srcBytes, nil
 }
 
-func saveWithExif(srcBytes []byte, dstImage image.Image, opt Options, fileName string) error {
+func saveWithExif(srcBytes []byte, dstImage image.Image, opt Options, fileName string, regions *metadata.RegionInfo) error {
 	dstBytes := new(bytes.Buffer)
 	err := imaging.Encode(dstBytes, dstImage, imaging.JPEG, imaging.JPEGQuality(opt.Quality))
 	if err != nil {
@@ -211,9 +213,38 @@ func saveWithExif(srcBytes []byte, dstImage image.Image, opt Options, fileName s
 	if err != nil {
 		return err
 	}
+	if regions != nil {
+		if err = mde.SetRegions(*regions); err != nil {
+			return err
+		}
+	}
 	return mde.WriteFile(fileName)
 }
 
+// sourceRegions returns the image regions found in the jpeg srcBytes
+func sourceRegions(srcBytes []byte) metadata.RegionInfo {
+	je, err := metadata.NewJpegEditor(srcBytes)
+	if err != nil {
+		return metadata.RegionInfo{}
+	}
+	return je.Xmp().GetRegions()
+}
+
+// destRegions adjusts regions from src to a crop and the dimensions of dst. Returns nil if
+// there are no regions to adjust
+func destRegions(regions metadata.RegionInfo, src image.Image, crop image.Rectangle, dst image.Image) *metadata.RegionInfo {
+	if regions.IsEmpty() {
+		return nil
+	}
+	ret := regions
+	if !crop.Empty() {
+		ret = regions.Crop(src.Bounds().Dx(), src.Bounds().Dy(), crop.Sub(src.Bounds().Min))
+	}
+	ret.Width = uint(dst.Bounds().Dx())
+	ret.Height = uint(dst.Bounds().Dy())
+	return &ret
+}
+
 // Save an image (defaults to Jpeg Quality 90)
 func Save(image image.Image, fileName string) error {
 	return SaveOpts(image, fileName, 90, nil)
@@ -229,24 +260,7 @@ func SaveOpts(image image.Image, fileName string, quality int, srcExif []byte) e
 		return imaging.Save(image, fileName, imaging.JPEGQuality(quality))
 	}
 	//we will add exif information
-	dstBytes := new(bytes.Buffer)
-	err := imaging.Encode(dstBytes, image, imaging.JPEG, imaging.JPEGQuality(quality))
-	if err != nil {
-		return err
-	}
-	mde, err := metadata.NewJpegEditor(dstBytes.Bytes())
-	if err != nil {
-		return err
-	}
-	err = mde.CopyMetaData(srcExif)
-	if err != nil {
-		return err
-	}
-	err = mde.Exif().SetDate(metadata.ModifyDate, time.Now())
-	if err != nil {
-		return err
-	}
-	return mde.WriteFile(fileName)
+	return saveWithExif(srcExif, image, Options{Quality: quality}, fileName, nil)
 }
 
 // CropImage crops an Image.
@@ -287,10 +301,27 @@ func RotateAndCropFile(source string, dest string, opts Options) error {
 		return err
 	}
 
-	srcImg = RotateImage(srcImg, angle)
-	srcImg = CropImage(srcImg, crop)
+	rotated := RotateImage(srcImg, angle)
+	dstImg := CropImage(rotated, crop)
 
-	return SaveOpts(srcImg, dest, opts.Quality, srcBytes)
+	if srcBytes == nil {
+		return SaveOpts(dstImg, dest, opts.Quality, nil)
+	}
+	quality := opts.Quality
+	if quality < 1 || quality > 100 {
+		quality = 90
+	}
+	var regions *metadata.RegionInfo
+	if srcRegions := sourceRegions(srcBytes); !srcRegions.IsEmpty() {
+		//the crop is applied to the rotated image
+		srcRegions = srcRegions.Rotate(srcImg.Bounds().Dx(), srcImg.Bounds().Dy(), angle)
+		if crop.Empty() || !crop.In(rotated.Bounds()) {
+			regions = destRegions(srcRegions, rotated, image.Rectangle{}, dstImg)
+		} else {
+			regions = destRegions(srcRegions, rotated, crop, dstImg)
+		}
+	}
+	return saveWithExif(srcBytes, dstImg, Options{Quality: quality}, dest, regions)
 
 	/*	angle := opts.Angle
 		crop := opts.Rectangle()
@@ -345,13 +376,17 @@ func TransformFile(source string, destinations map[string]Options) error {
 	if err != nil {
 		return err
 	}
+	var regions metadata.RegionInfo
+	if sourceJpeg {
+		regions = sourceRegions(srcBytes)
+	}
 	for dest, options := range destinations {
-		destImg := transform(srcImg, options)
+		destImg, crop := transform(srcImg, options, regions)
 		destJpg := isJpegFile(dest)
 		if !destJpg {
 			err = imaging.Save(destImg, dest)
 		} else if sourceJpeg && options.CopyExif {
-			err = saveWithExif(srcBytes, destImg, options, dest)
+			err = saveWithExif(srcBytes, destImg, options, dest, destRegions(regions, srcImg, crop, destImg))
 		} else {
 			err = imaging.Save(destImg, dest, imaging.JPEGQuality(options.Quality))
 		}
@@ -404,20 +439,100 @@ func transformPngFile(source string, destinations map[string]Options) error {
 }
 */
 
-func transform(src image.Image, opt Options) image.Image {
+// anchorRect returns a rectangle of size w*h placed in bounds according to the anchor (same as imaging)
+func anchorRect(bounds image.Rectangle, w, h int, ca CropAnchor) image.Rectangle {
+	var x, y int
+	switch ca {
+	case TopLeft:
+		x, y = bounds.Min.X, bounds.Min.Y
+	case Top:
+		x, y = bounds.Min.X+(bounds.Dx()-w)/2, bounds.Min.Y
+	case TopRight:
+		x, y = bounds.Max.X-w, bounds.Min.Y
+	case Left:
+		x, y = bounds.Min.X, bounds.Min.Y+(bounds.Dy()-h)/2
+	case Right:
+		x, y = bounds.Max.X-w, bounds.Min.Y+(bounds.Dy()-h)/2
+	case BottomLeft:
+		x, y = bounds.Min.X, bounds.Max.Y-h
+	case Bottom:
+		x, y = bounds.Min.X+(bounds.Dx()-w)/2, bounds.Max.Y-h
+	case BottomRight:
+		x, y = bounds.Max.X-w, bounds.Max.Y-h
+	default:
+		x, y = bounds.Min.X+(bounds.Dx()-w)/2, bounds.Min.Y+(bounds.Dy()-h)/2
+	}
+	return image.Rect(x, y, x+w, y+h).Intersect(bounds)
+}
+
+// focusRect returns a rectangle of size w*h centered (as far as possible) on center and kept within bounds
+func focusRect(bounds image.Rectangle, w, h int, center image.Point) image.Rectangle {
+	x := center.X - w/2
+	y := center.Y - h/2
+	if x+w > bounds.Max.X {
+		x = bounds.Max.X - w
+	}
+	if y+h > bounds.Max.Y {
+		y = bounds.Max.Y - h
+	}
+	if x < bounds.Min.X {
+		x = bounds.Min.X
+	}
+	if y < bounds.Min.Y {
+		y = bounds.Min.Y
+	}
+	return image.Rect(x, y, x+w, y+h).Intersect(bounds)
+}
+
+// fillRect returns the largest rectangle with the aspect ratio w/h that fits in bounds
+func fillRect(bounds image.Rectangle, w, h int) (int, int) {
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if w <= 0 || h <= 0 {
+		return srcW, srcH
+	}
+	if srcW*h > srcH*w {
+		return int(float64(srcH) * float64(w) / float64(h)), srcH
+	}
+	return srcW, int(float64(srcW) * float64(h) / float64(w))
+}
+
+// cropRect returns the part of src to crop to a w*h rectangle, either centered on the focus region or
+// according to the anchor
+func cropRect(src image.Image, w, h int, opt Options, regions metadata.RegionInfo) image.Rectangle {
+	bounds := src.Bounds()
+	if opt.FocusRegion != "" {
+		if r, found := regions.Find(opt.FocusRegion); found {
+			center := r.Area.Center(bounds.Dx(), bounds.Dy()).Add(bounds.Min)
+			return focusRect(bounds, w, h, center)
+		}
+	}
+	return anchorRect(bounds, w, h, opt.Anchor)
+}
+
+// transform src according to opt. Returns the transformed image and, if src was cropped, the crop
+// in src coordinates
+func transform(src image.Image, opt Options, regions metadata.RegionInfo) (image.Image, image.Rectangle) {
 	var dstImage image.Image
+	var crop image.Rectangle
 
 	a := anchor(opt.Anchor)
 	rf := resampleFiler(opt.Strategy)
 	switch opt.Transform {
 	case ResizeAndCrop:
-		dstImage = imaging.Fill(src, opt.Width, opt.Height, a, rf)
+		w, h := fillRect(src.Bounds(), opt.Width, opt.Height)
+		crop = cropRect(src, w, h, opt, regions)
+		if opt.FocusRegion == "" {
+			dstImage = imaging.Fill(src, opt.Width, opt.Height, a, rf)
+		} else {
+			dstImage = imaging.Resize(imaging.Crop(src, crop), opt.Width, opt.Height, rf)
+		}
 	case Crop:
-		dstImage = imaging.CropAnchor(src, opt.Width, opt.Height, a)
+		crop = cropRect(src, opt.Width, opt.Height, opt, regions)
+		dstImage = imaging.Crop(src, crop)
 	case Resize:
 		dstImage = imaging.Resize(src, opt.Width, opt.Height, rf)
 	case ResizeAndFit:
 		dstImage = imaging.Fit(src, opt.Width, opt.Height, rf)
 	}
-	return dstImage
+	return dstImage, crop
 }
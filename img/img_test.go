@@ -2,6 +2,7 @@ package img
 
 import (
 	"fmt"
+	"image"
 	"os"
 	"path"
 	"testing"
@@ -15,6 +16,44 @@ func TestTransformFile(t *testing.T) {
 
 }
 
+func TestAnchorRect(t *testing.T) {
+	bounds := image.Rect(0, 0, 100, 50)
+	tests := map[CropAnchor]image.Rectangle{
+		Center:      image.Rect(40, 15, 60, 35),
+		TopLeft:     image.Rect(0, 0, 20, 20),
+		BottomRight: image.Rect(80, 30, 100, 50),
+	}
+	for a, exp := range tests {
+		if act := anchorRect(bounds, 20, 20, a); act != exp {
+			t.Errorf("Expected %v got %v", exp, act)
+		}
+	}
+}
+
+func TestFocusRect(t *testing.T) {
+	bounds := image.Rect(0, 0, 100, 50)
+	tests := map[image.Point]image.Rectangle{
+		image.Pt(50, 25): image.Rect(40, 15, 60, 35),
+		image.Pt(5, 5):   image.Rect(0, 0, 20, 20),
+		image.Pt(99, 49): image.Rect(80, 30, 100, 50),
+	}
+	for c, exp := range tests {
+		if act := focusRect(bounds, 20, 20, c); act != exp {
+			t.Errorf("Expected %v got %v", exp, act)
+		}
+	}
+}
+
+func TestFillRect(t *testing.T) {
+	bounds := image.Rect(0, 0, 400, 200)
+	if w, h := fillRect(bounds, 100, 100); w != 200 || h != 200 {
+		t.Errorf("Expected 200x200 got %vx%v", w, h)
+	}
+	if w, h := fillRect(bounds, 400, 100); w != 400 || h != 100 {
+		t.Errorf("Expected 400x100 got %vx%v", w, h)
+	}
+}
+
 func ExampleTransformFile() {
 	sourceImg := "../assets/leica.jpg"
 	homeDir, _ := os.UserHomeDir()
@@ -0,0 +1,91 @@
+package makernote
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+Canon maker notes, see https://exiftool.org/TagNames/Canon.html
+*/
+
+const (
+	canonCameraSettings       uint16 = 0x0001
+	canonSerialNumber         uint16 = 0x000c
+	canonAFInfo2              uint16 = 0x0026
+	canonLensModel            uint16 = 0x0095
+	canonInternalSerialNumber uint16 = 0x0096
+)
+
+// indices in CameraSettings
+const (
+	canonSettingsFocusMode = 7
+	canonSettingsLensType  = 22
+)
+
+var canonFocusModes = map[int32]string{
+	0:   "One-shot AF",
+	1:   "AI Servo AF",
+	2:   "AI Focus AF",
+	3:   "Manual Focus (3)",
+	4:   "Single",
+	5:   "Continuous",
+	6:   "Manual Focus (6)",
+	16:  "Pan Focus",
+	256: "One-shot AF (Live View)",
+	257: "AI Servo AF (Live View)",
+	258: "AI Focus AF (Live View)",
+	512: "Movie Snap Focus",
+	519: "Movie Servo AF",
+}
+
+// parseCanon parses a Canon maker note. It has no header and offsets are relative to the exif tiff header
+func parseCanon(mn *MakerNote, tiff []byte, noteOffset int) error {
+	if err := mn.readNote(ifdReader{data: tiff, order: mn.ByteOrder}, noteOffset); err != nil {
+		return err
+	}
+	mn.LensModel = mn.str(canonLensModel)
+	if sn, found := mn.uint(canonSerialNumber); found {
+		mn.SerialNumber = fmt.Sprintf("%010d", sn)
+	} else {
+		mn.SerialNumber = mn.str(canonInternalSerialNumber)
+	}
+	if e, found := mn.Tags[canonCameraSettings]; found {
+		if v, ok := e.Int(canonSettingsFocusMode); ok {
+			if fm, found := canonFocusModes[v]; found {
+				mn.FocusMode = fm
+			}
+		}
+		if v, ok := e.Uint(canonSettingsLensType); ok && v != 0 && v != 0xffff {
+			mn.LensID = strconv.Itoa(int(v))
+		}
+	}
+	mn.AFPoints = canonAFPoints(mn)
+	return nil
+}
+
+// canonAFPoints returns the indices of the AF points in focus from AFInfo2
+func canonAFPoints(mn *MakerNote) string {
+	e, found := mn.Tags[canonAFInfo2]
+	if !found {
+		return ""
+	}
+	n, ok := e.Uint(2)
+	if !ok || n == 0 {
+		return ""
+	}
+	//AFInfoSize, AFAreaMode, NumAFPoints, ValidAFPoints, 4 image sizes, widths, heights, x and y positions
+	start := 8 + 4*int(n)
+	ret := []string{}
+	for i := 0; i < int(n); i++ {
+		mask, ok := e.Uint(start + i/16)
+		if !ok {
+			return ""
+		}
+		if mask&(1<<uint(i%16)) != 0 {
+			ret = append(ret, strconv.Itoa(i))
+		}
+	}
+	return strings.Join(ret, ",")
+}
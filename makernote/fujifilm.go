@@ -0,0 +1,103 @@
+package makernote
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+Fujifilm maker notes, see https://exiftool.org/TagNames/FujiFilm.html
+*/
+
+const (
+	fujiSerialNumber          uint16 = 0x0010
+	fujiSaturation            uint16 = 0x1003
+	fujiFocusMode             uint16 = 0x1021
+	fujiFocusPixel            uint16 = 0x1023
+	fujiFilmMode              uint16 = 0x1401
+	fujiMinFocalLength        uint16 = 0x1404
+	fujiMaxFocalLength        uint16 = 0x1405
+	fujiMaxApertureAtMinFocal uint16 = 0x1406
+	fujiMaxApertureAtMaxFocal uint16 = 0x1407
+	fujiImageCount            uint16 = 0x1438
+)
+
+var fujiFilmModes = map[uint32]string{
+	0x000: "Provia",
+	0x100: "Studio Portrait",
+	0x110: "Studio Portrait Enhanced Saturation",
+	0x120: "Astia",
+	0x130: "Studio Portrait Increased Sharpness",
+	0x200: "Velvia",
+	0x300: "Studio Portrait Ex",
+	0x400: "Velvia",
+	0x500: "Pro Neg. Std",
+	0x501: "Pro Neg. Hi",
+	0x600: "Classic Chrome",
+	0x700: "Eterna",
+	0x800: "Classic Negative",
+	0x900: "Bleach Bypass",
+	0xa00: "Nostalgic Neg",
+	0xb00: "Reala ACE",
+}
+
+// fujiMonochromeModes are the monochrome film simulations (stored as a Saturation value)
+var fujiMonochromeModes = map[uint32]string{
+	0x300: "Monochrome",
+	0x301: "Monochrome + R Filter",
+	0x302: "Monochrome + Ye Filter",
+	0x303: "Monochrome + G Filter",
+	0x310: "Sepia",
+	0x500: "Acros",
+	0x501: "Acros + R Filter",
+	0x502: "Acros + Ye Filter",
+	0x503: "Acros + G Filter",
+}
+
+var fujiFocusModes = map[uint32]string{
+	0:      "Auto",
+	1:      "Manual",
+	0xffff: "Movie",
+}
+
+// parseFujifilm parses a Fujifilm maker note. The note starts with "FUJIFILM" followed by the offset to the ifd.
+// It is always little endian and offsets are relative to the start of the note
+func parseFujifilm(mn *MakerNote, tiff []byte, noteOffset int) error {
+	if noteOffset+12 > len(tiff) {
+		return ErrInvalidIfd
+	}
+	ifdOffset := int(binary.LittleEndian.Uint32(tiff[noteOffset+8:]))
+	if err := mn.readNote(ifdReader{data: tiff, order: binary.LittleEndian, base: noteOffset}, ifdOffset); err != nil {
+		return err
+	}
+	mn.SerialNumber = mn.str(fujiSerialNumber)
+	if v, found := mn.uint(fujiSaturation); found {
+		mn.FilmSimulation = fujiMonochromeModes[v]
+	}
+	if v, found := mn.uint(fujiFilmMode); found && mn.FilmSimulation == "" {
+		mn.FilmSimulation = fujiFilmModes[v]
+	}
+	if v, found := mn.uint(fujiFocusMode); found {
+		mn.FocusMode = fujiFocusModes[v]
+	}
+	if e, found := mn.Tags[fujiFocusPixel]; found {
+		x, okx := e.Uint(0)
+		y, oky := e.Uint(1)
+		if okx && oky {
+			mn.AFPoints = fmt.Sprintf("%d,%d", x, y)
+		}
+	}
+	if v, found := mn.uint(fujiImageCount); found {
+		mn.ShutterCount = v & 0x7fff
+	}
+	var lens [4]float64
+	for i, tag := range []uint16{fujiMinFocalLength, fujiMaxFocalLength, fujiMaxApertureAtMinFocal, fujiMaxApertureAtMaxFocal} {
+		if e, found := mn.Tags[tag]; found {
+			lens[i], _ = e.Float(0)
+		}
+	}
+	if lens[0] != 0 {
+		mn.LensModel = lensSpec(lens[0], lens[1], lens[2], lens[3])
+	}
+	return nil
+}
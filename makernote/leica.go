@@ -0,0 +1,60 @@
+package makernote
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+/*
+Leica maker notes, see https://exiftool.org/TagNames/Panasonic.html#Leica2 (M8) and
+https://exiftool.org/TagNames/Panasonic.html#Leica5 (M9 and later)
+*/
+
+const (
+	leicaLensType        uint16 = 0x0303
+	leicaSerialNumber    uint16 = 0x0305
+	leicaM8SerialNumber  uint16 = 0x0303
+	leicaM8LensType      uint16 = 0x0310
+	leicaHeader          string = "LEICA\x00"
+	leicaM8Header        string = "LEICA\x00\x00\x00"
+	leicaCameraAGHeader  string = "LEICA CAMERA AG\x00"
+	leicaCameraAGIfdSkip        = 18
+)
+
+// parseLeica parses Leica maker notes. The M8 note ("LEICA\0\0\0") uses offsets relative to the exif tiff header
+// while later notes use offsets relative to the start of the note
+func parseLeica(mn *MakerNote, tiff []byte, noteOffset int) error {
+	note := tiff[noteOffset:]
+	switch {
+	case bytes.HasPrefix(note, []byte(leicaM8Header)):
+		if err := mn.readNote(ifdReader{data: tiff, order: mn.ByteOrder}, noteOffset+8); err != nil {
+			return err
+		}
+		if sn, found := mn.uint(leicaM8SerialNumber); found {
+			mn.SerialNumber = fmt.Sprintf("%07d", sn)
+		}
+		if lt, found := mn.uint(leicaM8LensType); found && lt != 0 {
+			//the two lowest bits hold the frame selector position
+			mn.LensID = strconv.Itoa(int(lt >> 2))
+		}
+		return nil
+	case bytes.HasPrefix(note, []byte(leicaCameraAGHeader)):
+		if err := mn.readNote(ifdReader{data: tiff, order: mn.ByteOrder, base: noteOffset}, leicaCameraAGIfdSkip); err != nil {
+			return err
+		}
+	case bytes.HasPrefix(note, []byte(leicaHeader)):
+		if err := mn.readNote(ifdReader{data: tiff, order: mn.ByteOrder, base: noteOffset}, 8); err != nil {
+			return err
+		}
+	default:
+		return ErrInvalidIfd
+	}
+	if e, found := mn.Tags[leicaLensType]; found && e.Type == TypeAscii {
+		mn.LensModel = e.String()
+	}
+	if sn, found := mn.uint(leicaSerialNumber); found {
+		mn.SerialNumber = fmt.Sprintf("%07d", sn)
+	}
+	return nil
+}
@@ -0,0 +1,424 @@
+package makernote
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+Parsing of the proprietary exif maker notes written by Canon, Nikon, Sony, Fujifilm and Leica. The layout of the
+different notes (headers, byte order and offset base) and their tags are documented by exiftool, see
+https://exiftool.org/TagNames/index.html and https://exiftool.org/makernote_types.html
+*/
+
+// Makernote errors
+var (
+	ErrNoMakerNote   = errors.New("No maker note found")
+	ErrInvalidTiff   = errors.New("Invalid tiff header")
+	ErrInvalidIfd    = errors.New("Invalid maker note ifd")
+	ErrUnknownVendor = errors.New("Unknown maker note vendor")
+)
+
+// Vendor identifies the camera maker that wrote a maker note
+type Vendor string
+
+// Supported vendors
+const (
+	Canon    Vendor = "Canon"
+	Nikon    Vendor = "Nikon"
+	Sony     Vendor = "Sony"
+	Fujifilm Vendor = "Fujifilm"
+	Leica    Vendor = "Leica"
+)
+
+const (
+	tagMake       uint16 = 0x010f
+	tagExifIfd    uint16 = 0x8769
+	tagMakerNote  uint16 = 0x927c
+	maxIfdEntries        = 1000
+)
+
+// Tiff field types
+const (
+	TypeByte      uint16 = 1
+	TypeAscii     uint16 = 2
+	TypeShort     uint16 = 3
+	TypeLong      uint16 = 4
+	TypeRational  uint16 = 5
+	TypeSByte     uint16 = 6
+	TypeUndefined uint16 = 7
+	TypeSShort    uint16 = 8
+	TypeSLong     uint16 = 9
+	TypeSRational uint16 = 10
+	TypeFloat     uint16 = 11
+	TypeDouble    uint16 = 12
+)
+
+var typeSizes = map[uint16]int{
+	TypeByte: 1, TypeAscii: 1, TypeShort: 2, TypeLong: 4, TypeRational: 8, TypeSByte: 1,
+	TypeUndefined: 1, TypeSShort: 2, TypeSLong: 4, TypeSRational: 8, TypeFloat: 4, TypeDouble: 8,
+}
+
+// Entry is a raw maker note ifd entry
+type Entry struct {
+	Tag   uint16
+	Type  uint16
+	Count uint32
+	Value []byte
+	order binary.ByteOrder
+	//offset of Value in the underlying data or -1 if the value is stored in the entry itself
+	offset int
+}
+
+// String returns the value as a string. Ascii values are trimmed of trailing NULs and spaces
+func (e Entry) String() string {
+	return strings.TrimSpace(strings.TrimRight(string(e.Value), "\x00"))
+}
+
+// Uint returns the i:th value of an unsigned (or undefined) entry
+func (e Entry) Uint(i int) (uint32, bool) {
+	switch e.Type {
+	case TypeByte, TypeUndefined, TypeSByte:
+		if i < len(e.Value) {
+			return uint32(e.Value[i]), true
+		}
+	case TypeShort, TypeSShort:
+		if 2*i+2 <= len(e.Value) {
+			return uint32(e.order.Uint16(e.Value[2*i:])), true
+		}
+	case TypeLong, TypeSLong:
+		if 4*i+4 <= len(e.Value) {
+			return e.order.Uint32(e.Value[4*i:]), true
+		}
+	}
+	return 0, false
+}
+
+// Int returns the i:th value of a signed (or unsigned) integer entry
+func (e Entry) Int(i int) (int32, bool) {
+	switch e.Type {
+	case TypeSByte:
+		if i < len(e.Value) {
+			return int32(int8(e.Value[i])), true
+		}
+	case TypeSShort:
+		if 2*i+2 <= len(e.Value) {
+			return int32(int16(e.order.Uint16(e.Value[2*i:]))), true
+		}
+	case TypeSLong:
+		if 4*i+4 <= len(e.Value) {
+			return int32(e.order.Uint32(e.Value[4*i:])), true
+		}
+	default:
+		v, ok := e.Uint(i)
+		return int32(v), ok
+	}
+	return 0, false
+}
+
+// Len returns the number of values in the entry
+func (e Entry) Len() int {
+	return int(e.Count)
+}
+
+// Float returns the i:th value of a rational entry as a float
+func (e Entry) Float(i int) (float64, bool) {
+	if (e.Type != TypeRational && e.Type != TypeSRational) || 8*i+8 > len(e.Value) {
+		return 0, false
+	}
+	n, d := e.order.Uint32(e.Value[8*i:]), e.order.Uint32(e.Value[8*i+4:])
+	if d == 0 {
+		return 0, false
+	}
+	if e.Type == TypeSRational {
+		return float64(int32(n)) / float64(int32(d)), true
+	}
+	return float64(n) / float64(d), true
+}
+
+// MakerNote holds the key fields of a parsed maker note. Fields that the vendor does not record (or that are
+// encrypted) are left empty. All entries of the main maker note ifd are available in Tags
+type MakerNote struct {
+	Vendor           Vendor           `json:"vendor"`
+	LensModel        string           `json:"lensModel,omitempty"`
+	LensID           string           `json:"lensID,omitempty"`
+	LensSerialNumber string           `json:"lensSerialNumber,omitempty"`
+	SerialNumber     string           `json:"serialNumber,omitempty"`
+	ShutterCount     uint32           `json:"shutterCount,omitempty"`
+	FilmSimulation   string           `json:"filmSimulation,omitempty"`
+	FocusMode        string           `json:"focusMode,omitempty"`
+	AFPoints         string           `json:"afPoints,omitempty"`
+	ByteOrder        binary.ByteOrder `json:"-"`
+	Tags             map[uint16]Entry `json:"-"`
+}
+
+// Tag returns the entry with the given tag id
+func (mn *MakerNote) Tag(tag uint16) (Entry, bool) {
+	e, found := mn.Tags[tag]
+	return e, found
+}
+
+func (mn *MakerNote) String() string {
+	sb := strings.Builder{}
+	sb.WriteString("MakerNote:{\n")
+	sb.WriteString(fmt.Sprintf("  Vendor: %v\n", mn.Vendor))
+	sb.WriteString(fmt.Sprintf("  Lens Model: %v\n", mn.LensModel))
+	sb.WriteString(fmt.Sprintf("  Lens ID: %v\n", mn.LensID))
+	sb.WriteString(fmt.Sprintf("  Lens Serial Number: %v\n", mn.LensSerialNumber))
+	sb.WriteString(fmt.Sprintf("  Serial Number: %v\n", mn.SerialNumber))
+	sb.WriteString(fmt.Sprintf("  Shutter Count: %v\n", mn.ShutterCount))
+	sb.WriteString(fmt.Sprintf("  Film Simulation: %v\n", mn.FilmSimulation))
+	sb.WriteString(fmt.Sprintf("  Focus Mode: %v\n", mn.FocusMode))
+	sb.WriteString(fmt.Sprintf("  AF Points: %v\n", mn.AFPoints))
+	tags := make([]int, 0, len(mn.Tags))
+	for t := range mn.Tags {
+		tags = append(tags, int(t))
+	}
+	sort.Ints(tags)
+	sb.WriteString(fmt.Sprintf("  Tags (%d):", len(tags)))
+	for _, t := range tags {
+		sb.WriteString(fmt.Sprintf(" %#04x", t))
+	}
+	sb.WriteString("\n}")
+	return sb.String()
+}
+
+// ifdReader reads ifds from data. All offsets are relative to base
+type ifdReader struct {
+	data  []byte
+	order binary.ByteOrder
+	base  int
+}
+
+func (r ifdReader) readIfd(offset int) (map[uint16]Entry, error) {
+	start := r.base + offset
+	if offset < 0 || start < 0 || start+2 > len(r.data) {
+		return nil, ErrInvalidIfd
+	}
+	n := int(r.order.Uint16(r.data[start:]))
+	if n == 0 || n > maxIfdEntries || start+2+12*n > len(r.data) {
+		return nil, ErrInvalidIfd
+	}
+	ret := map[uint16]Entry{}
+	for i := 0; i < n; i++ {
+		raw := r.data[start+2+12*i : start+14+12*i]
+		e := Entry{
+			Tag:    r.order.Uint16(raw),
+			Type:   r.order.Uint16(raw[2:]),
+			Count:  r.order.Uint32(raw[4:]),
+			order:  r.order,
+			offset: -1,
+		}
+		size, found := typeSizes[e.Type]
+		if !found || e.Count > uint32(len(r.data)) {
+			continue //skip unknown or broken entries
+		}
+		length := size * int(e.Count)
+		if length <= 4 {
+			e.Value = raw[8 : 8+length]
+		} else {
+			valueOffset := r.base + int(r.order.Uint32(raw[8:]))
+			if valueOffset < 0 || valueOffset+length > len(r.data) {
+				continue
+			}
+			e.Value = r.data[valueOffset : valueOffset+length]
+			e.offset = valueOffset
+		}
+		ret[e.Tag] = e
+	}
+	return ret, nil
+}
+
+// tiffHeader returns the byte order and first ifd offset of a tiff header
+func tiffHeader(data []byte) (binary.ByteOrder, int, error) {
+	if len(data) < 8 {
+		return nil, 0, ErrInvalidTiff
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, 0, ErrInvalidTiff
+	}
+	if order.Uint16(data[2:]) != 42 {
+		return nil, 0, ErrInvalidTiff
+	}
+	return order, int(order.Uint32(data[4:])), nil
+}
+
+// noteLocation describes where a maker note is stored in an exif tiff block
+type noteLocation struct {
+	order  binary.ByteOrder
+	make   string
+	offset int
+	length int
+}
+
+func locate(tiff []byte) (noteLocation, error) {
+	ret := noteLocation{}
+	order, offset, err := tiffHeader(tiff)
+	if err != nil {
+		return ret, err
+	}
+	ret.order = order
+	r := ifdReader{data: tiff, order: order}
+	root, err := r.readIfd(offset)
+	if err != nil {
+		return ret, err
+	}
+	exifPtr, found := root[tagExifIfd]
+	if !found {
+		return ret, ErrNoMakerNote
+	}
+	exifOffset, _ := exifPtr.Uint(0)
+	exifIfd, err := r.readIfd(int(exifOffset))
+	if err != nil {
+		return ret, err
+	}
+	note, found := exifIfd[tagMakerNote]
+	if !found || note.offset < 0 {
+		return ret, ErrNoMakerNote
+	}
+	if m, found := root[tagMake]; found {
+		ret.make = m.String()
+	}
+	ret.offset, ret.length = note.offset, len(note.Value)
+	return ret, nil
+}
+
+// Locate returns the offset and length of the maker note in an exif tiff block. Returns ErrNoMakerNote if the exif
+// block does not contain a maker note
+func Locate(tiff []byte) (offset int, length int, err error) {
+	loc, err := locate(tiff)
+	if err != nil {
+		return -1, 0, err
+	}
+	return loc.offset, loc.length, nil
+}
+
+// Parse finds and parses the maker note in an exif tiff block (i.e. the APP1 payload after the "Exif\0\0" header).
+// The vendor is detected from the Make tag and the maker note header. Returns ErrNoMakerNote if the exif
+// block does not contain a maker note
+func Parse(tiff []byte) (*MakerNote, error) {
+	loc, err := locate(tiff)
+	if err != nil {
+		return nil, err
+	}
+	return parseNote(loc.make, tiff, loc.order, loc.offset, loc.length)
+}
+
+// detectVendor detects the vendor from the maker note header or else the camera make
+func detectVendor(make string, note []byte) (Vendor, error) {
+	switch {
+	case bytes.HasPrefix(note, []byte("Nikon\x00")):
+		return Nikon, nil
+	case bytes.HasPrefix(note, []byte("FUJIFILM")):
+		return Fujifilm, nil
+	case bytes.HasPrefix(note, []byte("LEICA")):
+		return Leica, nil
+	case bytes.HasPrefix(note, []byte("SONY")):
+		return Sony, nil
+	}
+	make = strings.ToUpper(make)
+	switch {
+	case strings.HasPrefix(make, "CANON"):
+		return Canon, nil
+	case strings.HasPrefix(make, "NIKON"):
+		return Nikon, nil
+	case strings.HasPrefix(make, "SONY"):
+		return Sony, nil
+	case strings.HasPrefix(make, "FUJIFILM"):
+		return Fujifilm, nil
+	case strings.HasPrefix(make, "LEICA"):
+		return Leica, nil
+	}
+	return "", ErrUnknownVendor
+}
+
+func parseNote(make string, tiff []byte, order binary.ByteOrder, noteOffset, length int) (*MakerNote, error) {
+	if noteOffset < 0 || noteOffset+length > len(tiff) {
+		return nil, ErrInvalidIfd
+	}
+	note := tiff[noteOffset : noteOffset+length]
+	vendor, err := detectVendor(make, note)
+	if err != nil {
+		return nil, err
+	}
+	mn := &MakerNote{Vendor: vendor, ByteOrder: order}
+	switch vendor {
+	case Canon:
+		err = parseCanon(mn, tiff, noteOffset)
+	case Nikon:
+		err = parseNikon(mn, tiff, noteOffset)
+	case Sony:
+		err = parseSony(mn, tiff, noteOffset)
+	case Fujifilm:
+		err = parseFujifilm(mn, tiff, noteOffset)
+	case Leica:
+		err = parseLeica(mn, tiff, noteOffset)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mn, nil
+}
+
+// readNote reads the main maker note ifd at ifdOffset (relative to base)
+func (mn *MakerNote) readNote(r ifdReader, ifdOffset int) error {
+	tags, err := r.readIfd(ifdOffset)
+	if err != nil {
+		return err
+	}
+	mn.Tags = tags
+	mn.ByteOrder = r.order
+	return nil
+}
+
+func (mn *MakerNote) str(tag uint16) string {
+	if e, found := mn.Tags[tag]; found {
+		return e.String()
+	}
+	return ""
+}
+
+func (mn *MakerNote) uint(tag uint16) (uint32, bool) {
+	if e, found := mn.Tags[tag]; found {
+		return e.Uint(0)
+	}
+	return 0, false
+}
+
+// lensSpec formats a focal length and aperture range, e.g. "24-70mm f/2.8" or "18-55mm f/3.5-5.6"
+func lensSpec(minFocal, maxFocal, minAperture, maxAperture float64) string {
+	rng := func(a, b float64) string {
+		sa, sb := trimFloat(a), trimFloat(b)
+		if sa == sb || b == 0 {
+			return sa
+		}
+		return sa + "-" + sb
+	}
+	return fmt.Sprintf("%smm f/%s", rng(minFocal, maxFocal), rng(minAperture, maxAperture))
+}
+
+func trimFloat(f float64) string {
+	s := fmt.Sprintf("%.1f", f)
+	return strings.TrimSuffix(s, ".0")
+}
+
+// pointNames returns the names of the bits set in mask
+func pointNames(mask uint32, names []string) string {
+	ret := []string{}
+	for i, n := range names {
+		if mask&(1<<uint(i)) != 0 {
+			ret = append(ret, n)
+		}
+	}
+	return strings.Join(ret, ", ")
+}
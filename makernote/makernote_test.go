@@ -0,0 +1,285 @@
+package makernote
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+type testEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+func asciiEntry(tag uint16, s string) testEntry {
+	return testEntry{tag, TypeAscii, uint32(len(s) + 1), append([]byte(s), 0)}
+}
+
+func shortEntry(order binary.ByteOrder, tag uint16, typ uint16, values ...uint16) testEntry {
+	b := make([]byte, 2*len(values))
+	for i, v := range values {
+		order.PutUint16(b[2*i:], v)
+	}
+	return testEntry{tag, typ, uint32(len(values)), b}
+}
+
+func longEntry(order binary.ByteOrder, tag uint16, values ...uint32) testEntry {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		order.PutUint32(b[4*i:], v)
+	}
+	return testEntry{tag, TypeLong, uint32(len(values)), b}
+}
+
+func rationalEntry(order binary.ByteOrder, tag uint16, values ...uint32) testEntry {
+	e := longEntry(order, tag, values...)
+	e.typ, e.count = TypeRational, e.count/2
+	return e
+}
+
+func undefEntry(tag uint16, b []byte) testEntry {
+	return testEntry{tag, TypeUndefined, uint32(len(b)), b}
+}
+
+// ifdBytes encodes an ifd that will be placed at start. Value offsets are written relative to base
+func ifdBytes(order binary.ByteOrder, start, base int, entries []testEntry) []byte {
+	ifdSize := 2 + 12*len(entries) + 4
+	ifd := make([]byte, ifdSize)
+	data := []byte{}
+	order.PutUint16(ifd, uint16(len(entries)))
+	for i, e := range entries {
+		raw := ifd[2+12*i:]
+		order.PutUint16(raw, e.tag)
+		order.PutUint16(raw[2:], e.typ)
+		order.PutUint32(raw[4:], e.count)
+		if len(e.value) <= 4 {
+			copy(raw[8:], e.value)
+			continue
+		}
+		order.PutUint32(raw[8:], uint32(start+ifdSize+len(data)-base))
+		data = append(data, e.value...)
+		if len(data)%2 == 1 {
+			data = append(data, 0)
+		}
+	}
+	return append(ifd, data...)
+}
+
+// buildTiff creates an exif tiff block with a Make tag and a maker note created by note (given its offset)
+func buildTiff(order binary.ByteOrder, make string, note func(offset int) []byte) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	if order == binary.BigEndian {
+		tiff = []byte("MM\x00*\x00\x00\x00\x08")
+	}
+	exifOffset := 8 + 2 + 2*12 + 4 + len(make) + 1
+	exifOffset += exifOffset % 2
+	root := ifdBytes(order, 8, 0, []testEntry{asciiEntry(tagMake, make), longEntry(order, tagExifIfd, uint32(exifOffset))})
+	tiff = append(tiff, root...)
+	for len(tiff) < exifOffset {
+		tiff = append(tiff, 0)
+	}
+	noteBytes := note(exifOffset + 2 + 12 + 4)
+	exifIfd := ifdBytes(order, exifOffset, 0, []testEntry{undefEntry(tagMakerNote, noteBytes)})
+	return append(tiff, exifIfd...)
+}
+
+func TestParse_Canon(t *testing.T) {
+	le := binary.LittleEndian
+	settings := make([]uint16, 23)
+	settings[canonSettingsFocusMode] = 1
+	settings[canonSettingsLensType] = 137
+	afInfo := []uint16{0, 0, 3, 3, 0, 0, 0, 0, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0x5}
+	tiff := buildTiff(le, "Canon", func(offset int) []byte {
+		return ifdBytes(le, offset, 0, []testEntry{
+			shortEntry(le, canonCameraSettings, TypeShort, settings...),
+			longEntry(le, canonSerialNumber, 1234567),
+			shortEntry(le, canonAFInfo2, TypeShort, afInfo...),
+			asciiEntry(canonLensModel, "EF24-70mm f/2.8L USM"),
+		})
+	})
+	mn, err := Parse(tiff)
+	if err != nil {
+		t.Fatalf("Could not parse maker note: %v", err)
+	}
+	exp := MakerNote{Vendor: Canon, LensModel: "EF24-70mm f/2.8L USM", LensID: "137", SerialNumber: "0001234567",
+		FocusMode: "AI Servo AF", AFPoints: "0,2"}
+	assertMakerNote(exp, mn, t)
+}
+
+func TestParse_Nikon(t *testing.T) {
+	be := binary.BigEndian
+	lensData := []byte("0100\x00\x00\x92\x48\x50\x50\x14\x14\x62")
+	tiff := buildTiff(binary.LittleEndian, "NIKON CORPORATION", func(offset int) []byte {
+		note := []byte("Nikon\x00\x02\x10\x00\x00MM\x00*\x00\x00\x00\x08")
+		return append(note, ifdBytes(be, offset+18, offset+10, []testEntry{
+			asciiEntry(nikonFocusMode, "AF-S  "),
+			asciiEntry(nikonSerialNumber, "3012345"),
+			{nikonLensType, TypeByte, 1, []byte{0x06}},
+			rationalEntry(be, nikonLens, 50, 1, 50, 1, 14, 10, 14, 10),
+			undefEntry(nikonAFInfo, []byte{0, 0, 0, 0x01}),
+			undefEntry(nikonLensData, lensData),
+			longEntry(be, nikonShutterCount, 23456),
+		})...)
+	})
+	mn, err := Parse(tiff)
+	if err != nil {
+		t.Fatalf("Could not parse maker note: %v", err)
+	}
+	exp := MakerNote{Vendor: Nikon, LensModel: "50mm f/1.4", LensID: "92 48 50 50 14 14 62 06", SerialNumber: "3012345",
+		ShutterCount: 23456, FocusMode: "AF-S", AFPoints: "Center"}
+	assertMakerNote(exp, mn, t)
+}
+
+func TestParse_Sony(t *testing.T) {
+	le := binary.LittleEndian
+	tiff := buildTiff(le, "SONY", func(offset int) []byte {
+		return append([]byte("SONY DSC \x00\x00\x00"), ifdBytes(le, offset+12, 0, []testEntry{
+			longEntry(le, sonyLensType, 32784),
+			undefEntry(sonyLensSpec, []byte{0, 0x00, 0x16, 0x00, 0x50, 0x35, 0x56, 0}),
+			shortEntry(le, sonyFocusMode, TypeShort, 3),
+		})...)
+	})
+	mn, err := Parse(tiff)
+	if err != nil {
+		t.Fatalf("Could not parse maker note: %v", err)
+	}
+	exp := MakerNote{Vendor: Sony, LensModel: "16-50mm F3.5-5.6", LensID: "32784", FocusMode: "AF-C"}
+	assertMakerNote(exp, mn, t)
+}
+
+func TestParse_Fujifilm(t *testing.T) {
+	le := binary.LittleEndian
+	tiff := buildTiff(binary.BigEndian, "FUJIFILM", func(offset int) []byte {
+		return append([]byte("FUJIFILM\x0c\x00\x00\x00"), ifdBytes(le, offset+12, offset, []testEntry{
+			asciiEntry(fujiSerialNumber, "FF02B1234567"),
+			shortEntry(le, fujiSaturation, TypeShort, 0),
+			shortEntry(le, fujiFocusMode, TypeShort, 0),
+			shortEntry(le, fujiFocusPixel, TypeShort, 3008, 2000),
+			shortEntry(le, fujiFilmMode, TypeShort, 0x600),
+			rationalEntry(le, fujiMinFocalLength, 18, 1),
+			rationalEntry(le, fujiMaxFocalLength, 55, 1),
+			rationalEntry(le, fujiMaxApertureAtMinFocal, 28, 10),
+			rationalEntry(le, fujiMaxApertureAtMaxFocal, 4, 1),
+			shortEntry(le, fujiImageCount, TypeShort, 0x8000|1234),
+		})...)
+	})
+	mn, err := Parse(tiff)
+	if err != nil {
+		t.Fatalf("Could not parse maker note: %v", err)
+	}
+	exp := MakerNote{Vendor: Fujifilm, LensModel: "18-55mm f/2.8-4", SerialNumber: "FF02B1234567", ShutterCount: 1234,
+		FilmSimulation: "Classic Chrome", FocusMode: "Auto", AFPoints: "3008,2000"}
+	assertMakerNote(exp, mn, t)
+}
+
+func TestParse_Leica(t *testing.T) {
+	le := binary.LittleEndian
+	tiff := buildTiff(le, "LEICA CAMERA AG", func(offset int) []byte {
+		return append([]byte("LEICA\x00\x05\x00"), ifdBytes(le, offset+8, offset, []testEntry{
+			asciiEntry(leicaLensType, "Summilux-M 1:1.4/50 ASPH."),
+			longEntry(le, leicaSerialNumber, 4012345),
+		})...)
+	})
+	mn, err := Parse(tiff)
+	if err != nil {
+		t.Fatalf("Could not parse maker note: %v", err)
+	}
+	assertMakerNote(MakerNote{Vendor: Leica, LensModel: "Summilux-M 1:1.4/50 ASPH.", SerialNumber: "4012345"}, mn, t)
+
+	tiff = buildTiff(le, "Leica Camera AG", func(offset int) []byte {
+		return append([]byte("LEICA\x00\x00\x00"), ifdBytes(le, offset+8, 0, []testEntry{
+			longEntry(le, leicaM8SerialNumber, 3106345),
+			longEntry(le, leicaM8LensType, 11<<2|1),
+		})...)
+	})
+	if mn, err = Parse(tiff); err != nil {
+		t.Fatalf("Could not parse maker note: %v", err)
+	}
+	assertMakerNote(MakerNote{Vendor: Leica, LensID: "11", SerialNumber: "3106345"}, mn, t)
+}
+
+func TestParse_Errors(t *testing.T) {
+	le := binary.LittleEndian
+	if _, err := Parse([]byte("not a tiff")); err != ErrInvalidTiff {
+		t.Errorf("Expected %v got %v", ErrInvalidTiff, err)
+	}
+	tiff := buildTiff(le, "Unknown", func(offset int) []byte {
+		return ifdBytes(le, offset, 0, []testEntry{longEntry(le, 1, 1)})
+	})
+	if _, err := Parse(tiff); err != ErrUnknownVendor {
+		t.Errorf("Expected %v got %v", ErrUnknownVendor, err)
+	}
+	tiff = buildTiff(le, "Canon", func(offset int) []byte {
+		return []byte("garbage!")
+	})
+	if _, err := Parse(tiff); err != ErrInvalidIfd {
+		t.Errorf("Expected %v got %v", ErrInvalidIfd, err)
+	}
+	//a type 3 nikon note that ends before its tiff header
+	tiff = buildTiff(le, "NIKON CORPORATION", func(offset int) []byte {
+		return []byte("Nikon\x00\x02")
+	})
+	if _, err := Parse(tiff); err != ErrInvalidIfd {
+		t.Errorf("Expected %v got %v", ErrInvalidIfd, err)
+	}
+}
+
+func assertMakerNote(exp MakerNote, act *MakerNote, t *testing.T) {
+	t.Helper()
+	if act.Vendor != exp.Vendor {
+		t.Errorf("Expected %v got %v", exp.Vendor, act.Vendor)
+	}
+	if act.LensModel != exp.LensModel {
+		t.Errorf("Expected %v got %v", exp.LensModel, act.LensModel)
+	}
+	if act.LensID != exp.LensID {
+		t.Errorf("Expected %v got %v", exp.LensID, act.LensID)
+	}
+	if act.SerialNumber != exp.SerialNumber {
+		t.Errorf("Expected %v got %v", exp.SerialNumber, act.SerialNumber)
+	}
+	if act.ShutterCount != exp.ShutterCount {
+		t.Errorf("Expected %v got %v", exp.ShutterCount, act.ShutterCount)
+	}
+	if act.FilmSimulation != exp.FilmSimulation {
+		t.Errorf("Expected %v got %v", exp.FilmSimulation, act.FilmSimulation)
+	}
+	if act.FocusMode != exp.FocusMode {
+		t.Errorf("Expected %v got %v", exp.FocusMode, act.FocusMode)
+	}
+	if act.AFPoints != exp.AFPoints {
+		t.Errorf("Expected %v got %v", exp.AFPoints, act.AFPoints)
+	}
+}
+
+func TestRelocate(t *testing.T) {
+	le := binary.LittleEndian
+	var note []byte
+	var oldOffset int
+	buildTiff(le, "Canon", func(offset int) []byte {
+		oldOffset = offset
+		note = ifdBytes(le, offset, 0, []testEntry{asciiEntry(canonLensModel, "EF24-70mm f/2.8L USM")})
+		return note
+	})
+	//a longer make moves the (unchanged) note
+	tiff := buildTiff(le, "Canon Inc.", func(offset int) []byte {
+		return append([]byte{}, note...)
+	})
+	if offset, _, _ := Locate(tiff); offset == oldOffset {
+		t.Fatalf("Expected maker note to be moved")
+	}
+	if mn, err := Parse(tiff); err == nil && mn.LensModel == "EF24-70mm f/2.8L USM" {
+		t.Errorf("Expected broken offsets before relocation")
+	}
+	if changed, err := Relocate(tiff, oldOffset); !changed || err != nil {
+		t.Fatalf("Could not relocate maker note: %v", err)
+	}
+	mn, err := Parse(tiff)
+	if err != nil {
+		t.Fatalf("Could not parse maker note: %v", err)
+	}
+	if mn.LensModel != "EF24-70mm f/2.8L USM" {
+		t.Errorf("Expected %v got %v", "EF24-70mm f/2.8L USM", mn.LensModel)
+	}
+}
@@ -0,0 +1,99 @@
+package makernote
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+/*
+Nikon maker notes, see https://exiftool.org/TagNames/Nikon.html
+*/
+
+const (
+	nikonFocusMode    uint16 = 0x0007
+	nikonSerialNumber uint16 = 0x001d
+	nikonLensType     uint16 = 0x0083
+	nikonLens         uint16 = 0x0084
+	nikonAFInfo       uint16 = 0x0088
+	nikonLensData     uint16 = 0x0098
+	nikonShutterCount uint16 = 0x00a7
+)
+
+// nikonAFPoints are the names of the points of 11-point AF bodies (in AFInfo bit order)
+var nikonAFPoints = []string{"Center", "Top", "Bottom", "Mid-left", "Mid-right", "Upper-left", "Upper-right",
+	"Lower-left", "Lower-right", "Far Left", "Far Right"}
+
+// parseNikon parses the three Nikon maker note formats: Type 3 ("Nikon\0\2") that embeds its own tiff header and
+// uses offsets relative to it, Type 1 ("Nikon\0\1") used by older Coolpix cameras and headerless notes (D1)
+func parseNikon(mn *MakerNote, tiff []byte, noteOffset int) error {
+	note := tiff[noteOffset:]
+	switch {
+	case bytes.HasPrefix(note, []byte("Nikon\x00\x02")):
+		if len(note) < 10 {
+			return ErrInvalidIfd
+		}
+		order, ifdOffset, err := tiffHeader(note[10:])
+		if err != nil {
+			return err
+		}
+		if err = mn.readNote(ifdReader{data: tiff, order: order, base: noteOffset + 10}, ifdOffset); err != nil {
+			return err
+		}
+	case bytes.HasPrefix(note, []byte("Nikon\x00")):
+		if err := mn.readNote(ifdReader{data: tiff, order: mn.ByteOrder}, noteOffset+8); err != nil {
+			return err
+		}
+	default:
+		if err := mn.readNote(ifdReader{data: tiff, order: mn.ByteOrder}, noteOffset); err != nil {
+			return err
+		}
+	}
+	mn.SerialNumber = mn.str(nikonSerialNumber)
+	mn.FocusMode = mn.str(nikonFocusMode)
+	mn.ShutterCount, _ = mn.uint(nikonShutterCount)
+	if e, found := mn.Tags[nikonLens]; found {
+		var v [4]float64
+		for i := range v {
+			v[i], _ = e.Float(i)
+		}
+		if v[0] != 0 {
+			mn.LensModel = lensSpec(v[0], v[1], v[2], v[3])
+		}
+	}
+	mn.LensID = nikonLensID(mn)
+	if e, found := mn.Tags[nikonAFInfo]; found && len(e.Value) >= 4 {
+		inFocus := uint32(mn.ByteOrder.Uint16(e.Value[2:]))
+		mn.AFPoints = pointNames(inFocus, nikonAFPoints)
+	}
+	return nil
+}
+
+// nikonLensID returns the lens id used by exiftool to identify the lens, i.e. the hex encoded LensIDNumber,
+// LensFStops, focal lengths, apertures, MCUVersion and LensType. Only unencrypted LensData (as written by
+// older bodies) can be decoded
+func nikonLensID(mn *MakerNote) string {
+	e, found := mn.Tags[nikonLensData]
+	if !found || len(e.Value) < 4 {
+		return ""
+	}
+	var start int
+	switch string(e.Value[:4]) {
+	case "0100":
+		start = 6
+	case "0101":
+		start = 11
+	default:
+		return "" //encrypted
+	}
+	if len(e.Value) < start+7 {
+		return ""
+	}
+	lensType, _ := mn.uint(nikonLensType)
+	id := append(append([]byte{}, e.Value[start:start+7]...), byte(lensType))
+	ret := make([]string, len(id))
+	for i, b := range id {
+		ret[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(ret, " ")
+}
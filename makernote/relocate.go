@@ -0,0 +1,81 @@
+package makernote
+
+import (
+	"bytes"
+)
+
+// tiffRelativeIfd returns the position of the ifd in a maker note that uses offsets relative to the exif tiff
+// header. ok is false for notes that use offsets relative to the note itself since they can be moved as is
+func tiffRelativeIfd(vendor Vendor, note []byte) (ifd int, ok bool) {
+	switch vendor {
+	case Canon:
+		return 0, true
+	case Sony:
+		if bytes.HasPrefix(note, []byte("SONY")) {
+			return 12, true
+		}
+		return 0, true
+	case Nikon:
+		if bytes.HasPrefix(note, []byte("Nikon\x00\x02")) {
+			return 0, false
+		}
+		if bytes.HasPrefix(note, []byte("Nikon\x00")) {
+			return 8, true
+		}
+		return 0, true
+	case Leica:
+		if bytes.HasPrefix(note, []byte(leicaM8Header)) {
+			return 8, true
+		}
+	}
+	return 0, false
+}
+
+// Relocate fixes the offsets of a maker note that has been moved within tiff (e.g. when the exif block is rewritten).
+// oldOffset is the offset the note was originally written for. Notes with offsets relative to the note itself
+// (Nikon type 3, Fujifilm and Leica M9 and later) are position independent and are left unchanged. Only offsets
+// that point into the note are adjusted. Returns true if the note was changed
+func Relocate(tiff []byte, oldOffset int) (bool, error) {
+	loc, err := locate(tiff)
+	if err != nil {
+		return false, err
+	}
+	delta := loc.offset - oldOffset
+	if delta == 0 {
+		return false, nil
+	}
+	note := tiff[loc.offset : loc.offset+loc.length]
+	vendor, err := detectVendor(loc.make, note)
+	if err != nil {
+		return false, err
+	}
+	ifd, ok := tiffRelativeIfd(vendor, note)
+	if !ok {
+		return false, nil
+	}
+	if ifd+2 > len(note) {
+		return false, ErrInvalidIfd
+	}
+	n := int(loc.order.Uint16(note[ifd:]))
+	if n == 0 || n > maxIfdEntries || ifd+2+12*n > len(note) {
+		return false, ErrInvalidIfd
+	}
+	for i := 0; i < n; i++ {
+		raw := note[ifd+2+12*i:]
+		size, found := typeSizes[loc.order.Uint16(raw[2:])]
+		if !found || size*int(loc.order.Uint32(raw[4:])) <= 4 {
+			continue
+		}
+		if off := int(loc.order.Uint32(raw[8:])); off >= oldOffset && off < oldOffset+loc.length {
+			loc.order.PutUint32(raw[8:], uint32(off+delta))
+		}
+	}
+	//canon notes can end with a tiff like trailer holding the original offset of the note
+	if vendor == Canon && len(note) >= 8 {
+		trailer := note[len(note)-8:]
+		if order, _, err := tiffHeader(trailer); err == nil {
+			order.PutUint32(trailer[4:], uint32(loc.offset))
+		}
+	}
+	return true, nil
+}
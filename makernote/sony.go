@@ -0,0 +1,71 @@
+package makernote
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+/*
+Sony maker notes, see https://exiftool.org/TagNames/Sony.html. Serial numbers and shutter counts are stored in
+encrypted tags and are not decoded
+*/
+
+const (
+	sonyLensType  uint16 = 0xb027
+	sonyLensSpec  uint16 = 0xb02a
+	sonyFocusMode uint16 = 0xb042
+)
+
+var sonyFocusModes = map[uint32]string{
+	0: "Manual",
+	2: "AF-S",
+	3: "AF-C",
+	4: "AF-A",
+	6: "DMF",
+}
+
+// parseSony parses a Sony maker note. The ifd follows an optional 12 byte "SONY DSC " header and offsets are
+// relative to the exif tiff header
+func parseSony(mn *MakerNote, tiff []byte, noteOffset int) error {
+	ifdOffset := noteOffset
+	if bytes.HasPrefix(tiff[noteOffset:], []byte("SONY")) {
+		ifdOffset += 12
+	}
+	if err := mn.readNote(ifdReader{data: tiff, order: mn.ByteOrder}, ifdOffset); err != nil {
+		return err
+	}
+	if v, found := mn.uint(sonyLensType); found && v != 0xffff {
+		mn.LensID = strconv.Itoa(int(v))
+	}
+	if e, found := mn.Tags[sonyLensSpec]; found && len(e.Value) == 8 {
+		mn.LensModel = sonyLensSpecString(e.Value)
+	}
+	if v, found := mn.uint(sonyFocusMode); found {
+		mn.FocusMode = sonyFocusModes[v]
+	}
+	return nil
+}
+
+func bcd(b byte) int {
+	return int(b>>4)*10 + int(b&0x0f)
+}
+
+// sonyLensSpecString decodes the BCD encoded LensSpec, e.g. "16-50mm F3.5-5.6". The feature flags in the first
+// and last byte are ignored
+func sonyLensSpecString(spec []byte) string {
+	minFocal, maxFocal := bcd(spec[1])*100+bcd(spec[2]), bcd(spec[3])*100+bcd(spec[4])
+	if minFocal == 0 {
+		return ""
+	}
+	minAperture, maxAperture := float64(bcd(spec[5]))/10, float64(bcd(spec[6]))/10
+	ret := fmt.Sprintf("%dmm", minFocal)
+	if maxFocal != 0 && maxFocal != minFocal {
+		ret = fmt.Sprintf("%d-%dmm", minFocal, maxFocal)
+	}
+	ret += " F" + trimFloat(minAperture)
+	if maxAperture != 0 && maxAperture != minAperture {
+		ret += "-" + trimFloat(maxAperture)
+	}
+	return ret
+}
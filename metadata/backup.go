@@ -0,0 +1,71 @@
+package metadata
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// BackupSuffix is added to the file name of backups (same convention as exiftool)
+const BackupSuffix = "_original"
+
+var ErrNoBackup = errors.New("No backup file found")
+
+// BackupFileName returns the name of the backup of fileName
+func BackupFileName(fileName string) string {
+	return fileName + BackupSuffix
+}
+
+// BackupFile keeps a copy of fileName as fileName_original. Since editors replace files rather than writing to
+// them the backup is a hard link when possible and otherwise a copy that keeps the mode and modification time. An
+// existing backup is never overwritten so it always holds the first original
+func BackupFile(fileName string) error {
+	backup := BackupFileName(fileName)
+	if _, err := os.Lstat(backup); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(fileName, backup); err == nil {
+		return nil
+	}
+	return copyFile(fileName, backup)
+}
+
+// RestoreFile replaces fileName with its backup. The backup is removed
+func RestoreFile(fileName string) error {
+	backup := BackupFileName(fileName)
+	if _, err := os.Stat(backup); os.IsNotExist(err) {
+		return ErrNoBackup
+	} else if err != nil {
+		return err
+	}
+	return os.Rename(backup, fileName)
+}
+
+func copyFile(source, dest string) error {
+	fi, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dest)
+		return err
+	}
+	return os.Chtimes(dest, fi.ModTime(), fi.ModTime())
+}
@@ -0,0 +1,51 @@
+package metadata
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestBackupFile(t *testing.T) {
+	fileName := copyAsset(LeicaImg, t)
+	orig := getAssetBytes(fileName, t)
+	if err := BackupFile(fileName); err != nil {
+		t.Fatalf("Could not backup file: %v", err)
+	}
+	je := getJpegEditor(fileName, t)
+	_ = je.SetTitle("first edit")
+	if err := je.UpdateFile(false); err != nil {
+		t.Fatalf("Could not update file: %v", err)
+	}
+	//a second backup should keep the original
+	if err := BackupFile(fileName); err != nil {
+		t.Fatalf("Could not backup file: %v", err)
+	}
+	if !bytes.Equal(orig, getAssetBytes(BackupFileName(fileName), t)) {
+		t.Errorf("Expected backup to equal the original image")
+	}
+	if err := RestoreFile(fileName); err != nil {
+		t.Fatalf("Could not restore file: %v", err)
+	}
+	if !bytes.Equal(orig, getAssetBytes(fileName, t)) {
+		t.Errorf("Expected restored file to equal the original image")
+	}
+	if err := RestoreFile(fileName); err != ErrNoBackup {
+		t.Errorf("Expected %v got %v", ErrNoBackup, err)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	fileName := copyAsset(LeicaImg, t)
+	if err := copyFile(fileName, BackupFileName(fileName)); err != nil {
+		t.Fatalf("Could not copy file: %v", err)
+	}
+	src, _ := os.Stat(fileName)
+	dst, _ := os.Stat(BackupFileName(fileName))
+	if src.Mode() != dst.Mode() || !src.ModTime().Equal(dst.ModTime()) {
+		t.Errorf("Expected %v %v got %v %v", src.Mode(), src.ModTime(), dst.Mode(), dst.ModTime())
+	}
+	if err := copyFile(fileName, BackupFileName(fileName)); err == nil {
+		t.Errorf("Expected error got nil")
+	}
+}
@@ -0,0 +1,339 @@
+package metadata
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	xmpCreateDate            = "xmp:CreateDate"
+	xmpPsDateCreated         = "photoshop:DateCreated"
+	xmpExifDateTimeOriginal  = "exif:DateTimeOriginal"
+	xmpDateLayout            = "2006-01-02T15:04:05.999999999"
+	xmpDateLayoutZone        = "2006-01-02T15:04:05.999999999Z07:00"
+	xmpShortDateLayout       = "2006-01-02"
+	xmpDateLayoutMinutes     = "2006-01-02T15:04"
+	xmpDateLayoutMinutesZone = "2006-01-02T15:04Z07:00"
+)
+
+// exifDateTags holds the tags that together make up an ExifDate
+type exifDateTags struct {
+	ifd    ExifIndex
+	date   ExifTag
+	offset ExifTag
+	subSec ExifTag
+}
+
+var exifDates = map[ExifDate]exifDateTags{
+	OriginalDate:  {ExifIFD, ExifIFD_DateTimeOriginal, ExifIFD_OffsetTimeOriginal, ExifIFD_SubSecTimeOriginal},
+	ModifyDate:    {RootIFD, IFD_ModifyDate, ExifIFD_OffsetTime, ExifIFD_SubSecTime},
+	DigitizedDate: {ExifIFD, ExifIFD_CreateDate, ExifIFD_OffsetTimeDigitized, ExifIFD_SubSecTimeDigitized},
+}
+
+// ZoneSource describes how the time zone of an ExifTimestamp was determined
+type ZoneSource int
+
+// The different zone sources
+const (
+	// ZoneUnknown no time zone information was found. The time is given as UTC
+	ZoneUnknown ZoneSource = iota
+	// ZoneExplicit the time zone was read from the corresponding offset tag
+	ZoneExplicit
+	// ZoneGps the time zone was inferred from the difference to the (UTC) GPS time stamp
+	ZoneGps
+)
+
+func (zs ZoneSource) String() string {
+	switch zs {
+	case ZoneExplicit:
+		return "explicit"
+	case ZoneGps:
+		return "gps"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (zs ZoneSource) MarshalText() ([]byte, error) {
+	return []byte(zs.String()), nil
+}
+
+// ExifTimestamp is an exif date with sub second precision together with how its time zone was determined
+type ExifTimestamp struct {
+	Time time.Time  `json:"time"`
+	Zone ZoneSource `json:"zone"`
+}
+
+// maxZoneOffset is the largest offset from UTC used by any time zone
+const maxZoneOffset = 14 * time.Hour
+
+// subSecDuration parses an exif SubSecTime value (the digits of the fractional second)
+func subSecDuration(subSec string) time.Duration {
+	subSec = strings.TrimRight(strings.TrimSpace(subSec), "\x00")
+	if subSec == "" || !isDigits(subSec) {
+		return 0
+	}
+	if len(subSec) > 9 {
+		subSec = subSec[:9]
+	}
+	n, _ := strconv.Atoi(subSec + strings.Repeat("0", 9-len(subSec)))
+	return time.Duration(n)
+}
+
+func subSecString(t time.Time) string {
+	return strings.TrimRight(fmt.Sprintf("%09d", t.Nanosecond()), "0")
+}
+
+func (ed *ExifData) scanExifTimestamp(dateTag ExifDate) (ExifTimestamp, error) {
+	ret := ExifTimestamp{}
+	if ed.IsEmpty() {
+		return ret, ErrExifNoData
+	}
+	tags, found := exifDates[dateTag]
+	if !found {
+		return ret, fmt.Errorf("Unknown date to scan: %v", dateTag)
+	}
+	var t, o, s string
+	if err := ed.Scan(tags.ifd, tags.date, &t); err != nil {
+		return ret, err
+	}
+	_ = ed.ScanIfdExif(tags.offset, &o) //dont care about offset errors
+	_ = ed.ScanIfdExif(tags.subSec, &s)
+	var err error
+	if ret.Time, err = exifDateTime(t, o); err != nil {
+		return ret, err
+	}
+	ret.Time = ret.Time.Add(subSecDuration(s))
+	if o != "" {
+		ret.Zone = ZoneExplicit
+	}
+	return ret, nil
+}
+
+// gpsTime returns the GPS date and time stamp (UTC). If there is no GPSDateStamp the date of local is used
+// and hasDate is false
+func (ed *ExifData) gpsTime(local time.Time) (gps time.Time, hasDate bool, err error) {
+	hms := []URat{}
+	if err = ed.Scan(GpsIFD, GpsIFD_GPSTimeStamp, &hms); err != nil {
+		return
+	}
+	if len(hms) != 3 {
+		return gps, false, ErrExifParseTag
+	}
+	secs := 0.0
+	for i, f := range []float64{3600, 60, 1} {
+		if hms[i].Denominator == 0 {
+			return gps, false, ErrExifParseTag
+		}
+		secs += f * float64(hms[i].Numerator) / float64(hms[i].Denominator)
+	}
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	dateStr := ""
+	if e := ed.Scan(GpsIFD, GpsIFD_GPSDateStamp, &dateStr); e == nil {
+		if d, e := time.Parse("2006:01:02", strings.TrimSpace(dateStr)); e == nil {
+			day, hasDate = d, true
+		}
+	}
+	return day.Add(time.Duration(secs * float64(time.Second))), hasDate, nil
+}
+
+// gpsZoneOffset infers the zone offset (in seconds) of local (a wall clock time parsed as UTC) from the GPS time stamp.
+// Offsets are rounded to the nearest 15 minutes
+func (ed *ExifData) gpsZoneOffset(local time.Time) (int, bool) {
+	gps, hasDate, err := ed.gpsTime(local)
+	if err != nil {
+		return 0, false
+	}
+	diff := local.Sub(gps)
+	if !hasDate { //the gps time could be on the previous or next day
+		for diff > maxZoneOffset {
+			diff -= 24 * time.Hour
+		}
+		for diff < -12*time.Hour {
+			diff += 24 * time.Hour
+		}
+	}
+	diff = diff.Round(15 * time.Minute)
+	if diff > maxZoneOffset || diff < -12*time.Hour {
+		return 0, false
+	}
+	return int(diff.Seconds()), true
+}
+
+// ScanExifTimestamp reads the given dateTag (including sub seconds) into dest. If there is no offset tag the
+// time zone is inferred from the GPS time stamp. If that is not possible either the time is given as UTC and
+// the zone is set to ZoneUnknown
+func (ed *ExifData) ScanExifTimestamp(dateTag ExifDate, dest *ExifTimestamp) error {
+	ts, err := ed.scanExifTimestamp(dateTag)
+	if err != nil {
+		return err
+	}
+	if ts.Zone == ZoneUnknown {
+		if offset, ok := ed.gpsZoneOffset(ts.Time); ok {
+			t := ts.Time
+			ts.Time = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.FixedZone("", offset))
+			ts.Zone = ZoneGps
+		}
+	}
+	*dest = ts
+	return nil
+}
+
+// xmpDates are the xmp dates that are changed by ShiftDates
+var xmpDates = []string{xmpCreateDate, xmpPsDateCreated, xmpExifDateTimeOriginal}
+
+// parseXmpDate parses an xmp (ISO 8601) date and reports if it contained a time zone
+func parseXmpDate(value string) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	for _, l := range []string{xmpDateLayoutZone, xmpDateLayoutMinutesZone} {
+		if t, err := time.Parse(l, value); err == nil {
+			return t, true, nil
+		}
+	}
+	for _, l := range []string{xmpDateLayout, xmpDateLayoutMinutes, xmpShortDateLayout, "2006-01", "2006"} {
+		if t, err := time.Parse(l, value); err == nil {
+			return t, false, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("Could not parse xmp date: %s", value)
+}
+
+// formatXmpDate formats t as an xmp date. Date only values (as given by the original value) are kept as dates
+func formatXmpDate(t time.Time, hasZone bool, original string) string {
+	if !strings.Contains(original, "T") {
+		return t.Format(xmpShortDateLayout)
+	}
+	if hasZone {
+		return t.Format(xmpDateLayoutZone)
+	}
+	return t.Format(xmpDateLayout)
+}
+
+// shiftTime adds offset to t. If newZone is not nil the resulting wall clock time is moved to
+// newZone. Returns the new time and whether it has a known time zone
+func shiftTime(t time.Time, hasZone bool, offset time.Duration, newZone *time.Location) (time.Time, bool) {
+	t = t.Add(offset)
+	if newZone == nil {
+		return t, hasZone
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), newZone), true
+}
+
+// shiftDate shifts a date without a time by the whole days in offset. A shift of a few hours (e.g. a time zone
+// fix) should not move the date to another day
+func shiftDate(t time.Time, offset time.Duration) time.Time {
+	return t.AddDate(0, 0, int(offset/(24*time.Hour)))
+}
+
+// ShiftDates adds offset to the capture and modify dates in Exif (DateTimeOriginal, CreateDate and ModifyDate),
+// Iptc (DateCreated and DigitalCreationDate) and Xmp (xmp:CreateDate, photoshop:DateCreated and exif:DateTimeOriginal).
+// If newZone is not nil the shifted wall clock times are moved to newZone (and their offsets updated), e.g. to correct a
+// camera that was set to the wrong time zone. Dates without a time zone are kept without one unless newZone is given.
+// Dates without a time are only shifted by the whole days in offset. GPS timestamps are always UTC and are not changed
+func (je *JpegEditor) ShiftDates(offset time.Duration, newZone *time.Location) error {
+	b, err := je.Bytes()
+	if err != nil {
+		return err
+	}
+	sl, err := parseJpegBytes(b)
+	if err != nil {
+		return err
+	}
+	if ed, err := NewExifData(sl); err == nil {
+		if err = je.shiftExifDates(ed, offset, newZone); err != nil {
+			return err
+		}
+	}
+	if ipd, err := NewIptcData(sl); err == nil {
+		//xmp dates are shifted by the same offset
+		shift := func(*IptcEditor) error { return je.shiftIptcDates(ipd, offset, newZone) }
+		if err = je.syncIptc(shift); err != nil {
+			return err
+		}
+	}
+	return je.shiftXmpDates(offset, newZone)
+}
+
+func (je *JpegEditor) shiftExifDates(ed *ExifData, offset time.Duration, newZone *time.Location) error {
+	dates := []struct {
+		date      ExifDate
+		offsetTag ExifTag
+	}{
+		{OriginalDate, ExifIFD_OffsetTimeOriginal},
+		{DigitizedDate, ExifIFD_OffsetTimeDigitized},
+		{ModifyDate, ExifIFD_OffsetTime},
+	}
+	for _, d := range dates {
+		t := time.Time{}
+		if err := ed.ScanExifDate(d.date, &t); err != nil {
+			continue
+		}
+		o := ""
+		_ = ed.ScanIfdExif(d.offsetTag, &o)
+		st, hasZone := shiftTime(t, o != "", offset, newZone)
+		var err error
+		if hasZone {
+			err = je.ee.SetDate(d.date, st)
+		} else {
+			err = je.ee.setDateTime(d.date, st)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (je *JpegEditor) shiftIptcDates(ipd *IptcData, offset time.Duration, newZone *time.Location) error {
+	dates := []struct {
+		date    IptcDate
+		dateTag IptcTag
+		timeTag IptcTag
+	}{
+		{DateCreated, IPTCApplication_DateCreated, IPTCApplication_TimeCreated},
+		{DigitalCreationDate, IPTCApplication_DigitalCreationDate, IPTCApplication_DigitalCreationTime},
+	}
+	for _, d := range dates {
+		t := time.Time{}
+		if err := ipd.ScanDate(d.date, &t); err != nil {
+			continue
+		}
+		timeStr := ""
+		if err := ipd.ScanApplication(d.timeTag, &timeStr); err != nil || timeStr == "" {
+			//date only
+			if err = je.ie.setApplication(d.dateTag, shiftDate(t, offset).Format(IptcShortDate)); err != nil {
+				return err
+			}
+			continue
+		}
+		st, _ := shiftTime(t, true, offset, newZone)
+		if err := je.ie.SetDate(d.date, st); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (je *JpegEditor) shiftXmpDates(offset time.Duration, newZone *time.Location) error {
+	for _, path := range xmpDates {
+		value := je.xe.getPathString(path)
+		if value == "" {
+			continue
+		}
+		t, hasZone, err := parseXmpDate(value)
+		if err != nil {
+			continue
+		}
+		st, hasZone := shiftTime(t, hasZone, offset, newZone)
+		if !strings.Contains(value, "T") {
+			st = shiftDate(t, offset)
+		}
+		if err = je.xe.setPath(path, formatXmpDate(st, hasZone, value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
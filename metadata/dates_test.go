@@ -0,0 +1,189 @@
+package metadata
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseXmpDate(t *testing.T) {
+	tests := []struct {
+		value   string
+		hasZone bool
+	}{
+		{"2020-10-27T09:34:03+02:00", true},
+		{"2020-10-27T09:34:03.25Z", true},
+		{"2020-10-27T09:34+02:00", true},
+		{"2020-10-27T09:34:03", false},
+		{"2020-10-27", false},
+	}
+	for _, test := range tests {
+		_, hasZone, err := parseXmpDate(test.value)
+		if err != nil {
+			t.Errorf("Could not parse %s: %v", test.value, err)
+		}
+		if hasZone != test.hasZone {
+			t.Errorf("Expected %v got %v", test.hasZone, hasZone)
+		}
+	}
+	if _, _, err := parseXmpDate("not a date"); err == nil {
+		t.Errorf("Expected parse error")
+	}
+}
+
+func TestShiftTime(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	orig := time.Date(2020, 10, 27, 9, 34, 3, 0, time.FixedZone("", 2*60*60))
+	exp := time.Date(2020, 10, 27, 16, 34, 3, 0, tokyo)
+	act, hasZone := shiftTime(orig, false, 7*time.Hour, tokyo)
+	if !act.Equal(exp) || !hasZone {
+		t.Errorf("Expected %v got %v", exp, act)
+	}
+	act, hasZone = shiftTime(orig, false, -time.Hour, nil)
+	if !act.Equal(orig.Add(-time.Hour)) || hasZone {
+		t.Errorf("Expected %v got %v", orig.Add(-time.Hour), act)
+	}
+}
+
+func TestJpegEditor_ShiftDates(t *testing.T) {
+	je := getJpegEditor(LeicaImg, t)
+	created := time.Date(2020, 10, 27, 9, 34, 3, 0, time.FixedZone("", 2*60*60))
+	if err := je.Iptc().SetDate(DateCreated, created); err != nil {
+		t.Fatalf("Could not set iptc date: %v", err)
+	}
+	if err := je.xe.setPath(xmpPsDateCreated, "2020-10-27T09:34:03+02:00"); err != nil {
+		t.Fatalf("Could not set xmp date: %v", err)
+	}
+	je = reloadJpegEditor(je, true, t)
+	tokyo := time.FixedZone("", 9*60*60)
+	if err := je.ShiftDates(7*time.Hour, tokyo); err != nil {
+		t.Fatalf("Could not shift dates: %v", err)
+	}
+	exp := time.Date(2020, 10, 27, 16, 34, 3, 0, tokyo)
+	md := jpegEditorMD(je, t)
+	for _, d := range []ExifDate{OriginalDate, DigitizedDate} {
+		act := time.Time{}
+		if err := md.Exif().ScanExifDate(d, &act); err != nil {
+			t.Errorf("Could not scan date: %v", err)
+		}
+		if !act.Equal(exp) {
+			t.Errorf("Expected %v got %v", exp, act)
+		}
+		if _, offset := act.Zone(); offset != 9*60*60 {
+			t.Errorf("Expected %v got %v", 9*60*60, offset)
+		}
+	}
+	if act := md.Iptc().GetDate(DateCreated); !act.Equal(exp) {
+		t.Errorf("Expected %v got %v", exp, act)
+	}
+	expXmp := "2020-10-27T16:34:03+09:00"
+	if act := md.Xmp().getPathString(xmpPsDateCreated); act != expXmp {
+		t.Errorf("Expected %v got %v", expXmp, act)
+	}
+}
+
+func TestSubSecDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		exp   time.Duration
+	}{
+		{"5", 500 * time.Millisecond},
+		{"25", 250 * time.Millisecond},
+		{"123456789123", 123456789},
+		{"", 0},
+		{"ab", 0},
+	}
+	for _, test := range tests {
+		if act := subSecDuration(test.value); act != test.exp {
+			t.Errorf("Expected %v got %v", test.exp, act)
+		}
+	}
+}
+
+func TestExifData_ScanExifTimestamp(t *testing.T) {
+	ts := ExifTimestamp{}
+	if err := getExifData(GPSImg, t).ScanExifTimestamp(OriginalDate, &ts); err != nil {
+		t.Fatalf("Could not scan date: %v", err)
+	}
+	exp := time.Date(2018, 4, 28, 21, 23, 12, 0, time.FixedZone("", -4*60*60))
+	if !ts.Time.Equal(exp) || ts.Zone != ZoneGps {
+		t.Errorf("Expected %v (%v) got %v (%v)", exp, ZoneGps, ts.Time, ts.Zone)
+	}
+	if err := getExifData(LeicaImg, t).ScanExifTimestamp(OriginalDate, &ts); err != nil {
+		t.Fatalf("Could not scan date: %v", err)
+	}
+	if ts.Zone != ZoneExplicit {
+		t.Errorf("Expected %v got %v", ZoneExplicit, ts.Zone)
+	}
+	if err := getExifData(NikonImg, t).ScanExifTimestamp(OriginalDate, &ts); err != nil {
+		t.Fatalf("Could not scan date: %v", err)
+	}
+	if ts.Zone != ZoneUnknown || ts.Time.Location() != time.UTC {
+		t.Errorf("Expected %v got %v", ZoneUnknown, ts.Zone)
+	}
+}
+
+func TestExifEditor_SetDateSubSec(t *testing.T) {
+	je := getJpegEditor(LeicaImg, t)
+	exp := time.Date(2021, 3, 4, 10, 11, 12, 345000000, time.FixedZone("", 60*60))
+	if err := je.Exif().SetDate(OriginalDate, exp); err != nil {
+		t.Fatalf("Could not set date: %v", err)
+	}
+	md := jpegEditorMD(je, t)
+	ts := ExifTimestamp{}
+	if err := md.Exif().ScanExifTimestamp(OriginalDate, &ts); err != nil {
+		t.Fatalf("Could not scan date: %v", err)
+	}
+	if !ts.Time.Equal(exp) || ts.Zone != ZoneExplicit {
+		t.Errorf("Expected %v got %v", exp, ts.Time)
+	}
+	subSec := ""
+	if err := md.Exif().ScanIfdExif(ExifIFD_SubSecTimeOriginal, &subSec); err != nil || subSec != "345" {
+		t.Errorf("Expected %v got %v", "345", subSec)
+	}
+	//a whole second removes the previous fraction
+	je = reloadJpegEditor(je, true, t)
+	exp = exp.Truncate(time.Second)
+	if err := je.Exif().SetDate(OriginalDate, exp); err != nil {
+		t.Fatalf("Could not set date: %v", err)
+	}
+	md = jpegEditorMD(je, t)
+	if err := md.Exif().ScanExifTimestamp(OriginalDate, &ts); err != nil || !ts.Time.Equal(exp) {
+		t.Errorf("Expected %v got %v", exp, ts.Time)
+	}
+	if err := md.Exif().ScanIfdExif(ExifIFD_SubSecTimeOriginal, &subSec); err == nil {
+		t.Errorf("Expected no sub seconds got %v", subSec)
+	}
+}
+
+func TestJpegEditor_ShiftDatesDateOnly(t *testing.T) {
+	je := getJpegEditor(LeicaImg, t)
+	je.ie.Clear(true)
+	if err := je.ie.setApplication(IPTCApplication_DateCreated, "20201027"); err != nil {
+		t.Fatalf("Could not set iptc date: %v", err)
+	}
+	if err := je.xe.setPath(xmpPsDateCreated, "2020-10-27"); err != nil {
+		t.Fatalf("Could not set xmp date: %v", err)
+	}
+	je = reloadJpegEditor(je, true, t)
+	//a sub day shift (e.g. a time zone fix) should not move a date without a time
+	tests := []struct {
+		offset time.Duration
+		exp    string
+	}{
+		{-time.Hour, "2020-10-27"},
+		{-25 * time.Hour, "2020-10-26"},
+		{49 * time.Hour, "2020-10-28"},
+	}
+	for _, test := range tests {
+		if err := je.ShiftDates(test.offset, time.FixedZone("", -5*60*60)); err != nil {
+			t.Fatalf("Could not shift dates: %v", err)
+		}
+		md := jpegEditorMD(je, t)
+		if act := md.Iptc().GetDate(DateCreated).Format(xmpShortDateLayout); act != test.exp {
+			t.Errorf("Expected %v got %v", test.exp, act)
+		}
+		if act := md.Xmp().getPathString(xmpPsDateCreated); act != test.exp {
+			t.Errorf("Expected %v got %v", test.exp, act)
+		}
+	}
+}
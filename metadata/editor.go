@@ -1,22 +1,39 @@
 package metadata
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
+	"fmt"
 	"github.com/dsoprea/go-exif/v3"
 	jpegstructure "github.com/dsoprea/go-jpeg-image-structure/v2"
+	"github.com/msvens/mimage/makernote"
+	"github.com/msvens/mimage/photoshop"
+	"io"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 var errJpegWrongFileExt = errors.New("File does not end with .jpg or .jpeg")
 
+var (
+	ErrNoSource      = errors.New("Editor was not created from a file")
+	ErrSourceChanged = errors.New("Source file has changed since it was read")
+	//ErrMakerNoteRelocation if the exif rewrite moved a maker note whose offsets could not be fixed
+	ErrMakerNoteRelocation = errors.New("Maker note could not be relocated")
+)
+
 // JpegEditor holds the exif, xmp and iptc editors as well as the jpeg segment list
 type JpegEditor struct {
 	sl *jpegstructure.SegmentList
 	xe *XmpEditor
 	ee *ExifEditor
 	ie *IptcEditor
+	//source file and, for header only editors, the offset of the scan data in source
+	source     string
+	sourceInfo os.FileInfo
+	scanOffset int64
 }
 
 // NewJpegEditorFile from a jpeg image file
@@ -25,7 +42,97 @@ func NewJpegEditorFile(fileName string) (*JpegEditor, error) {
 	if err != nil {
 		return nil, err
 	}
-	return NewJpegEditor(b)
+	je, err := NewJpegEditor(b)
+	if err != nil {
+		return je, err
+	}
+	return je, je.setSource(fileName)
+}
+
+// NewJpegEditorFileHeader from a jpeg image file. Only the header segments (everything up to the start of the
+// entropy coded image data) are read. When the editor is written the image data is streamed from fileName, which
+// avoids reading large images into memory
+func NewJpegEditorFileHeader(fileName string) (*JpegEditor, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	header, size, err := readJpegHeader(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	//terminate the header with an EOI marker so it can be parsed as a jpeg without image data
+	je, err := NewJpegEditor(append(header, 0xff, markerEOI))
+	if err != nil {
+		return je, err
+	}
+	je.scanOffset = size
+	return je, je.setSource(fileName)
+}
+
+const (
+	markerSOI = 0xd8
+	markerEOI = 0xd9
+	markerSOS = 0xda
+)
+
+// readJpegHeader reads all segments up to and including the start of scan segment. Fill bytes before markers
+// are dropped from the header so the returned size is the number of bytes read from r (the offset of the image data)
+func readJpegHeader(r *bufio.Reader) ([]byte, int64, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil || header[0] != 0xff || header[1] != markerSOI {
+		return nil, 0, ErrParseImage
+	}
+	size := int64(len(header))
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, 0, ErrParseImage
+		}
+		if b != 0xff {
+			return nil, 0, ErrParseImage
+		}
+		marker, err := r.ReadByte()
+		size += 2
+		for err == nil && marker == 0xff { //fill bytes
+			marker, err = r.ReadByte()
+			size++
+		}
+		if err != nil || marker == markerEOI {
+			return nil, 0, ErrParseImage
+		}
+		header = append(header, 0xff, marker)
+		if marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) { //markers without a length
+			continue
+		}
+		length := make([]byte, 2)
+		if _, err = io.ReadFull(r, length); err != nil {
+			return nil, 0, ErrParseImage
+		}
+		n := int(length[0])<<8 | int(length[1])
+		if n < 2 {
+			return nil, 0, ErrParseImage
+		}
+		segment := make([]byte, n-2)
+		if _, err = io.ReadFull(r, segment); err != nil {
+			return nil, 0, ErrParseImage
+		}
+		size += int64(n)
+		header = append(append(header, length...), segment...)
+		if marker == markerSOS {
+			return header, size, nil
+		}
+	}
+}
+
+func (je *JpegEditor) setSource(fileName string) error {
+	fi, err := os.Stat(fileName)
+	if err != nil {
+		return err
+	}
+	je.source, je.sourceInfo = fileName, fi
+	return nil
 }
 
 // NewJpegEditor from a jpeg image byte slice
@@ -54,6 +161,18 @@ func (je *JpegEditor) appendSegment(idx int, s *jpegstructure.Segment) {
 	je.sl = jpegstructure.NewSegmentList(newS)
 }
 
+// removeSegments removes the segments at indexes (in increasing order)
+func (je *JpegEditor) removeSegments(indexes []int) {
+	if len(indexes) == 0 {
+		return
+	}
+	newS := je.sl.Segments()
+	for i := len(indexes) - 1; i >= 0; i-- {
+		newS = append(newS[:indexes[i]], newS[indexes[i]+1:]...)
+	}
+	je.sl = jpegstructure.NewSegmentList(newS)
+}
+
 // Bytes return jpeg image bytes from this editor. Any edits will be committed
 func (je *JpegEditor) Bytes() ([]byte, error) {
 	if je.ie.IsDirty() {
@@ -88,10 +207,9 @@ func (je *JpegEditor) CopyMetaData(sourceImg []byte) error {
 	if err = je.DropIptc(); err != nil {
 		return err
 	}
-	if _, s, e := sl.FindIptc(); e == nil {
-		je.appendSegment(1, s)
-	} else if e != jpegstructure.ErrNoIptc {
-		return e
+	indexes, _, _ := photoshop.ParseJpegSegments(sl)
+	for i, idx := range indexes {
+		je.appendSegment(1+i, sl.Segments()[idx])
 	}
 
 	//copy XmpEditor
@@ -142,6 +260,7 @@ func (je *JpegEditor) DropExif() error {
 
 // DropXmp removes xmp data from this editor
 func (je *JpegEditor) DropXmp() error {
+	je.sl = dropXmpExtensions(je.sl)
 	i, _, err := je.sl.FindXmp()
 	if err == nil {
 		segments := je.sl.Segments()
@@ -157,16 +276,10 @@ func (je *JpegEditor) DropXmp() error {
 	}
 }
 
-// DropIptc removes iptc data from this editor
+// DropIptc removes iptc data (all photoshop segments) from this editor
 func (je *JpegEditor) DropIptc() error {
-	i, _, err := je.sl.FindIptc()
-	if err == nil {
-		segments := je.sl.Segments()
-		segments = append(segments[:i], segments[i+1:]...)
-		je.sl = jpegstructure.NewSegmentList(segments)
-	} else if err == jpegstructure.ErrNoIptc {
-		return nil
-	}
+	indexes, _, _ := photoshop.ParseJpegSegments(je.sl)
+	je.removeSegments(indexes)
 	return nil
 }
 
@@ -197,74 +310,195 @@ func (je *JpegEditor) setExif() error {
 	if err := je.sl.SetExif(builder); err != nil {
 		return err
 	}
+	return je.relocateMakerNote()
+}
+
+// relocateMakerNote fixes the offsets of maker notes that are relative to the exif header since the rewritten
+// exif most likely moved the maker note. Returns ErrMakerNoteRelocation if a moved note can not be relocated
+// (unknown vendor or broken ifd) rather than writing a maker note with offsets pointing at the old position
+func (je *JpegEditor) relocateMakerNote() error {
+	if je.ee.makerNoteOffset < 0 {
+		return nil
+	}
+	_, rawExif, err := je.sl.Exif()
+	if err != nil {
+		return err
+	}
+	if _, err = makernote.Relocate(rawExif, je.ee.makerNoteOffset); err != nil {
+		return fmt.Errorf("%w: %v", ErrMakerNoteRelocation, err)
+	}
 	return nil
 }
 
 func (je *JpegEditor) setIptc() error {
-	//MarkerId: MARKER_APP13
-	iptcBytes, err := je.ie.Bytes()
+	//MarkerId: MARKER_APP13. Large photoshop blocks are split over several segments
+	blocks, err := je.ie.segments()
 	if err != nil {
 		return err
 	}
-	if je.ie.segmentIdx != -1 {
-		s := je.sl.Segments()[je.ie.segmentIdx]
-		s.Data = iptcBytes
-		return nil
+	if digest := je.ie.Digest(); digest != "" {
+		if err = je.xe.SetLegacyIptcDigest(digest); err != nil {
+			return err
+		}
+	} else if je.xe.getPathString(xmpPsLegacyIptcDigest) != "" {
+		if err = je.xe.deletePath(xmpPsLegacyIptcDigest); err != nil {
+			return err
+		}
+	}
+	//replace the existing segments or add after the first segment
+	idx := 1
+	if indexes, _, _ := photoshop.ParseJpegSegments(je.sl); len(indexes) > 0 {
+		idx = indexes[0]
+		je.removeSegments(indexes)
+	}
+	for i, b := range blocks {
+		je.appendSegment(idx+i, &jpegstructure.Segment{MarkerId: jpegstructure.MARKER_APP13, Data: b})
 	}
-	iptcS := &jpegstructure.Segment{MarkerId: jpegstructure.MARKER_APP13, Data: iptcBytes}
-	je.appendSegment(1, iptcS)
-	je.ie.segmentIdx = 1
 	return nil
 
 }
 
+// syncIptc runs iptc edits that are mirrored in xmp. Such edits keep the iptc digest (see mwg) while any other
+// iptc edit removes it when the iptc is written
+func (je *JpegEditor) syncIptc(edit func(ie *IptcEditor) error) error {
+	unsynced := je.ie.unsynced
+	err := edit(je.ie)
+	je.ie.unsynced = unsynced
+	return err
+}
+
+// SetDescription sets the description (caption) in Xmp (dc:description), Iptc (Caption-Abstract) and
+// ImageDescription in Exif
+func (je *JpegEditor) SetDescription(description string) error {
+	je.Xmp().SetDescription(description)
+	if err := je.ee.SetImageDescription(description); err != nil {
+		return err
+	}
+	return je.syncIptc(func(ie *IptcEditor) error { return ie.SetDescription(description) })
+}
+
 // SetKeywords sets the keywords in Xmp and Iptc
 func (je *JpegEditor) SetKeywords(keywords []string) error {
 	je.Xmp().SetKeywords(keywords)
-	return je.ie.SetKeywords(keywords)
+	return je.syncIptc(func(ie *IptcEditor) error { return ie.SetKeywords(keywords) })
 }
 
-// SetTitle sets title in Xmp and Iptc and ImageDescription in Exif
+// SetTitle sets title in Xmp and Iptc. Exif has no title field (ImageDescription is set by SetDescription)
 func (je *JpegEditor) SetTitle(title string) error {
 	je.Xmp().SetTitle(title)
-	if err := je.ee.SetImageDescription(title); err != nil {
-		return err
-	}
-	return je.Iptc().SetTitle(title)
-
+	return je.syncIptc(func(ie *IptcEditor) error { return ie.SetTitle(title) })
 }
 
 func (je *JpegEditor) setXmp() error {
-	xmpBytes, err := je.xe.Bytes(true)
+	standard, extended, err := je.xe.packets()
 	if err != nil {
 		return err
 	}
-	_, s, err := je.sl.FindXmp()
+	xmpBytes := append(append([]byte{}, xmpPrefix...), standard...)
+	je.xe.packetSize = len(standard)
+	je.sl = dropXmpExtensions(je.sl)
+	i, s, err := je.sl.FindXmp()
 	if err == nil { //replace existing XmpEditor data
 		s.Data = xmpBytes
-		return nil
 	} else if err == jpegstructure.ErrNoXmp { //add XmpEditor data
+		i = 1
 		xmpS := &jpegstructure.Segment{MarkerId: jpegstructure.MARKER_APP1, Data: xmpBytes}
-		je.appendSegment(1, xmpS)
-		return nil
+		je.appendSegment(i, xmpS)
 	} else {
 		return err
 	}
+	//extended xmp follows the standard xmp
+	for j, s := range xmpExtensionSegments(extended) {
+		je.appendSegment(i+1+j, s)
+	}
+	return nil
+}
+
+// UpdateFile writes this editor back to the file it was created from by first committing any edits. The image is
+// written to a temporary file in the same directory that replaces the source once it has been completely written, so
+// the source is never left partially written. The file mode is kept and if preserveModTime is set also the
+// modification time
+func (je *JpegEditor) UpdateFile(preserveModTime bool) error {
+	if je.source == "" {
+		return ErrNoSource
+	}
+	if err := writeFileAtomic(je.source, je.sourceInfo.Mode().Perm(), je.write); err != nil {
+		return err
+	}
+	if preserveModTime {
+		return os.Chtimes(je.source, time.Now(), je.sourceInfo.ModTime())
+	}
+	return nil
 }
 
-// WriteFile writes this editor to file by first committing any edits. Any existing
-// file will be truncated. Destination needs to have jpg or jpeg extension
+// WriteFile writes this editor to file by first committing any edits. The image is written to a temporary file
+// that replaces dest once it has been completely written. The mode of an existing dest is kept.
+// Destination needs to have jpg or jpeg extension
 func (je *JpegEditor) WriteFile(dest string) error {
 	//make sure dest has the right file extension
 	if filepath.Ext(dest) != ".jpg" && filepath.Ext(dest) != ".jpeg" {
 		return errJpegWrongFileExt
 	}
-	out, err := je.Bytes()
+	perm := os.FileMode(0644)
+	if fi, err := os.Stat(dest); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	return writeFileAtomic(dest, perm, je.write)
+}
+
+// writeFileAtomic writes to a temporary file that is renamed to dest once write has completed
+func writeFileAtomic(dest string, perm os.FileMode, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err = write(tmp); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(dest, out, 0644)
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
 
+// write writes this editor to w. For header only editors the new header is followed by the image data of the source
+func (je *JpegEditor) write(w io.Writer) error {
+	out, err := je.Bytes()
+	if err != nil {
+		return err
+	}
+	if je.scanOffset == 0 {
+		_, err = w.Write(out)
+		return err
+	}
+	src, err := os.Open(je.source)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if fi, err := src.Stat(); err != nil {
+		return err
+	} else if fi.Size() != je.sourceInfo.Size() || !fi.ModTime().Equal(je.sourceInfo.ModTime()) {
+		return ErrSourceChanged
+	}
+	//drop the EOI marker that terminates the header
+	if _, err = w.Write(out[:len(out)-2]); err != nil {
+		return err
+	}
+	if _, err = src.Seek(je.scanOffset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, src); err != nil {
+		return fmt.Errorf("could not copy image data: %w", err)
+	}
+	return nil
 }
 
 // Xmp returns the xmp editor
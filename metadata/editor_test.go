@@ -1,8 +1,12 @@
 package metadata
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"github.com/msvens/mimage/makernote"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -354,8 +358,41 @@ func TestJpegEditor_SetTitle(t *testing.T) {
 		if md.Xmp().GetTitle() != expTitle {
 			t.Errorf("Expected title %s got %s", expTitle, md.Xmp().GetTitle())
 		}
-		if md.Exif().GetImageDescription() != expTitle {
-			t.Errorf("Expected title %s got %s", expTitle, md.Xmp().GetTitle())
+		if md.Exif().GetImageDescription() == expTitle {
+			t.Errorf("Expected title not to be written to exif image description")
+		}
+	}
+}
+
+func TestJpegEditor_SetDescription(t *testing.T) {
+	fnames := []string{LeicaImg, NoExifImg}
+	for _, fname := range fnames {
+		je := getJpegEditor(fname, t)
+		expDesc := "First paragraph of the caption.\n\nSecond paragraph of the caption."
+		if err := je.SetTitle("Title"); err != nil {
+			t.Fatalf("Could not set title for image %s got error %v", fname, err)
+		}
+		if err := je.SetDescription(expDesc); err != nil {
+			t.Fatalf("Could not set description for image %s got error %v", fname, err)
+		}
+		md := jpegEditorMD(je, t)
+		if md.Iptc().GetDescription() != expDesc {
+			t.Errorf("Expected description %s got %s", expDesc, md.Iptc().GetDescription())
+		}
+		if md.Xmp().GetDescription() != expDesc {
+			t.Errorf("Expected description %s got %s", expDesc, md.Xmp().GetDescription())
+		}
+		if md.Exif().GetImageDescription() != expDesc {
+			t.Errorf("Expected description %s got %s", expDesc, md.Exif().GetImageDescription())
+		}
+		if md.Summary().Description != expDesc {
+			t.Errorf("Expected description %s got %s", expDesc, md.Summary().Description)
+		}
+		if md.Summary().Title != "Title" {
+			t.Errorf("Expected title %s got %s", "Title", md.Summary().Title)
+		}
+		if len(md.Conflicts()) != 0 {
+			t.Errorf("Expected no conflicts got %v", md.Conflicts())
 		}
 	}
 }
@@ -381,6 +418,15 @@ func TestJpegEditor_WriteFile(t *testing.T) {
 		t.Errorf("Could not delete temp file: %v", err)
 	}
 
+	//existing file mode should be kept
+	dest := copyAsset(LeicaImg, t)
+	if err := je.WriteFile(dest); err != nil {
+		t.Fatalf("Could not write file: %v", err)
+	}
+	if fi, _ := os.Stat(dest); fi.Mode().Perm() != 0640 {
+		t.Errorf("Expected %v got %v", os.FileMode(0640), fi.Mode().Perm())
+	}
+
 	//test wrong file extension
 	wrongOut := filepath.Join(os.TempDir(), "TestWriteFile.png")
 	if err := je.WriteFile(wrongOut); err == nil {
@@ -390,6 +436,122 @@ func TestJpegEditor_WriteFile(t *testing.T) {
 	}
 }
 
+func copyAsset(asset string, t *testing.T) string {
+	b := getAssetBytes(asset, t)
+	dest := filepath.Join(t.TempDir(), filepath.Base(asset))
+	if err := os.WriteFile(dest, b, 0640); err != nil {
+		t.Fatalf("Could not copy asset: %v", err)
+	}
+	return dest
+}
+
+func TestNewJpegEditorFileHeader(t *testing.T) {
+	for _, asset := range []string{LeicaImg, NikonImg, NoExifImg} {
+		b := getAssetBytes(asset, t)
+		je, err := NewJpegEditorFileHeader(asset)
+		if err != nil {
+			t.Fatalf("Could not create header editor: %v", err)
+		}
+		//the header followed by the image data should be the original image
+		header, _ := je.Bytes()
+		header = header[:len(header)-2]
+		if !bytes.HasPrefix(b, header) || int64(len(header)) != je.scanOffset {
+			t.Errorf("Expected header of %v to be a prefix of the image", asset)
+		}
+		md, err := je.MetaData()
+		if err != nil {
+			t.Fatalf("Could not get metadata: %v", err)
+		}
+		if exp := getMetaData(asset, t).Summary(); !reflect.DeepEqual(exp, md.Summary()) {
+			t.Errorf("Expected %v got %v", exp, md.Summary())
+		}
+	}
+	if _, err := NewJpegEditorFileHeader(AssetPath + "xmp.xml"); err == nil {
+		t.Errorf("Expected error got nil")
+	}
+}
+
+func TestJpegEditor_UpdateFile(t *testing.T) {
+	dest := copyAsset(LeicaImg, t)
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(dest, modTime, modTime); err != nil {
+		t.Fatalf("Could not set modification time: %v", err)
+	}
+	je, err := NewJpegEditorFileHeader(dest)
+	if err != nil {
+		t.Fatalf("Could not create header editor: %v", err)
+	}
+	if err = je.SetTitle("updated title"); err != nil {
+		t.Fatalf("Could not set title: %v", err)
+	}
+	if err = je.UpdateFile(true); err != nil {
+		t.Fatalf("Could not update file: %v", err)
+	}
+	if title := getMetaData(dest, t).Summary().Title; title != "updated title" {
+		t.Errorf("Expected %v got %v", "updated title", title)
+	}
+	//image data should be unchanged
+	orig, got := getAssetBytes(LeicaImg, t), getAssetBytes(dest, t)
+	scanData := orig[je.scanOffset:]
+	if !bytes.HasSuffix(got, scanData) {
+		t.Errorf("Expected image data to be unchanged")
+	}
+	fi, err := os.Stat(dest)
+	if err != nil {
+		t.Fatalf("Could not stat file: %v", err)
+	}
+	if !fi.ModTime().Equal(modTime) {
+		t.Errorf("Expected %v got %v", modTime, fi.ModTime())
+	}
+	if fi.Mode().Perm() != 0640 {
+		t.Errorf("Expected %v got %v", os.FileMode(0640), fi.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(dest)); len(entries) != 1 {
+		t.Errorf("Expected %v got %v", 1, len(entries))
+	}
+	//the editor no longer matches the source
+	if err = je.UpdateFile(false); err != ErrSourceChanged {
+		t.Errorf("Expected %v got %v", ErrSourceChanged, err)
+	}
+	je, _ = NewJpegEditor(getAssetBytes(LeicaImg, t))
+	if err = je.UpdateFile(false); err != ErrNoSource {
+		t.Errorf("Expected %v got %v", ErrNoSource, err)
+	}
+}
+
+func TestJpegEditor_UpdateFileFillBytes(t *testing.T) {
+	orig := getAssetBytes(LeicaImg, t)
+	//fill bytes before the first marker after SOI
+	padded := append([]byte{0xff, markerSOI, 0xff, 0xff, 0xff}, orig[2:]...)
+	dest := filepath.Join(t.TempDir(), "padded.jpg")
+	if err := os.WriteFile(dest, padded, 0640); err != nil {
+		t.Fatalf("Could not write file: %v", err)
+	}
+	je, err := NewJpegEditorFileHeader(dest)
+	if err != nil {
+		t.Fatalf("Could not create header editor: %v", err)
+	}
+	_, origOffset, _ := readJpegHeader(bufio.NewReader(bytes.NewReader(orig)))
+	if je.scanOffset != origOffset+3 {
+		t.Errorf("Expected %v got %v", origOffset+3, je.scanOffset)
+	}
+	if err = je.SetTitle("padded title"); err != nil {
+		t.Fatalf("Could not set title: %v", err)
+	}
+	if err = je.UpdateFile(false); err != nil {
+		t.Fatalf("Could not update file: %v", err)
+	}
+	//the image data should follow directly after the new header
+	got := getAssetBytes(dest, t)
+	_, gotOffset, err := readJpegHeader(bufio.NewReader(bytes.NewReader(got)))
+	if err != nil || !bytes.Equal(got[gotOffset:], orig[origOffset:]) {
+		t.Errorf("Expected image data to be unchanged")
+	}
+	if title := getMetaData(dest, t).Summary().Title; title != "padded title" {
+		t.Errorf("Expected %v got %v", "padded title", title)
+	}
+}
+
 func ExampleJpegEditor_SetTitle() {
 	je, err := NewJpegEditorFile("../assets/leica.jpg")
 	if err != nil {
@@ -409,3 +571,121 @@ func ExampleJpegEditor_SetTitle() {
 
 	//Output: New Title: some new title
 }
+
+// exifEntries returns the raw bytes of all exif entries (by ifd and tag) except offsets and Software
+func exifEntries(md *MetaData) map[string][]byte {
+	skip := map[uint16]bool{uint16(IFD_Software): true, uint16(IFD_ExifOffset): true, uint16(IFD_GPSInfo): true,
+		uint16(ExifIFD_InteropOffset): true, uint16(IFD_ThumbnailOffset): true, uint16(IFD_ThumbnailLength): true}
+	ret := map[string][]byte{}
+	if md.Exif().IsEmpty() {
+		return ret
+	}
+	for _, ifd := range md.Exif().rawExif.Ifds {
+		for _, e := range ifd.Entries() {
+			if skip[e.TagId()] {
+				continue
+			}
+			raw, _ := e.GetRawBytes()
+			ret[fmt.Sprintf("%s/%#04x", ifd.IfdIdentity().String(), e.TagId())] = raw
+		}
+	}
+	return ret
+}
+
+func TestJpegEditor_ExifRoundTrip(t *testing.T) {
+	files, err := filepath.Glob(AssetPath + "*.jpg")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("Could not find assets: %v", err)
+	}
+	for _, fname := range files {
+		exp := exifEntries(getMetaData(fname, t))
+		je := getJpegEditor(fname, t)
+		je.Exif().SetDirty()
+		act := exifEntries(jpegEditorMD(je, t))
+		for k, v := range exp {
+			if !bytes.Equal(v, act[k]) {
+				t.Errorf("%s: Expected %v got %v for %s", fname, v, act[k], k)
+			}
+		}
+	}
+}
+
+// canonMakerNote creates a canon maker note (with offsets relative to the exif header) holding a lens model
+func canonMakerNote(order binary.ByteOrder, offset int, lensModel string) []byte {
+	value := append([]byte(lensModel), 0)
+	note := make([]byte, 18, 18+len(value))
+	order.PutUint16(note, 1)
+	order.PutUint16(note[2:], 0x0095)
+	order.PutUint16(note[4:], 2)
+	order.PutUint32(note[6:], uint32(len(value)))
+	order.PutUint32(note[10:], uint32(offset+18))
+	return append(note, value...)
+}
+
+func TestJpegEditor_PreserveMakerNote(t *testing.T) {
+	lensModel := "EF50mm f/1.8 STM"
+	placeholder := bytes.Repeat([]byte{0xab}, 18+len(lensModel)+1)
+	je := getJpegEditor(LeicaImg, t)
+	if err := je.Exif().SetIfdRootTag(IFD_Make, "Canon"); err != nil {
+		t.Fatalf("Could not set make: %v", err)
+	}
+	if err := je.Exif().SetIfdExifTag(ExifIFD_MakerNote, placeholder); err != nil {
+		t.Fatalf("Could not set maker note: %v", err)
+	}
+	b, err := je.Bytes()
+	if err != nil {
+		t.Fatalf("Could not get bytes: %v", err)
+	}
+	//replace the placeholder with a maker note that is valid at its position
+	tiffStart := bytes.Index(b, []byte("Exif\x00\x00")) + 6
+	pos := bytes.Index(b, placeholder)
+	var order binary.ByteOrder = binary.BigEndian
+	if string(b[tiffStart:tiffStart+2]) == "II" {
+		order = binary.LittleEndian
+	}
+	copy(b[pos:], canonMakerNote(order, pos-tiffStart, lensModel))
+	md, err := NewMetaData(b)
+	if err != nil {
+		t.Fatalf("Could not read metadata: %v", err)
+	}
+	if mn, err := md.Exif().MakerNote(); err != nil || mn.LensModel != lensModel {
+		t.Fatalf("Expected %v got %v (%v)", lensModel, mn, err)
+	}
+
+	//edit exif so that the maker note is moved
+	if je, err = NewJpegEditor(b); err != nil {
+		t.Fatalf("Could not create editor: %v", err)
+	}
+	if err = je.Exif().SetImageDescription(string(bytes.Repeat([]byte("a"), 200))); err != nil {
+		t.Fatalf("Could not set description: %v", err)
+	}
+	md = jpegEditorMD(je, t)
+	oldOffset, _, _ := makernote.Locate(b[tiffStart:])
+	if offset, _, _ := makernote.Locate(md.Exif().raw); offset == oldOffset {
+		t.Errorf("Expected maker note to be moved")
+	}
+	mn, err := md.Exif().MakerNote()
+	if err != nil {
+		t.Fatalf("Could not parse maker note: %v", err)
+	}
+	if mn.LensModel != lensModel {
+		t.Errorf("Expected %v got %v", lensModel, mn.LensModel)
+	}
+	if md.Summary().LensModel == "" {
+		t.Errorf("Expected lens model from exif or maker note")
+	}
+
+	//a moved maker note of an unknown vendor can not be relocated and should not be written
+	if je, err = NewJpegEditor(b); err != nil {
+		t.Fatalf("Could not create editor: %v", err)
+	}
+	if err = je.Exif().SetIfdRootTag(IFD_Make, "Acme Camera Company"); err != nil {
+		t.Fatalf("Could not set make: %v", err)
+	}
+	if err = je.Exif().SetImageDescription(string(bytes.Repeat([]byte("a"), 200))); err != nil {
+		t.Fatalf("Could not set description: %v", err)
+	}
+	if _, err = je.Bytes(); !errors.Is(err, ErrMakerNoteRelocation) {
+		t.Errorf("Expected %v got %v", ErrMakerNoteRelocation, err)
+	}
+}
@@ -1,12 +1,14 @@
 package metadata
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"github.com/dsoprea/go-exif/v3"
 	exifcommon "github.com/dsoprea/go-exif/v3/common"
 	exifundefined "github.com/dsoprea/go-exif/v3/undefined"
 	jpegstructure "github.com/dsoprea/go-jpeg-image-structure/v2"
+	"github.com/msvens/mimage/makernote"
 	"strings"
 	"time"
 )
@@ -18,6 +20,7 @@ var (
 	ErrExifValueNotFound = errors.New("Ifd value not found")
 	ErrExifParseTag      = errors.New("Exif tag could not be parsed")
 	ErrExifUndefinedType = errors.New("Tag type undefined")
+	ErrExifIfdLoop       = errors.New("Exif ifd offsets form a loop")
 )
 
 // Exif Time formats
@@ -273,18 +276,39 @@ func ExifValueStringErr(index ExifIndex, tag ExifTag, value interface{}) (string
 // ExifData holds the underlying IfdIndex
 type ExifData struct {
 	rawExif *exif.IfdIndex
+	raw     []byte
 }
 
 // NewExifData creates an ExifData from a jpeg segment list. Returns
 // ErrExifNoData if the segment list did not contain any exif data
 func NewExifData(segments *jpegstructure.SegmentList) (*ExifData, error) {
-	var rawExif []byte
-	var ifdMapping *exifcommon.IfdMapping
-	var err error
-	if _, rawExif, err = segments.Exif(); err != nil {
-		return &ExifData{}, ErrExifNoData
+	rawExif, err := findRawExif(segments)
+	if err != nil {
+		return &ExifData{}, err
+	}
+	return newExifData(rawExif)
+}
+
+var exifHeader = []byte("Exif\000\000")
+
+// findRawExif returns the tiff block of the first exif segment without parsing it (SegmentList.Exif
+// never returns if the ifd offsets form a loop)
+func findRawExif(segments *jpegstructure.SegmentList) ([]byte, error) {
+	for _, s := range segments.Segments() {
+		if s.MarkerId == jpegstructure.MARKER_APP1 && bytes.HasPrefix(s.Data, exifHeader) {
+			return s.Data[len(exifHeader):], nil
+		}
 	}
-	if ifdMapping, err = exifcommon.NewIfdMappingWithStandard(); err != nil {
+	return nil, ErrExifNoData
+}
+
+// newExifData parses an exif tiff block
+func newExifData(rawExif []byte) (*ExifData, error) {
+	if hasIfdLoop(rawExif) {
+		return &ExifData{}, ErrExifIfdLoop
+	}
+	ifdMapping, err := exifcommon.NewIfdMappingWithStandard()
+	if err != nil {
 		return nil, err
 	}
 	ti := exif.NewTagIndex()
@@ -296,7 +320,16 @@ func NewExifData(segments *jpegstructure.SegmentList) (*ExifData, error) {
 	if err != nil {
 		return &ExifData{}, err
 	}
-	return &ExifData{&index}, nil
+	return &ExifData{rawExif: &index, raw: rawExif}, nil
+}
+
+// MakerNote parses the vendor specific maker note (ExifIFD_MakerNote). Returns makernote.ErrNoMakerNote
+// if there is no maker note and makernote.ErrUnknownVendor if the vendor is not supported
+func (ed *ExifData) MakerNote() (*makernote.MakerNote, error) {
+	if ed.IsEmpty() {
+		return nil, ErrExifNoData
+	}
+	return makernote.Parse(ed.raw)
 }
 
 // IsEmpty returns true if the underlying IfdIndex is nil
@@ -507,30 +540,39 @@ func scanMultipleExifValue(_ ExifTagDesc, source interface{}, dest interface{})
 	return nil
 }
 
-// Scan reads the specific tag from index into dest
-func (ed *ExifData) Scan(ifdIndex ExifIndex, tagId ExifTag, dest interface{}) error {
+// value returns the tag description together with the raw (go-exif) value of tagId in ifdIndex
+func (ed *ExifData) value(ifdIndex ExifIndex, tagId ExifTag) (ExifTagDesc, interface{}, error) {
 	if ed.IsEmpty() {
-		return ErrExifNoData
+		return ExifTagDesc{}, nil, ErrExifNoData
 	}
 	tagDesc, found := ExifTagDescriptions[ExifIndexTag{ifdIndex, tagId}]
 	if !found {
-		return ErrExifTagNotFound
+		return tagDesc, nil, ErrExifTagNotFound
 	}
 	ifd, found := ed.rawExif.Lookup[IFDPaths[ifdIndex]]
 	if !found {
-		return ErrExifTagNotFound
+		return tagDesc, nil, ErrExifTagNotFound
 	}
 	entries, err := ifd.FindTagWithId(uint16(tagId))
 	if err != nil {
-		return ErrExifTagNotFound
+		return tagDesc, nil, ErrExifTagNotFound
 	}
 	if len(entries) < 1 {
-		return ErrExifValueNotFound
+		return tagDesc, nil, ErrExifValueNotFound
 	}
 
 	value, err := entries[0].Value()
 	if err != nil {
-		return ErrExifValueNotFound
+		return tagDesc, nil, ErrExifValueNotFound
+	}
+	return tagDesc, value, nil
+}
+
+// Scan reads the specific tag from index into dest
+func (ed *ExifData) Scan(ifdIndex ExifIndex, tagId ExifTag, dest interface{}) error {
+	tagDesc, value, err := ed.value(ifdIndex, tagId)
+	if err != nil {
+		return err
 	}
 
 	if tagDesc.Count == 1 || tagDesc.Type == ExifString || tagDesc.Type == ExifUndef {
@@ -540,44 +582,14 @@ func (ed *ExifData) Scan(ifdIndex ExifIndex, tagId ExifTag, dest interface{}) er
 	return scanMultipleExifValue(tagDesc, value, dest)
 }
 
-// ScanExifDate reads the given dateTag into dest
+// ScanExifDate reads the given dateTag (including sub seconds) into dest. If the date has no
+// corresponding offset tag it is read as UTC. Use ScanExifTimestamp to also infer the time zone
 func (ed *ExifData) ScanExifDate(dateTag ExifDate, dest *time.Time) error {
-	if ed.IsEmpty() {
-		return ErrExifNoData
-	}
-	var t, o string
-	var err error
-	switch dateTag {
-	case OriginalDate:
-		if err = ed.ScanIfdExif(ExifIFD_DateTimeOriginal, &t); err != nil {
-			return err
-		}
-		_ = ed.ScanIfdExif(ExifIFD_OffsetTimeOriginal, &o) //dont care about offset errors
-		*dest, err = exifDateTime(t, o)
-		if err != nil {
-			return err
-		}
-	case ModifyDate:
-		if err = ed.ScanIfdRoot(IFD_ModifyDate, &t); err != nil {
-			return err
-		}
-		_ = ed.ScanIfdExif(ExifIFD_OffsetTime, &o) //dont care about offset errors
-		*dest, err = exifDateTime(t, o)
-		if err != nil {
-			return err
-		}
-	case DigitizedDate:
-		if err = ed.ScanIfdExif(ExifIFD_CreateDate, &t); err != nil {
-			return err
-		}
-		_ = ed.ScanIfdExif(ExifIFD_OffsetTimeDigitized, &o) //dont care about offset errors
-		*dest, err = exifDateTime(t, o)
-		if err != nil {
-			return err
-		}
-	default:
-		return fmt.Errorf("Unknown date to scan: %v", dateTag)
+	ts, err := ed.scanExifTimestamp(dateTag)
+	if err != nil {
+		return err
 	}
+	*dest = ts.Time
 	return nil
 }
 
@@ -3,6 +3,7 @@ package metadata
 import (
 	"fmt"
 	exifundefined "github.com/dsoprea/go-exif/v3/undefined"
+	"github.com/msvens/mimage/makernote"
 	"strings"
 	"testing"
 	"time"
@@ -374,6 +375,18 @@ func TestParseIfdDateTime(t *testing.T) {
 
 }
 
+func TestExifData_MakerNote(t *testing.T) {
+	//the maker notes of the test assets have been stripped
+	for _, fname := range []string{LeicaImg, NikonImg, GPSImg} {
+		if _, err := getExifData(fname, t).MakerNote(); err != makernote.ErrNoMakerNote {
+			t.Errorf("Expected %v got %v", makernote.ErrNoMakerNote, err)
+		}
+	}
+	if _, err := (&ExifData{}).MakerNote(); err != ErrExifNoData {
+		t.Errorf("Expected %v got %v", ErrExifNoData, err)
+	}
+}
+
 // Todo:
 func TestTimeOffsetString(t *testing.T) {
 
@@ -7,6 +7,7 @@ import (
 	exifundefined "github.com/dsoprea/go-exif/v3/undefined"
 	jpegstructure "github.com/dsoprea/go-jpeg-image-structure/v2"
 	"github.com/go-errors/errors"
+	"github.com/msvens/mimage/makernote"
 	"time"
 )
 
@@ -16,10 +17,13 @@ const exifEditorSoftware = "github.com/msvens/mimage (go-exif)"
 type ExifEditor struct {
 	rootIb *exif.IfdBuilder
 	dirty  bool
+	//offset of the maker note in the original exif data (or -1). Needed to relocate the maker note on rewrite
+	makerNoteOffset int
+	writerOptions   WriterOptions
 }
 
 func exifOffsetString(t time.Time) string {
-	return t.Format("-0700")
+	return t.Format("-07:00")
 }
 
 // NewExifEditor from a jpeg segment list
@@ -27,7 +31,10 @@ func NewExifEditor(sl *jpegstructure.SegmentList) (*ExifEditor, error) {
 	if sl == nil {
 		return &ExifEditor{}, fmt.Errorf("nil segment list")
 	}
-	rootIfd, _, err := sl.Exif()
+	if rawExif, err := findRawExif(sl); err == nil && hasIfdLoop(rawExif) {
+		return &ExifEditor{}, ErrExifIfdLoop
+	}
+	rootIfd, rawExif, err := sl.Exif()
 	if err != nil {
 		if errors.Is(err, exif.ErrNoExif) {
 			return NewExifEditorEmpty(false)
@@ -35,7 +42,12 @@ func NewExifEditor(sl *jpegstructure.SegmentList) (*ExifEditor, error) {
 		return &ExifEditor{}, err
 	}
 	rootIb := exif.NewIfdBuilderFromExistingChain(rootIfd)
-	return &ExifEditor{rootIb, false}, nil
+	fixInlineValues(rootIfd, rootIb)
+	ret := &ExifEditor{rootIb: rootIb, makerNoteOffset: -1}
+	if offset, _, err := makernote.Locate(rawExif); err == nil {
+		ret.makerNoteOffset = offset
+	}
+	return ret, nil
 }
 
 // NewExifEditorEmpty create a new empty editor and sets the dirty flag
@@ -59,6 +71,7 @@ func (ee *ExifEditor) Clear(dirty bool) error {
 		exifcommon.IfdStandardIfdIdentity,
 		exifcommon.EncodeDefaultByteOrder)
 	ee.dirty = dirty
+	ee.makerNoteOffset = -1
 	return nil
 
 }
@@ -87,36 +100,40 @@ func (ee *ExifEditor) SetDirty() {
 }
 
 // SetDate sets the specified dateTag to time. Both the DateTime and corresponding offest
-// will be set
+// will be set (as well as sub seconds if time has a fractional second)
 func (ee *ExifEditor) SetDate(dateTag ExifDate, time time.Time) error {
-	var err error
-	offset := exifOffsetString(time)
-	switch dateTag {
-	case OriginalDate:
-		if err = ee.SetIfdExifTag(ExifIFD_DateTimeOriginal, time); err != nil {
-			return err
-		}
-		if err = ee.SetIfdExifTag(ExifIFD_OffsetTimeOriginal, offset); err != nil {
-			return err
-		}
-	case ModifyDate:
-		if err = ee.SetIfdRootTag(IFD_ModifyDate, time); err != nil {
-			return err
-		}
-		if err = ee.SetIfdExifTag(ExifIFD_OffsetTime, offset); err != nil {
-			return err
-		}
-	case DigitizedDate:
-		if err = ee.SetIfdExifTag(ExifIFD_CreateDate, time); err != nil {
-			return err
-		}
-		if err = ee.SetIfdExifTag(ExifIFD_OffsetTimeDigitized, offset); err != nil {
-			return err
-		}
-	default:
+	if err := ee.setDateTime(dateTag, time); err != nil {
+		return err
+	}
+	return ee.SetIfdExifTag(exifDates[dateTag].offset, exifOffsetString(time))
+}
+
+// setDateTime sets the specified dateTag (and sub seconds) to t without setting the corresponding offset. Sub
+// seconds are removed if t is a whole second
+func (ee *ExifEditor) setDateTime(dateTag ExifDate, t time.Time) error {
+	tags, found := exifDates[dateTag]
+	if !found {
 		return fmt.Errorf("Unknown date to set: %v", dateTag)
 	}
-	return nil
+	var err error
+	if tags.ifd == RootIFD {
+		err = ee.SetIfdRootTag(tags.date, t)
+	} else {
+		err = ee.SetIfdExifTag(tags.date, t)
+	}
+	if err != nil {
+		return err
+	}
+	if t.Nanosecond() != 0 {
+		return ee.SetIfdExifTag(tags.subSec, subSecString(t))
+	}
+	//remove any previous fraction so it is not added to the whole second
+	exifIb, err := exif.GetOrCreateIbFromRootIb(ee.rootIb, IFDPaths[ExifIFD])
+	if err != nil {
+		return err
+	}
+	_, err = exifIb.DeleteAll(uint16(tags.subSec))
+	return err
 }
 
 // SetImageDescription sets IFD_ImageDescription to description
@@ -130,7 +147,7 @@ func (ee *ExifEditor) SetIfdExifTag(id ExifTag, value interface{}) error {
 	if err != nil {
 		return err
 	}
-	if err = exifIb.SetStandard(uint16(id), toGoExifValue(value)); err != nil {
+	if err = setIbTag(exifIb, id, value); err != nil {
 		return err
 	}
 	ee.dirty = true
@@ -139,24 +156,64 @@ func (ee *ExifEditor) SetIfdExifTag(id ExifTag, value interface{}) error {
 
 // SetIfdRootTag set RootIFD tag id to value
 func (ee *ExifEditor) SetIfdRootTag(id ExifTag, value interface{}) error {
-	if err := ee.rootIb.SetStandard(uint16(id), toGoExifValue(value)); err != nil {
+	if err := setIbTag(ee.rootIb, id, value); err != nil {
 		return err
 	}
 	ee.dirty = true
 	return nil
 }
 
+// setIbTag sets tag id in ib. Raw bytes are stored as is (as an undefined value), e.g. for the maker note
+func setIbTag(ib *exif.IfdBuilder, id ExifTag, value interface{}) error {
+	if raw, ok := value.([]byte); ok {
+		//the byte order is only used when encoding non raw values
+		return ib.Set(exif.NewBuilderTag(ib.IfdIdentity().UnindexedString(), uint16(id), exifcommon.TypeUndefined,
+			exif.NewIfdBuilderTagValueFromBytes(raw), exifcommon.EncodeDefaultByteOrder))
+	}
+	return ib.SetStandard(uint16(id), toGoExifValue(value))
+}
+
+// fixInlineValues works around go-exif reading inline undefined values (e.g. FileSource) as all 4 bytes of the
+// value field which would change their count (and value) when the exif is rewritten
+func fixInlineValues(ifd *exif.Ifd, ib *exif.IfdBuilder) {
+	for _, ite := range ifd.Entries() {
+		if ite.ChildIfdPath() != "" || ite.TagType() != exifcommon.TypeUndefined {
+			continue
+		}
+		raw, err := ite.GetRawBytes()
+		if err != nil || uint32(len(raw)) <= ite.UnitCount() {
+			continue
+		}
+		_ = setIbTag(ib, ExifTag(ite.TagId()), raw[:ite.UnitCount()])
+	}
+	for _, child := range ifd.Children() {
+		if bt, err := ib.FindTag(child.IfdIdentity().TagId()); err == nil && bt.Value().IsIb() {
+			fixInlineValues(child, bt.Value().Ib())
+		}
+	}
+	if next, _ := ib.NextIb(); next != nil && ifd.NextIfd() != nil {
+		fixInlineValues(ifd.NextIfd(), next)
+	}
+}
+
 func (ee *ExifEditor) setSoftware() error {
 	if !ee.dirty {
 		return nil
 	}
-	if err := ee.SetIfdRootTag(IFD_Software, exifEditorSoftware); err != nil {
-		return err
+	if !ee.writerOptions.KeepSoftware {
+		if err := ee.SetIfdRootTag(IFD_Software, ee.writerOptions.agent(exifEditorSoftware)); err != nil {
+			return err
+		}
 	}
 	ee.dirty = false
 	return nil
 }
 
+// SetWriterOptions sets the options used when edits are committed
+func (ee *ExifEditor) SetWriterOptions(opts WriterOptions) {
+	ee.writerOptions = opts
+}
+
 // SetUserComment sets ExifIFD_UserComment to comment using exifundefined.Tag9286UserComment. The
 // comment will be Unicode encoded
 func (ee *ExifEditor) SetUserComment(comment string) error {
@@ -197,6 +254,8 @@ func toGoExifValue(value interface{}) interface{} {
 		return ret
 	case LensInfo:
 		return t.toRational()
+	case time.Time: //go-exif would store the time as UTC. Exif dates are local times (with a separate offset)
+		return t.Format(ExifDateTime)
 	default:
 		return t
 	}
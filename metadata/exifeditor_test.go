@@ -128,10 +128,10 @@ func TestExifEditor_SetDate(t *testing.T) {
 		}
 	}
 	md := jpegEditorMD(je, t)
-	if cmpDates(tnow, md.Summary().OriginalDate) {
+	if !cmpDates(tnow, md.Summary().OriginalDate) {
 		t.Errorf("Expected %v got %v", tnow, md.Summary().OriginalDate)
 	}
-	if cmpDates(tnow, md.Summary().ModifyDate) {
+	if !cmpDates(tnow, md.Summary().ModifyDate) {
 		t.Errorf("Expected %v got %v", tnow, md.Summary().ModifyDate)
 	}
 	//TODO: Verifiy digitized date
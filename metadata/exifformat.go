@@ -0,0 +1,380 @@
+package metadata
+
+import (
+	"fmt"
+	exifcommon "github.com/dsoprea/go-exif/v3/common"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+Human readable rendering of exif values, similar to exiftool's print conversion. See
+https://exiftool.org/TagNames/EXIF.html
+*/
+
+type exifFormatter func(ed *ExifData, index ExifIndex, tag ExifTag) (string, error)
+
+var exifFormatters = map[ExifIndexTag]exifFormatter{
+	{ExifIFD, ExifIFD_ExposureTime}:            formatExposureTime,
+	{ExifIFD, ExifIFD_ShutterSpeedValue}:       formatShutterSpeed,
+	{ExifIFD, ExifIFD_FNumber}:                 formatFNumber,
+	{ExifIFD, ExifIFD_ApertureValue}:           formatAperture,
+	{ExifIFD, ExifIFD_MaxApertureValue}:        formatAperture,
+	{ExifIFD, ExifIFD_ExposureCompensation}:    formatExposureCompensation,
+	{ExifIFD, ExifIFD_FocalLength}:             formatFocalLength,
+	{ExifIFD, ExifIFD_FocalLengthIn35mmFormat}: formatFocalLength35,
+	{ExifIFD, ExifIFD_Flash}:                   formatFlash,
+	{ExifIFD, ExifIFD_LensInfo}:                formatLensInfo,
+	{GpsIFD, GpsIFD_GPSLatitude}:               formatGpsCoordinate,
+	{GpsIFD, GpsIFD_GPSLongitude}:              formatGpsCoordinate,
+	{GpsIFD, GpsIFD_GPSAltitude}:               formatGpsAltitude,
+	{GpsIFD, GpsIFD_GPSTimeStamp}:              formatGpsTimeStamp,
+}
+
+// Format returns a human readable rendering of tag in index, e.g. "1/250 s" for the ExposureTime, "f/2.8" for
+// the FNumber or decimal degrees for GPS coordinates. Enumerated values are rendered with their common name
+// (see ExifValueString) and other values are formatted according to their type. Returns ErrExifTagNotFound if the
+// tag does not exist
+func (ed *ExifData) Format(index ExifIndex, tag ExifTag) (string, error) {
+	if f, found := exifFormatters[ExifIndexTag{index, tag}]; found {
+		return f(ed, index, tag)
+	}
+	tagDesc, value, err := ed.value(index, tag)
+	if err != nil {
+		return "", err
+	}
+	return formatExifValue(index, tagDesc, value), nil
+}
+
+// formatDecimal formats f with at most 4 decimals (and no trailing zeros)
+func formatDecimal(f float64) string {
+	return strconv.FormatFloat(math.Round(f*1e4)/1e4, 'f', -1, 64)
+}
+
+func formatURat(r URat) string {
+	if r.Denominator == 0 {
+		return "undef"
+	}
+	return formatDecimal(r.Float64())
+}
+
+func formatRat(r Rat) string {
+	if r.Denominator == 0 {
+		return "undef"
+	}
+	return formatDecimal(r.Float64())
+}
+
+// firstExifValue returns the first value of a raw exif value using the types expected by ExifValueStringErr
+func firstExifValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(strings.TrimRight(v, "\x00"))
+	case []uint8:
+		if len(v) > 0 {
+			return v[0]
+		}
+	case []uint16:
+		if len(v) > 0 {
+			return v[0]
+		}
+	case []uint32:
+		if len(v) > 0 {
+			return v[0]
+		}
+	case []int32:
+		if len(v) > 0 {
+			return v[0]
+		}
+	case []exifcommon.Rational:
+		if len(v) > 0 {
+			return newURatFromRational(v[0])
+		}
+	case []exifcommon.SignedRational:
+		if len(v) > 0 {
+			return newRatFromSignedRational(v[0])
+		}
+	case []float32:
+		if len(v) > 0 {
+			return v[0]
+		}
+	case []float64:
+		if len(v) > 0 {
+			return v[0]
+		}
+	}
+	return nil
+}
+
+func joinExifValues(n int, f func(i int) string) string {
+	ret := make([]string, n)
+	for i := range ret {
+		ret[i] = f(i)
+	}
+	return strings.Join(ret, " ")
+}
+
+func formatExifValue(index ExifIndex, tagDesc ExifTagDesc, value interface{}) string {
+	if tagDesc.Values != nil {
+		if s, err := ExifValueStringErr(index, tagDesc.Id, firstExifValue(value)); err == nil {
+			return s
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(strings.TrimRight(v, "\x00"))
+	case []uint8:
+		if tagDesc.Type == ExifUndef {
+			return fmt.Sprintf("(Binary data %d bytes)", len(v))
+		}
+		return joinExifValues(len(v), func(i int) string { return strconv.Itoa(int(v[i])) })
+	case []uint16:
+		return joinExifValues(len(v), func(i int) string { return strconv.Itoa(int(v[i])) })
+	case []uint32:
+		return joinExifValues(len(v), func(i int) string { return strconv.FormatUint(uint64(v[i]), 10) })
+	case []int32:
+		return joinExifValues(len(v), func(i int) string { return strconv.Itoa(int(v[i])) })
+	case []exifcommon.Rational:
+		return joinExifValues(len(v), func(i int) string { return formatURat(newURatFromRational(v[i])) })
+	case []exifcommon.SignedRational:
+		return joinExifValues(len(v), func(i int) string { return formatRat(newRatFromSignedRational(v[i])) })
+	case []float32:
+		return joinExifValues(len(v), func(i int) string { return formatDecimal(float64(v[i])) })
+	case []float64:
+		return joinExifValues(len(v), func(i int) string { return formatDecimal(v[i]) })
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// formatSeconds formats an exposure time in seconds, e.g. "1/250 s" or "2 s"
+func formatSeconds(secs float64) string {
+	if secs > 0 && secs < 0.25001 {
+		return fmt.Sprintf("1/%d s", int(math.Round(1/secs)))
+	}
+	return formatDecimal(secs) + " s"
+}
+
+func formatFStop(f float64) string {
+	return fmt.Sprintf("f/%.1f", f)
+}
+
+func formatExposureTime(ed *ExifData, index ExifIndex, tag ExifTag) (string, error) {
+	v := URat{}
+	if err := ed.Scan(index, tag, &v); err != nil {
+		return "", err
+	}
+	if v.Denominator == 0 {
+		return "undef", nil
+	}
+	return formatSeconds(v.Float64()), nil
+}
+
+// formatShutterSpeed formats the APEX ShutterSpeedValue as an exposure time
+func formatShutterSpeed(ed *ExifData, index ExifIndex, tag ExifTag) (string, error) {
+	v := Rat{}
+	if err := ed.Scan(index, tag, &v); err != nil {
+		return "", err
+	}
+	if v.Denominator == 0 {
+		return "undef", nil
+	}
+	return formatSeconds(math.Pow(2, -v.Float64())), nil
+}
+
+func formatFNumber(ed *ExifData, index ExifIndex, tag ExifTag) (string, error) {
+	v := URat{}
+	if err := ed.Scan(index, tag, &v); err != nil {
+		return "", err
+	}
+	if v.Denominator == 0 || v.Numerator == 0 {
+		return "undef", nil
+	}
+	return formatFStop(v.Float64()), nil
+}
+
+// formatAperture formats an APEX aperture value as an f-number
+func formatAperture(ed *ExifData, index ExifIndex, tag ExifTag) (string, error) {
+	v := URat{}
+	if err := ed.Scan(index, tag, &v); err != nil {
+		return "", err
+	}
+	if v.Denominator == 0 {
+		return "undef", nil
+	}
+	return formatFStop(math.Pow(2, v.Float64()/2)), nil
+}
+
+// formatExposureCompensation formats exposure compensation as a (signed) fraction of stops, e.g. "+1/3"
+func formatExposureCompensation(ed *ExifData, index ExifIndex, tag ExifTag) (string, error) {
+	v := Rat{}
+	if err := ed.Scan(index, tag, &v); err != nil {
+		return "", err
+	}
+	if v.Denominator == 0 {
+		return "undef", nil
+	}
+	f := v.Float64()
+	switch {
+	case f == 0:
+		return "0", nil
+	case math.Abs(f-math.Round(f)) < 1e-4:
+		return fmt.Sprintf("%+d", int(math.Round(f))), nil
+	case math.Abs(f*2-math.Round(f*2)) < 1e-4:
+		return fmt.Sprintf("%+d/2", int(math.Round(f*2))), nil
+	case math.Abs(f*3-math.Round(f*3)) < 1e-4:
+		return fmt.Sprintf("%+d/3", int(math.Round(f*3))), nil
+	case f > 0:
+		return "+" + formatDecimal(f), nil
+	default:
+		return formatDecimal(f), nil
+	}
+}
+
+// formatFocalLength formats the focal length. The 35 mm equivalent is included if FocalLengthIn35mmFormat is set
+func formatFocalLength(ed *ExifData, index ExifIndex, tag ExifTag) (string, error) {
+	v := URat{}
+	if err := ed.Scan(index, tag, &v); err != nil {
+		return "", err
+	}
+	if v.Denominator == 0 {
+		return "undef", nil
+	}
+	ret := fmt.Sprintf("%.1f mm", v.Float64())
+	var fl35 uint16
+	if err := ed.ScanIfdExif(ExifIFD_FocalLengthIn35mmFormat, &fl35); err == nil && fl35 != 0 {
+		ret += fmt.Sprintf(" (35 mm equivalent: %.1f mm)", float64(fl35))
+	}
+	return ret, nil
+}
+
+func formatFocalLength35(ed *ExifData, index ExifIndex, tag ExifTag) (string, error) {
+	var v uint16
+	if err := ed.Scan(index, tag, &v); err != nil {
+		return "", err
+	}
+	if v == 0 {
+		return "Unknown", nil
+	}
+	return fmt.Sprintf("%d mm", v), nil
+}
+
+// FlashString decodes the bit flags of an exif Flash value in the order mode, fired, red-eye and return, e.g.
+// "Auto, Fired, Red-eye reduction". Unlike ExifValueString every value is decoded from its bits so that the
+// wording does not depend on whether the value is in the (inconsistent) exiftool table
+func FlashString(flash uint16) string {
+	if flash == 0 {
+		return "No Flash"
+	}
+	parts := []string{}
+	switch (flash >> 3) & 0x3 {
+	case 1:
+		parts = append(parts, "On")
+	case 2:
+		parts = append(parts, "Off")
+	case 3:
+		parts = append(parts, "Auto")
+	}
+	switch {
+	case flash&0x20 != 0:
+		parts = append(parts, "No flash function")
+	case flash&0x1 != 0:
+		parts = append(parts, "Fired")
+	default:
+		parts = append(parts, "Did not fire")
+	}
+	if flash&0x40 != 0 {
+		parts = append(parts, "Red-eye reduction")
+	}
+	switch (flash >> 1) & 0x3 {
+	case 2:
+		parts = append(parts, "Return not detected")
+	case 3:
+		parts = append(parts, "Return detected")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatFlash(ed *ExifData, index ExifIndex, tag ExifTag) (string, error) {
+	var v uint16
+	if err := ed.Scan(index, tag, &v); err != nil {
+		return "", err
+	}
+	return FlashString(v), nil
+}
+
+// Format returns the lens info as a lens specification, e.g. "24-70mm f/2.8" or "18-55mm f/3.5-5.6"
+func (li LensInfo) Format() string {
+	num := func(r URat) string {
+		if r.Denominator == 0 || r.Numerator == 0 {
+			return "?"
+		}
+		return strconv.FormatFloat(math.Round(r.Float64()*10)/10, 'f', -1, 64)
+	}
+	rng := func(min, max URat) string {
+		if a, b := num(min), num(max); a != b {
+			return a + "-" + b
+		}
+		return num(min)
+	}
+	return fmt.Sprintf("%smm f/%s", rng(li.MinFocalLength, li.MaxFocalLength),
+		rng(li.MinFNumberMinFocalLength, li.MinFNumberMaxFocalLength))
+}
+
+func formatLensInfo(ed *ExifData, index ExifIndex, tag ExifTag) (string, error) {
+	v := LensInfo{}
+	if err := ed.Scan(index, tag, &v); err != nil {
+		return "", err
+	}
+	return v.Format(), nil
+}
+
+// formatGpsCoordinate formats GPSLatitude and GPSLongitude as signed decimal degrees
+func formatGpsCoordinate(ed *ExifData, index ExifIndex, tag ExifTag) (string, error) {
+	v := []URat{}
+	if err := ed.Scan(index, tag, &v); err != nil {
+		return "", err
+	}
+	if len(v) != 3 {
+		return "", ErrExifParseTag
+	}
+	deg := v[0].Float64() + v[1].Float64()/60 + v[2].Float64()/3600
+	refTag := GpsIFD_GPSLatitudeRef
+	if tag == GpsIFD_GPSLongitude {
+		refTag = GpsIFD_GPSLongitudeRef
+	}
+	ref := ""
+	if err := ed.Scan(index, refTag, &ref); err == nil && (strings.HasPrefix(ref, "S") || strings.HasPrefix(ref, "W")) {
+		deg = -deg
+	}
+	return strconv.FormatFloat(deg, 'f', 6, 64), nil
+}
+
+func formatGpsAltitude(ed *ExifData, index ExifIndex, tag ExifTag) (string, error) {
+	v := URat{}
+	if err := ed.Scan(index, tag, &v); err != nil {
+		return "", err
+	}
+	ret := fmt.Sprintf("%.1f m", v.Float64())
+	var ref uint8
+	if err := ed.Scan(index, GpsIFD_GPSAltitudeRef, &ref); err == nil && ref == 1 {
+		ret += " Below Sea Level"
+	}
+	return ret, nil
+}
+
+func formatGpsTimeStamp(ed *ExifData, index ExifIndex, tag ExifTag) (string, error) {
+	v := []URat{}
+	if err := ed.Scan(index, tag, &v); err != nil {
+		return "", err
+	}
+	if len(v) != 3 {
+		return "", ErrExifParseTag
+	}
+	secs := formatDecimal(v[2].Float64())
+	if v[2].Float64() < 10 {
+		secs = "0" + secs
+	}
+	return fmt.Sprintf("%02d:%02d:%s", int(v[0].Float64()), int(v[1].Float64()), secs), nil
+}
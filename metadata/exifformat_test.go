@@ -0,0 +1,76 @@
+package metadata
+
+import (
+	"testing"
+)
+
+func TestExifData_Format(t *testing.T) {
+	tests := []struct {
+		fname string
+		index ExifIndex
+		tag   ExifTag
+		exp   string
+	}{
+		{LeicaImg, ExifIFD, ExifIFD_ExposureTime, "1/250 s"},
+		{LeicaImg, ExifIFD, ExifIFD_FNumber, "f/2.5"},
+		{LeicaImg, ExifIFD, ExifIFD_ExposureCompensation, "-0.3"},
+		{LeicaImg, ExifIFD, ExifIFD_ExposureProgram, "Manual"},
+		{LeicaImg, RootIFD, IFD_Make, "LEICA CAMERA AG"},
+		{NikonImg, ExifIFD, ExifIFD_FocalLength, "16.0 mm (35 mm equivalent: 24.0 mm)"},
+		{NikonImg, ExifIFD, ExifIFD_Flash, "No Flash"},
+		{NikonImg, ExifIFD, ExifIFD_ISO, "200"},
+		{GPSImg, ExifIFD, ExifIFD_FocalLength, "4.3 mm"},
+		{GPSImg, GpsIFD, GpsIFD_GPSLatitude, "26.586667"},
+		{GPSImg, GpsIFD, GpsIFD_GPSLongitude, "-80.053611"},
+		{GPSImg, GpsIFD, GpsIFD_GPSTimeStamp, "01:22:57"},
+		{GPSImg, GpsIFD, GpsIFD_GPSVersionID, "2 2 0 0"},
+		{GPSImg, RootIFD, IFD_XResolution, "72"},
+	}
+	for _, test := range tests {
+		act, err := getExifData(test.fname, t).Format(test.index, test.tag)
+		if err != nil {
+			t.Errorf("Could not format %v: %v", ExifTagName(test.index, test.tag), err)
+		}
+		if act != test.exp {
+			t.Errorf("Expected %v got %v", test.exp, act)
+		}
+	}
+	if _, err := getExifData(NikonImg, t).Format(ExifIFD, ExifIFD_LensInfo); err != ErrExifTagNotFound {
+		t.Errorf("Expected %v got %v", ErrExifTagNotFound, err)
+	}
+}
+
+func TestFlashString(t *testing.T) {
+	tests := map[uint16]string{
+		0x00: "No Flash",
+		0x19: "Auto, Fired",
+		0x59: "Auto, Fired, Red-eye reduction",
+		0x4b: "On, Fired, Red-eye reduction",
+		0x30: "Off, No flash function",
+		0x20: "No flash function",
+		0x0d: "On, Fired, Return not detected",
+		0x50: "Off, Did not fire, Red-eye reduction",
+		0x5f: "Auto, Fired, Red-eye reduction, Return detected",
+	}
+	for k, exp := range tests {
+		if act := FlashString(k); act != exp {
+			t.Errorf("Expected %v got %v", exp, act)
+		}
+	}
+}
+
+func TestLensInfo_Format(t *testing.T) {
+	tests := []struct {
+		li  LensInfo
+		exp string
+	}{
+		{LensInfo{URat{24, 1}, URat{70, 1}, URat{28, 10}, URat{28, 10}}, "24-70mm f/2.8"},
+		{LensInfo{URat{18, 1}, URat{55, 1}, URat{35, 10}, URat{56, 10}}, "18-55mm f/3.5-5.6"},
+		{LensInfo{URat{50, 1}, URat{50, 1}, URat{14, 10}, URat{0, 0}}, "50mm f/1.4-?"},
+	}
+	for _, test := range tests {
+		if act := test.li.Format(); act != test.exp {
+			t.Errorf("Expected %v got %v", test.exp, act)
+		}
+	}
+}
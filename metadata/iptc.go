@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	jpegstructure "github.com/dsoprea/go-jpeg-image-structure/v2"
+	"github.com/msvens/mimage/photoshop"
+	"golang.org/x/text/encoding"
 	"strings"
 	"time"
 	"unicode"
@@ -21,7 +23,10 @@ var iptcUtfCharSet = string([]byte{27, 37, 71})
 
 // IptcData holds a map of iptc record tags
 type IptcData struct {
-	raw map[IptcRecordTag]IptcRecordDataset
+	raw     map[IptcRecordTag]IptcRecordDataset
+	iim     []byte
+	digest  []byte
+	charset string
 }
 
 // IptcDate specifies date/time tag
@@ -84,13 +89,30 @@ func IptcTagName(record IptcRecord, tag IptcTag) string {
 	return fmt.Sprintf("Unknown Tag. Record: %v, Dataset: %v", record, tag)
 }
 
-// NewIptcData creates IptcData from a jpeg segment list
+// NewIptcData creates IptcData from a jpeg segment list. Strings without a CodedCharacterSet that are not valid
+// UTF-8 are read as ISO-8859-1 or DefaultIptcCharset (see detectIptcCharset)
 func NewIptcData(segments *jpegstructure.SegmentList) (*IptcData, error) {
+	return newIptcData(segments, nil)
+}
+
+func newIptcData(segments *jpegstructure.SegmentList, fallback encoding.Encoding) (*IptcData, error) {
 	if segments == nil {
 		return nil, fmt.Errorf("Segmentlist is nil")
 	}
-	raw, err := ParseIptcJpeg(segments)
-	return &IptcData{raw}, err
+	raw, enc, res, err := parseIptcResources(segments, fallback)
+	ret := IptcData{raw: raw, charset: iptcCharsetName(enc)}
+	if r, ok := res[photoshop.IptcId]; ok {
+		ret.iim = r.Data
+	}
+	if r, ok := res[photoshop.DigestId]; ok {
+		ret.digest = r.Data
+	}
+	return &ret, err
+}
+
+// Charset returns the character set the iptc strings were stored in (they are always returned as UTF-8)
+func (ipd *IptcData) Charset() string {
+	return ipd.charset
 }
 
 // IsEmpty returns true if IptcData has no tags
@@ -111,6 +133,15 @@ func (ipd *IptcData) GetDate(dateTag IptcDate) time.Time {
 	return ret
 }
 
+// GetDescription retrieves the IPTCApplication_CaptionAbstract. Returns the empty string in case of an error
+func (ipd *IptcData) GetDescription() string {
+	ret := ""
+	if err := ipd.ScanApplication(IPTCApplication_CaptionAbstract, &ret); err != nil {
+		return ""
+	}
+	return ret
+}
+
 // GetKeywords  retrieves IPTCApplication_Keywords. Returnes an empty slice in case of an error
 func (ipd *IptcData) GetKeywords() []string {
 	ret := []string{}
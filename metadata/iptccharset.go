@@ -0,0 +1,125 @@
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/ianaindex"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrIptcCharset if a character set name is not known
+var ErrIptcCharset = errors.New("Unknown character set")
+
+// IptcCharsetUTF8 is the name of the UTF-8 character set
+const IptcCharsetUTF8 = "UTF-8"
+
+// DefaultIptcCharset is the character set used for iptc data without a CodedCharacterSet that is not valid UTF-8
+// and has C1 control bytes (0x80-0x9f) if no fallback is given. Other legacy data is read as ISO-8859-1
+const DefaultIptcCharset = "windows-1252"
+
+// iso2022Charsets maps the final byte of ISO 2022 G1 (ESC - F) and G2 (ESC . F) designations to character sets
+var iso2022Charsets = map[byte]*charmap.Charmap{
+	'A': charmap.ISO8859_1,
+	'B': charmap.ISO8859_2,
+	'C': charmap.ISO8859_3,
+	'D': charmap.ISO8859_4,
+	'F': charmap.ISO8859_7,
+	'G': charmap.ISO8859_6,
+	'H': charmap.ISO8859_8,
+	'L': charmap.ISO8859_5,
+	'M': charmap.ISO8859_9,
+	'b': charmap.ISO8859_15,
+}
+
+// iptcEncoding returns the fallback encoding with the given IANA name (e.g. ISO-8859-1 or windows-1252). Returns
+// nil (no fallback) if name is empty or UTF-8 since the fallback is only used for data that is not valid UTF-8
+func iptcEncoding(name string) (encoding.Encoding, error) {
+	if name == "" || strings.EqualFold(name, IptcCharsetUTF8) || strings.EqualFold(name, "utf8") {
+		return nil, nil
+	}
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err != nil || enc == nil {
+		return nil, ErrIptcCharset
+	}
+	return enc, nil
+}
+
+// iptcCharsetName returns the preferred MIME (or IANA) name of enc (nil is UTF-8)
+func iptcCharsetName(enc encoding.Encoding) string {
+	if enc == nil {
+		return IptcCharsetUTF8
+	}
+	if name, err := ianaindex.MIME.Name(enc); err == nil {
+		return name
+	}
+	if name, err := ianaindex.IANA.Name(enc); err == nil {
+		return name
+	}
+	return "unknown"
+}
+
+// codedCharacterSet returns the encoding of a CodedCharacterSet (ISO 2022 escape sequences). found is false
+// if the sequence is not recognized
+func codedCharacterSet(ccs []byte) (enc encoding.Encoding, found bool) {
+	if bytes.Contains(ccs, []byte(iptcUtfCharSet)) || bytes.Contains(ccs, []byte("\x1b%/G")) ||
+		bytes.Contains(ccs, []byte("\x1b%/I")) {
+		return nil, true
+	}
+	for i := 0; i+2 < len(ccs); i++ {
+		if ccs[i] == 0x1b && (ccs[i+1] == '-' || ccs[i+1] == '.') {
+			if cm, ok := iso2022Charsets[ccs[i+2]]; ok {
+				return cm, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// detectIptcCharset returns the encoding of the string datasets in tags (nil for UTF-8). A known
+// CodedCharacterSet is used as is. Otherwise data that is valid UTF-8 is read as UTF-8 and anything
+// else using fallback. If fallback is nil the data is read as ISO-8859-1, or DefaultIptcCharset if it
+// has C1 control bytes
+func detectIptcCharset(tags map[IptcRecordTag][][]byte, fallback encoding.Encoding) encoding.Encoding {
+	if ccs, ok := tags[IptcRecordTag{IPTCEnvelope, IPTCEnvelope_CodedCharacterSet}]; ok && len(ccs) > 0 {
+		if enc, found := codedCharacterSet(ccs[0]); found {
+			return enc
+		}
+	}
+	valid, c1 := true, false
+	for rt, data := range tags {
+		if desc, ok := IptcTagDescriptions[rt]; !ok || (desc.Type != IptcString && desc.Type != IptcDigits) {
+			continue
+		}
+		for _, d := range data {
+			valid = valid && utf8.Valid(d)
+			for _, b := range d {
+				c1 = c1 || (b >= 0x80 && b <= 0x9f)
+			}
+		}
+	}
+	if valid {
+		return nil
+	}
+	switch {
+	case fallback != nil:
+		return fallback
+	case c1:
+		return charmap.Windows1252
+	default:
+		return charmap.ISO8859_1
+	}
+}
+
+// decodeIptcString converts data in enc to UTF-8
+func decodeIptcString(enc encoding.Encoding, data []byte) string {
+	if enc == nil {
+		return string(data)
+	}
+	if out, err := enc.NewDecoder().Bytes(data); err == nil {
+		return string(out)
+	}
+	return string(data)
+}
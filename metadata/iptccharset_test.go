@@ -0,0 +1,146 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/msvens/mimage/photoshop"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"testing"
+)
+
+// legacyIim encodes a caption and (optionally) a CodedCharacterSet as is
+func legacyIim(caption []byte, ccs string, t *testing.T) []byte {
+	buf := bytes.Buffer{}
+	bw := bufio.NewWriter(&buf)
+	if ccs != "" {
+		if err := encodeIptcRecordData(bw, IPTCEnvelope, IPTCEnvelope_CodedCharacterSet, ccs); err != nil {
+			t.Fatalf("Could not encode iptc: %v", err)
+		}
+	}
+	if err := encodeIptcRecordData(bw, IPTCApplication, IPTCApplication_CaptionAbstract, caption); err != nil {
+		t.Fatalf("Could not encode iptc: %v", err)
+	}
+	if err := bw.Flush(); err != nil {
+		t.Fatalf("Could not encode iptc: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// legacyIptcImage returns the leica image with its iptc replaced by iim
+func legacyIptcImage(iim []byte, t *testing.T) []byte {
+	je := getJpegEditor(LeicaImg, t)
+	res := map[uint16]photoshop.ImageResource{photoshop.IptcId: photoshop.NewPhotoshopImageResource(photoshop.IptcId, iim)}
+	b, err := photoshop.Marshal(res, true)
+	if err != nil {
+		t.Fatalf("Could not marshal photoshop resources: %v", err)
+	}
+	idx, _, err := photoshop.ParseJpegResources(je.sl)
+	if err != nil {
+		t.Fatalf("Could not find photoshop segment: %v", err)
+	}
+	je.sl.Segments()[idx].Data = b
+	out := bytes.Buffer{}
+	if err = je.sl.Write(&out); err != nil {
+		t.Fatalf("Could not write image: %v", err)
+	}
+	return out.Bytes()
+}
+
+func TestCodedCharacterSet(t *testing.T) {
+	for ccs, exp := range map[string]string{
+		iptcUtfCharSet: IptcCharsetUTF8,
+		"\x1b-A":       "ISO-8859-1",
+		"\x1b(B\x1b.A": "ISO-8859-1",
+		"\x1b-B":       "ISO-8859-2",
+		"\x1b-b":       "ISO-8859-15",
+	} {
+		enc, found := codedCharacterSet([]byte(ccs))
+		if !found || iptcCharsetName(enc) != exp {
+			t.Errorf("Expected %v got %v", exp, iptcCharsetName(enc))
+		}
+	}
+	if _, found := codedCharacterSet([]byte("\x1b$B")); found {
+		t.Errorf("Expected unknown character set")
+	}
+}
+
+func TestDecodeIptc_Charset(t *testing.T) {
+	latin1, _ := charmap.ISO8859_1.NewEncoder().String("Morgondimma över sjön")
+	cp1252, _ := charmap.Windows1252.NewEncoder().String("Sjön – “dimma”")
+	for _, test := range []struct {
+		iim      []byte
+		fallback encoding.Encoding
+		exp      string
+		charset  string
+	}{
+		{legacyIim([]byte(latin1), "", t), nil, "Morgondimma över sjön", "ISO-8859-1"},
+		{legacyIim([]byte(cp1252), "", t), nil, "Sjön – “dimma”", DefaultIptcCharset},
+		{legacyIim([]byte(cp1252), "", t), charmap.ISO8859_1, "Sjön \u0096 \u0093dimma\u0094", "ISO-8859-1"},
+		{legacyIim([]byte(latin1), "\x1b-A", t), charmap.ISO8859_2, "Morgondimma över sjön", "ISO-8859-1"},
+		{legacyIim([]byte("Morgondimma över sjön"), "", t), nil, "Morgondimma över sjön", IptcCharsetUTF8},
+		{legacyIim([]byte("Morgondimma över sjön"), iptcUtfCharSet, t), nil, "Morgondimma över sjön", IptcCharsetUTF8},
+	} {
+		raw, enc, err := decodeIptc(bytes.NewReader(test.iim), test.fallback)
+		if err != nil {
+			t.Fatalf("Could not decode iptc: %v", err)
+		}
+		if act := raw[IptcRecordTag{IPTCApplication, IPTCApplication_CaptionAbstract}].Data; act != test.exp {
+			t.Errorf("Expected %v got %v", test.exp, act)
+		}
+		if iptcCharsetName(enc) != test.charset {
+			t.Errorf("Expected %v got %v", test.charset, iptcCharsetName(enc))
+		}
+	}
+}
+
+func TestNewMetaData_IptcCharset(t *testing.T) {
+	latin2, _ := charmap.ISO8859_2.NewEncoder().String("Dvořák")
+	img := legacyIptcImage(legacyIim([]byte(latin2), "", t), t)
+	md, err := NewMetaData(img)
+	if err != nil {
+		t.Fatalf("Could not read image: %v", err)
+	}
+	if md.Iptc().GetDescription() != "Dvoøák" || md.Iptc().Charset() != "ISO-8859-1" {
+		t.Errorf("Expected %v got %v (%v)", "Dvoøák", md.Iptc().GetDescription(), md.Iptc().Charset())
+	}
+	if md, err = newMetaData(img, ReadOptions{IptcCharset: "ISO-8859-2"}); err != nil {
+		t.Fatalf("Could not read image: %v", err)
+	}
+	if md.Iptc().GetDescription() != "Dvořák" || md.Iptc().Charset() != "ISO-8859-2" {
+		t.Errorf("Expected %v got %v (%v)", "Dvořák", md.Iptc().GetDescription(), md.Iptc().Charset())
+	}
+	if _, err = newMetaData(img, ReadOptions{IptcCharset: "no-such-charset"}); err != ErrIptcCharset {
+		t.Errorf("Expected %v got %v", ErrIptcCharset, err)
+	}
+}
+
+func TestIptcEditor_Transcode(t *testing.T) {
+	latin1, _ := charmap.ISO8859_1.NewEncoder().String("Morgondimma över sjön")
+	je, err := NewJpegEditor(legacyIptcImage(legacyIim([]byte(latin1), "", t), t))
+	if err != nil {
+		t.Fatalf("Could not create editor: %v", err)
+	}
+	if je.Iptc().Charset() != "ISO-8859-1" {
+		t.Errorf("Expected %v got %v", "ISO-8859-1", je.Iptc().Charset())
+	}
+	if err = je.Iptc().SetFallbackCharset("ISO-8859-1"); err != nil {
+		t.Fatalf("Could not set charset: %v", err)
+	}
+	if err = je.Iptc().SetTitle("Sjön"); err != nil {
+		t.Fatalf("Could not set title: %v", err)
+	}
+	if err = je.Iptc().SetFallbackCharset("ISO-8859-2"); err == nil {
+		t.Errorf("Expected error after edit")
+	}
+	md := jpegEditorMD(je, t)
+	if md.Iptc().Charset() != IptcCharsetUTF8 {
+		t.Errorf("Expected %v got %v", IptcCharsetUTF8, md.Iptc().Charset())
+	}
+	if md.Iptc().GetDescription() != "Morgondimma över sjön" {
+		t.Errorf("Expected %v got %v", "Morgondimma över sjön", md.Iptc().GetDescription())
+	}
+	if md.Iptc().GetTitle() != "Sjön" {
+		t.Errorf("Expected %v got %v", "Sjön", md.Iptc().GetTitle())
+	}
+}
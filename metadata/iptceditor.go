@@ -5,6 +5,7 @@ import (
 	"fmt"
 	jpegstructure "github.com/dsoprea/go-jpeg-image-structure/v2"
 	"github.com/msvens/mimage/photoshop"
+	"golang.org/x/text/encoding"
 	"time"
 )
 
@@ -20,10 +21,14 @@ func iptcDateTimeStr(t time.Time) (string, string) {
 
 // IptcEditor holds raw iptc data
 type IptcEditor struct {
-	raw        map[IptcRecordTag]IptcRecordDataset
-	resources  map[uint16]photoshop.ImageResource
-	segmentIdx int
-	dirty      bool
+	raw       map[IptcRecordTag]IptcRecordDataset
+	resources photoshop.Resources
+	dirty     bool
+	//charset is the character set of the records that were read. Anything else than UTF-8 (nil) is transcoded when
+	//the records are written
+	charset encoding.Encoding
+	//unsynced is set by edits that are not mirrored in xmp (see JpegEditor.syncIptc)
+	unsynced bool
 }
 
 // NewIptcEditor from a jpeg segment list
@@ -34,29 +39,57 @@ func NewIptcEditor(sl *jpegstructure.SegmentList) (*IptcEditor, error) {
 	ret := IptcEditor{}
 	ret.raw = map[IptcRecordTag]IptcRecordDataset{}
 	var err error
-	ret.segmentIdx, ret.resources, err = photoshop.ParseJpeg(sl)
+	_, ret.resources, err = photoshop.ParseJpegSegments(sl)
 	if err != nil && err != photoshop.ErrNoPhotoshopBlock {
 		return &ret, err
 	}
-	iptcData, ok := ret.resources[photoshop.IptcId]
+	iptcData, ok := ret.resources.Get(photoshop.IptcId)
 	if !ok { //simply return
 		return &ret, nil
 	}
-	ret.raw, err = DecodeIptc(bytes.NewReader(iptcData.Data))
+	ret.raw, ret.charset, err = decodeIptc(bytes.NewReader(iptcData.Data), nil)
 	return &ret, err
 
 }
 
+// SetFallbackCharset re-reads the iptc records using the character set name (e.g. ISO-8859-1) for strings that
+// have no CodedCharacterSet and are not valid UTF-8. Has to be called before any edits
+func (ie *IptcEditor) SetFallbackCharset(name string) error {
+	enc, err := iptcEncoding(name)
+	if err != nil {
+		return err
+	}
+	if ie.dirty {
+		return fmt.Errorf("Iptc has already been edited")
+	}
+	iptcData, ok := ie.resources.Get(photoshop.IptcId)
+	if !ok {
+		return nil
+	}
+	raw, charset, err := decodeIptc(bytes.NewReader(iptcData.Data), enc)
+	if err != nil {
+		return err
+	}
+	ie.raw, ie.charset = raw, charset
+	return nil
+}
+
+// Charset returns the character set the iptc records were read from. Records are always written as UTF-8
+func (ie *IptcEditor) Charset() string {
+	return iptcCharsetName(ie.charset)
+}
+
 // NewIptcEditorEmpty creates a new empty IptcEditor
 func NewIptcEditorEmpty(dirty bool) *IptcEditor {
-	return &IptcEditor{raw: map[IptcRecordTag]IptcRecordDataset{}, segmentIdx: -1,
-		resources: map[uint16]photoshop.ImageResource{}, dirty: dirty}
+	return &IptcEditor{raw: map[IptcRecordTag]IptcRecordDataset{}, resources: photoshop.Resources{}, dirty: dirty}
 }
 
 // Clear this model and set the dirty property
 func (ie *IptcEditor) Clear(dirty bool) {
 	ie.raw = map[IptcRecordTag]IptcRecordDataset{}
+	ie.charset = nil
 	ie.dirty = dirty
+	ie.unsynced = dirty
 }
 
 // IsDirty returns true if the IptcData has been changed
@@ -71,21 +104,56 @@ func (ie IptcEditor) IsEmpty() bool {
 
 // Bytes generate Photoshop Image Resource block including IPTC information
 func (ie *IptcEditor) Bytes() ([]byte, error) {
-	if ie.IsDirty() {
+	if err := ie.updateResources(); err != nil {
+		return nil, err
+	}
+	return photoshop.MarshalResources(ie.resources, true)
+}
+
+// segments generate Photoshop Image Resource blocks that each fit in a jpeg segment
+func (ie *IptcEditor) segments() ([][]byte, error) {
+	if err := ie.updateResources(); err != nil {
+		return nil, err
+	}
+	return photoshop.MarshalSegments(ie.resources)
+}
+
+// updateResources stores the iptc records in the photoshop resources. If the records were edited the digest is
+// updated when all edits were mirrored in xmp and otherwise removed
+func (ie *IptcEditor) updateResources() error {
+	//records read from another character set are transcoded to UTF-8
+	dirty := ie.IsDirty() || ie.charset != nil
+	synced := !ie.unsynced && ie.charset == nil
+	if dirty {
 		if err := ie.setMandatoryTags(); err != nil {
-			return nil, err
+			return err
 		}
-		delete(ie.resources, photoshop.DigestId) //we discard any digest information
 	}
 	//generate IptcData
 	out := &bytes.Buffer{}
 	err := EncodeIptc(out, ie.raw)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	ie.resources.Set(photoshop.NewPhotoshopImageResource(photoshop.IptcId, out.Bytes()))
+	if dirty && synced { //xmp holds the same values so the digest is updated to signal that (see mwg)
+		ie.resources.Set(photoshop.NewPhotoshopImageResource(photoshop.DigestId, IptcDigest(out.Bytes())))
+	} else if dirty { //without a digest mwg readers treat iptc as authoritative
+		ie.resources.Delete(photoshop.DigestId)
 	}
-	ie.resources[photoshop.IptcId] = photoshop.NewPhotoshopImageResource(photoshop.IptcId, out.Bytes())
 	ie.dirty = false
-	return photoshop.Marshal(ie.resources, true)
+	ie.unsynced = false
+	ie.charset = nil
+	return nil
+}
+
+// Digest returns the hex encoded digest of the iptc data as it was last written by Bytes (or read).
+// Returns "" if there is no digest
+func (ie *IptcEditor) Digest() string {
+	if r, ok := ie.resources.Get(photoshop.DigestId); ok {
+		return iptcDigestString(r.Data)
+	}
+	return ""
 }
 
 func (ie *IptcEditor) setApplication(tag IptcTag, value interface{}) error {
@@ -138,9 +206,15 @@ func (ie *IptcEditor) SetDate(dateTag IptcDate, t time.Time) error {
 	return nil
 }
 
+// SetDescription sets IPTCApplication_CaptionAbstract to description
+func (ie *IptcEditor) SetDescription(description string) error {
+	return ie.setApplication(IPTCApplication_CaptionAbstract, description)
+}
+
 // SetDirty force this editor to dirty
 func (ie *IptcEditor) SetDirty() {
 	ie.dirty = true
+	ie.unsynced = true
 }
 
 // SetEnvelope set IPTCEnvelop tag to value
@@ -155,6 +229,15 @@ func (ie *IptcEditor) setMandatoryTags() error {
 	return ie.SetEnvelope(IPTCEnvelope_CodedCharacterSet, iptcUtfCharSet)
 }
 
+func (ie *IptcEditor) keywords() []string {
+	if ds, ok := ie.raw[IptcRecordTag{IPTCApplication, IPTCApplication_Keywords}]; ok {
+		if ret, ok := ds.Data.([]string); ok {
+			return ret
+		}
+	}
+	return []string{}
+}
+
 // SetKeywords sets IPTCApplication_Keywords to keywords
 func (ie *IptcEditor) SetKeywords(keywords []string) error {
 	return ie.Set(IPTCApplication, IPTCApplication_Keywords, keywords)
@@ -229,6 +312,7 @@ func (ie *IptcEditor) Set(record IptcRecord, tag IptcTag, value interface{}) err
 	if valueOk {
 		ie.raw[rt] = newTag
 		ie.dirty = true
+		ie.unsynced = true
 		return nil
 	}
 	return ErrIptcTagValue
@@ -1,6 +1,7 @@
 package metadata
 
 import (
+	"github.com/msvens/mimage/photoshop"
 	"reflect"
 	"testing"
 	"time"
@@ -220,3 +221,86 @@ func TestIptcEditor_SetTitle(t *testing.T) {
 	}
 
 }
+
+func TestIptcEditor_BytesKeepsResources(t *testing.T) {
+	je := getJpegEditor(LeicaImg, t)
+	thumb, _ := je.ie.resources.Get(photoshop.ThumbnailId)
+	je.ie.resources = append(photoshop.Resources{photoshop.NewPhotoshopImageResource(photoshop.CopyrightFlagId, []byte{1})},
+		append(je.ie.resources, thumb)...)
+	if err := je.SetTitle("new title"); err != nil {
+		t.Fatalf("Could not set title: %v", err)
+	}
+	b, err := je.ie.Bytes()
+	if err != nil {
+		t.Fatalf("Could not write iptc: %v", err)
+	}
+	resources, err := photoshop.UnmarshalResources(b, true)
+	if err != nil {
+		t.Fatalf("Could not read resources: %v", err)
+	}
+	ids := []uint16{}
+	for _, r := range resources {
+		ids = append(ids, r.ResourceId)
+	}
+	expected := []uint16{photoshop.CopyrightFlagId, photoshop.ResolutionInfoId, photoshop.IptcId, photoshop.ThumbnailId,
+		photoshop.DigestId, photoshop.ThumbnailId}
+	if !reflect.DeepEqual(expected, ids) {
+		t.Errorf("Expected %v got %v", expected, ids)
+	}
+}
+
+func TestIptcEditor_SplitSegments(t *testing.T) {
+	je := getJpegEditor(LeicaImg, t)
+	resources, err := photoshop.UnmarshalResources(getAssetBytes(LeicaLargeResources, t), false)
+	if err != nil {
+		t.Fatalf("Could not unmarshal resources: %v", err)
+	}
+	thumbs := len(resources.GetAll(photoshop.ThumbnailId))
+	je.ie.resources = resources
+	if err = je.Iptc().SetTitle("split title"); err != nil {
+		t.Fatalf("Could not set title: %v", err)
+	}
+	b, err := je.Bytes()
+	if err != nil {
+		t.Fatalf("Could not write image: %v", err)
+	}
+	sl, err := parseJpegBytes(b)
+	if err != nil {
+		t.Fatalf("Could not parse image: %v", err)
+	}
+	indexes, resources, err := photoshop.ParseJpegSegments(sl)
+	if err != nil || len(indexes) != 2 {
+		t.Fatalf("Expected 2 photoshop segments got %v %v", indexes, err)
+	}
+	if len(resources.GetAll(photoshop.ThumbnailId)) != thumbs {
+		t.Errorf("Expected %v got %v", thumbs, len(resources.GetAll(photoshop.ThumbnailId)))
+	}
+	md, err := NewMetaData(b)
+	if err != nil {
+		t.Fatalf("Could not read image: %v", err)
+	}
+	if md.Iptc().GetTitle() != "split title" {
+		t.Errorf("Expected %v got %v", "split title", md.Iptc().GetTitle())
+	}
+	if _, errs, _ := Repair(b); len(errs) != 0 {
+		t.Errorf("Expected no repairs got %v", errs)
+	}
+	//edit again and make sure the split segments are replaced
+	je = reloadJpegEditor(je, false, t)
+	if err = je.Iptc().SetTitle("new split title"); err != nil {
+		t.Fatalf("Could not set title: %v", err)
+	}
+	md = jpegEditorMD(je, t)
+	if md.Iptc().GetTitle() != "new split title" {
+		t.Errorf("Expected %v got %v", "new split title", md.Iptc().GetTitle())
+	}
+	if indexes, _, _ = photoshop.ParseJpegSegments(je.sl); len(indexes) != 2 {
+		t.Errorf("Expected 2 photoshop segments got %v", indexes)
+	}
+	if err = je.DropIptc(); err != nil {
+		t.Fatalf("Could not drop iptc: %v", err)
+	}
+	if _, _, err = photoshop.ParseJpegSegments(je.sl); err != photoshop.ErrNoPhotoshopBlock {
+		t.Errorf("Expected %v got %v", photoshop.ErrNoPhotoshopBlock, err)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	jpegstructure "github.com/dsoprea/go-jpeg-image-structure/v2"
 	"github.com/msvens/mimage/photoshop"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
 	"io"
 	"sort"
 )
@@ -213,8 +215,15 @@ func decodeIptcRecordData(r io.Reader) (IptcRecordTag, []byte, error) {
 	return recTag, data, nil
 }
 
-// DecodeIptc according to https://iptc.org/std/IIM/4.2/specification/IIMV4.2.pdf
+// DecodeIptc according to https://iptc.org/std/IIM/4.2/specification/IIMV4.2.pdf. Strings are converted to UTF-8
+// (see detectIptcCharset) without a fallback character set
 func DecodeIptc(r io.Reader) (map[IptcRecordTag]IptcRecordDataset, error) {
+	ret, _, err := decodeIptc(r, nil)
+	return ret, err
+}
+
+// decodeIptc decodes all records in r. Returns the decoded records and the character set of the strings
+func decodeIptc(r io.Reader, fallback encoding.Encoding) (map[IptcRecordTag]IptcRecordDataset, encoding.Encoding, error) {
 	allTags := map[IptcRecordTag][][]byte{}
 	var err error
 
@@ -230,6 +239,7 @@ func DecodeIptc(r io.Reader) (map[IptcRecordTag]IptcRecordDataset, error) {
 		}
 	}
 	ret := map[IptcRecordTag]IptcRecordDataset{}
+	enc := detectIptcCharset(allTags, fallback)
 
 	for rt, data := range allTags {
 		desc, ok := IptcTagDescriptions[rt]
@@ -237,6 +247,11 @@ func DecodeIptc(r io.Reader) (map[IptcRecordTag]IptcRecordDataset, error) {
 			fmt.Println("Could not find tag so skipping it: ", rt)
 			continue
 		}
+		if enc != nil && (desc.Type == IptcString || desc.Type == IptcDigits) {
+			for i, d := range data {
+				data[i] = []byte(decodeIptcString(enc, d))
+			}
+		}
 		ds := IptcRecordDataset{Record: rt.Record, Tag: rt.Tag, Repeatable: desc.Repeatable, Type: desc.Type}
 		if desc.Repeatable {
 			ds.Data, err = decodeIptcDataToSlice(desc, data)
@@ -244,11 +259,11 @@ func DecodeIptc(r io.Reader) (map[IptcRecordTag]IptcRecordDataset, error) {
 			ds.Data, err = decodeIptcData(desc, data[0])
 		}
 		if err != nil {
-			return ret, err
+			return ret, enc, err
 		}
 		ret[rt] = ds
 	}
-	return ret, nil
+	return ret, enc, nil
 }
 
 func encodeIptcRecordData(bw *bufio.Writer, record IptcRecord, tag IptcTag, val interface{}) error {
@@ -375,15 +390,22 @@ func EncodeIptc(w io.Writer, recs map[IptcRecordTag]IptcRecordDataset) error {
 
 // ParseIptcJpeg extracts iptc data from a jpeg segment list. Returns ErrNoIptc if the segments dont contain any IPTC data
 func ParseIptcJpeg(sl *jpegstructure.SegmentList) (map[IptcRecordTag]IptcRecordDataset, error) {
+	ret, _, _, err := parseIptcResources(sl, charmap.Windows1252)
+	return ret, err
+}
+
+// parseIptcResources extracts iptc data, its character set as well as the photoshop resources it was stored in
+func parseIptcResources(sl *jpegstructure.SegmentList, fallback encoding.Encoding) (map[IptcRecordTag]IptcRecordDataset, encoding.Encoding, map[uint16]photoshop.ImageResource, error) {
 	ret := map[IptcRecordTag]IptcRecordDataset{}
 	_, res, err := photoshop.ParseJpeg(sl)
 	if err != nil && err == photoshop.ErrNoPhotoshopBlock {
-		return ret, ErrNoIptc
+		return ret, nil, res, ErrNoIptc
 	} else if err != nil {
-		return ret, err
+		return ret, nil, res, err
 	}
 	if iptcData, ok := res[photoshop.IptcId]; ok {
-		return DecodeIptc(bytes.NewReader(iptcData.Data))
+		ret, enc, err := decodeIptc(bytes.NewReader(iptcData.Data), fallback)
+		return ret, enc, res, err
 	}
-	return ret, ErrNoIptc
+	return ret, nil, res, ErrNoIptc
 }
@@ -0,0 +1,126 @@
+package metadata
+
+import (
+	"strings"
+)
+
+// HierarchySeparator separates the levels of a hierarchical keyword, e.g. "Places|Europe|Sweden"
+const HierarchySeparator = "|"
+
+const digiKamSeparator = "/"
+
+const (
+	xmpLrHierarchicalSubject = "lr:hierarchicalSubject"
+	xmpDigiKamTagsList       = "digiKam:TagsList"
+)
+
+func init() {
+	registerXmpNamespace("lr", "http://ns.adobe.com/lightroom/1.0/")
+	registerXmpNamespace("digiKam", "http://www.digikam.org/ns/1.0/")
+}
+
+// FlattenKeywords returns all levels of the hierarchical keywords (ancestors as well as leaves) as
+// flat keywords. Duplicates (case insensitive) are removed
+func FlattenKeywords(hierarchical []string) []string {
+	levels := []string{}
+	for _, h := range hierarchical {
+		for _, k := range strings.Split(h, HierarchySeparator) {
+			levels = append(levels, strings.TrimSpace(k))
+		}
+	}
+	return mergeKeywords(levels)
+}
+
+// GetHierarchicalKeywords returns lr:hierarchicalSubject. If that does not exist digiKam:TagsList is
+// used instead. Levels are separated with HierarchySeparator
+func (xd XmpData) GetHierarchicalKeywords() []string {
+	if ret := xd.getPathStrings(xmpLrHierarchicalSubject); len(ret) > 0 {
+		return ret
+	}
+	ret := xd.getPathStrings(xmpDigiKamTagsList)
+	for i := range ret {
+		ret[i] = strings.ReplaceAll(ret[i], digiKamSeparator, HierarchySeparator)
+	}
+	return ret
+}
+
+// SetHierarchicalKeywords replaces lr:hierarchicalSubject and digiKam:TagsList with keywords. Levels should
+// be separated with HierarchySeparator
+func (xe *XmpEditor) SetHierarchicalKeywords(keywords []string) error {
+	if err := xe.setPathStrings(xmpLrHierarchicalSubject, keywords); err != nil {
+		return err
+	}
+	tags := make([]string, len(keywords))
+	for i, k := range keywords {
+		tags[i] = strings.ReplaceAll(k, HierarchySeparator, digiKamSeparator)
+	}
+	return xe.setPathStrings(xmpDigiKamTagsList, tags)
+}
+
+// SetHierarchicalKeywords sets the hierarchical keywords in Xmp. If flatten is true all levels of the
+// keywords are also added to the flat keywords in Xmp and Iptc (existing flat keywords from both are kept)
+func (je *JpegEditor) SetHierarchicalKeywords(keywords []string, flatten bool) error {
+	if err := je.xe.SetHierarchicalKeywords(keywords); err != nil {
+		return err
+	}
+	if !flatten {
+		return nil
+	}
+	return je.SetKeywords(mergeKeywords(je.keywords(), FlattenKeywords(keywords)))
+}
+
+// mergeKeywords concatenates lists of keywords and removes (case insensitive) duplicates. The first
+// occurrence of a keyword is kept
+func mergeKeywords(lists ...[]string) []string {
+	ret := []string{}
+	seen := map[string]bool{}
+	for _, l := range lists {
+		for _, k := range l {
+			if k == "" || seen[strings.ToLower(k)] {
+				continue
+			}
+			seen[strings.ToLower(k)] = true
+			ret = append(ret, k)
+		}
+	}
+	return ret
+}
+
+// keywords returns the union of the Xmp and Iptc keywords of this editor
+func (je *JpegEditor) keywords() []string {
+	return mergeKeywords(je.xe.GetKeywords(), je.ie.keywords())
+}
+
+// AddKeywords adds keywords to the existing keywords in Xmp and Iptc. Keywords that already
+// exists (case insensitive) are not added again
+func (je *JpegEditor) AddKeywords(keywords []string) error {
+	return je.SetKeywords(mergeKeywords(je.keywords(), keywords))
+}
+
+// RemoveKeywords removes keywords (case insensitive) from Xmp and Iptc
+func (je *JpegEditor) RemoveKeywords(keywords []string) error {
+	remove := map[string]bool{}
+	for _, k := range keywords {
+		remove[strings.ToLower(k)] = true
+	}
+	ret := []string{}
+	for _, k := range je.keywords() {
+		if !remove[strings.ToLower(k)] {
+			ret = append(ret, k)
+		}
+	}
+	return je.SetKeywords(ret)
+}
+
+// RenameKeyword renames keyword from (case insensitive) to to in Xmp and Iptc. If to already exists
+// from is simply removed
+func (je *JpegEditor) RenameKeyword(from, to string) error {
+	ret := []string{}
+	for _, k := range je.keywords() {
+		if strings.EqualFold(k, from) {
+			k = to
+		}
+		ret = append(ret, k)
+	}
+	return je.SetKeywords(mergeKeywords(ret))
+}
@@ -0,0 +1,131 @@
+package metadata
+
+import (
+	"reflect"
+	"testing"
+	"trimmer.io/go-xmp/xmp"
+)
+
+func TestFlattenKeywords(t *testing.T) {
+	exp := []string{"Places", "Europe", "Sweden", "Norway", "People"}
+	act := FlattenKeywords([]string{"Places|Europe|Sweden", "places|Europe|Norway", "People"})
+	if !reflect.DeepEqual(act, exp) {
+		t.Errorf("Expected %v got %v", exp, act)
+	}
+}
+
+func TestXmpEditor_SetHierarchicalKeywords(t *testing.T) {
+	exp := []string{"Places|Europe|Sweden", "People|Anna"}
+	xe, _ := NewXmpEditorFromDocument(xmp.NewDocument())
+	if err := xe.SetHierarchicalKeywords(exp); err != nil {
+		t.Fatalf("Could not set hierarchical keywords: %v", err)
+	}
+	xd := reloadXmpEditor(xe, t)
+	if act := xd.GetHierarchicalKeywords(); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Expected %v got %v", exp, act)
+	}
+	expTags := []string{"Places/Europe/Sweden", "People/Anna"}
+	if act := xd.getPathStrings(xmpDigiKamTagsList); !reflect.DeepEqual(act, expTags) {
+		t.Errorf("Expected %v got %v", expTags, act)
+	}
+}
+
+func TestXmpData_GetHierarchicalKeywordsDigiKam(t *testing.T) {
+	xe, _ := NewXmpEditorFromDocument(xmp.NewDocument())
+	if err := xe.setPathStrings(xmpDigiKamTagsList, []string{"Places/Europe/Sweden"}); err != nil {
+		t.Fatalf("Could not set tags list: %v", err)
+	}
+	exp := []string{"Places|Europe|Sweden"}
+	if act := reloadXmpEditor(xe, t).GetHierarchicalKeywords(); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Expected %v got %v", exp, act)
+	}
+}
+
+func TestJpegEditor_SetHierarchicalKeywords(t *testing.T) {
+	for _, fname := range []string{LeicaImg, NoExifImg} {
+		je := getJpegEditor(fname, t)
+		if err := je.SetKeywords([]string{"flat"}); err != nil {
+			t.Fatalf("Could not set keywords: %v", err)
+		}
+		je = reloadJpegEditor(je, true, t)
+		hier := []string{"Places|Europe|Sweden", "People|Anna"}
+		if err := je.SetHierarchicalKeywords(hier, true); err != nil {
+			t.Fatalf("Could not set hierarchical keywords: %v", err)
+		}
+		md := jpegEditorMD(je, t)
+		expFlat := []string{"flat", "Places", "Europe", "Sweden", "People", "Anna"}
+		if act := md.Summary().Keywords; !reflect.DeepEqual(act, expFlat) {
+			t.Errorf("Expected %v got %v", expFlat, act)
+		}
+		if act := md.Iptc().GetKeywords(); !reflect.DeepEqual(act, expFlat) {
+			t.Errorf("Expected %v got %v", expFlat, act)
+		}
+		if act := md.Summary().HierarchicalKeywords; !reflect.DeepEqual(act, hier) {
+			t.Errorf("Expected %v got %v", hier, act)
+		}
+	}
+}
+
+func TestJpegEditor_SetHierarchicalKeywordsIptcOnly(t *testing.T) {
+	je := getJpegEditor(LeicaImg, t)
+	je.Xmp().SetKeywords([]string{"xmp", "sweden"})
+	if err := je.Iptc().SetKeywords([]string{"iptc only"}); err != nil {
+		t.Fatalf("Could not set keywords: %v", err)
+	}
+	je = reloadJpegEditor(je, true, t)
+	if err := je.SetHierarchicalKeywords([]string{"Places|Europe|Sweden"}, true); err != nil {
+		t.Fatalf("Could not set hierarchical keywords: %v", err)
+	}
+	md := jpegEditorMD(je, t)
+	exp := []string{"xmp", "sweden", "iptc only", "Places", "Europe"}
+	if act := md.Iptc().GetKeywords(); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Expected %v got %v", exp, act)
+	}
+}
+
+func TestMergeKeywords(t *testing.T) {
+	exp := []string{"Anna", "Sweden", "published"}
+	act := mergeKeywords([]string{"Anna", "Sweden"}, []string{"anna", "published", "SWEDEN", ""})
+	if !reflect.DeepEqual(act, exp) {
+		t.Errorf("Expected %v got %v", exp, act)
+	}
+}
+
+func TestJpegEditor_AddRemoveKeywords(t *testing.T) {
+	for _, fname := range []string{LeicaImg, NoExifImg} {
+		je := getJpegEditor(fname, t)
+		//iptc and xmp out of sync
+		je.Xmp().SetKeywords([]string{"curated", "Sweden"})
+		if err := je.Iptc().SetKeywords([]string{"sweden", "iptc only"}); err != nil {
+			t.Fatalf("Could not set keywords: %v", err)
+		}
+		je = reloadJpegEditor(je, true, t)
+		if err := je.AddKeywords([]string{"published", "CURATED"}); err != nil {
+			t.Fatalf("Could not add keywords: %v", err)
+		}
+		exp := []string{"curated", "Sweden", "iptc only", "published"}
+		md := jpegEditorMD(je, t)
+		if act := md.Xmp().GetKeywords(); !reflect.DeepEqual(act, exp) {
+			t.Errorf("Expected %v got %v", exp, act)
+		}
+		if act := md.Iptc().GetKeywords(); !reflect.DeepEqual(act, exp) {
+			t.Errorf("Expected %v got %v", exp, act)
+		}
+
+		je = reloadJpegEditor(je, true, t)
+		if err := je.RemoveKeywords([]string{"IPTC ONLY", "missing"}); err != nil {
+			t.Fatalf("Could not remove keywords: %v", err)
+		}
+		if err := je.RenameKeyword("sweden", "Sverige"); err != nil {
+			t.Fatalf("Could not rename keyword: %v", err)
+		}
+		exp = []string{"curated", "Sverige", "published"}
+		md = jpegEditorMD(je, t)
+		if act := md.Xmp().GetKeywords(); !reflect.DeepEqual(act, exp) {
+			t.Errorf("Expected %v got %v", exp, act)
+		}
+		if act := md.Iptc().GetKeywords(); !reflect.DeepEqual(act, exp) {
+			t.Errorf("Expected %v got %v", exp, act)
+		}
+	}
+}
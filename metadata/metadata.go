@@ -18,40 +18,47 @@ var ErrParseImage = errors.New("Could not parse image")
 
 // Summary holds the most common image metadata of interest
 type Summary struct {
-	Title                   string        `json:"title,omitempty"`
-	Keywords                []string      `json:"keywords,omitempty"`
-	Software                string        `json:"software,omitempty"`
-	Rating                  uint16        `json:"rating,omitempty"`
-	CameraMake              string        `json:"cameraMake,omitempty"`
-	CameraModel             string        `json:"cameraModel,omitempty"`
-	LensInfo                LensInfo      `json:"lensInfo,omitempty"`
-	LensModel               string        `json:"lensModel,omitempty"`
-	LensMake                string        `json:"lensMake,omitempty"`
-	FocalLength             URat          `json:"focalLength,omitempty"`
-	FocalLengthIn35mmFormat uint16        `json:"focalLengthIn35mmFormat,omitempty"`
-	MaxApertureValue        URat          `json:"maxApertureValue,omitempty"`
-	FlashMode               uint16        `json:"flashMode,omitempty"`
-	ExposureTime            URat          `json:"exposureTime,omitempty"`
-	ExposureCompensation    Rat           `json:"exposureCompensation,omitempty"`
-	ExposureProgram         uint16        `json:"exposureProgram,omitempty"`
-	FNumber                 URat          `json:"fNumber,omitempty"`
-	ISO                     uint16        `json:"ISO,omitempty"`
-	ColorSpace              uint16        `json:"colorSpace,omitempty"`
-	XResolution             URat          `json:"xResolution,omitempty"`
-	YResolution             URat          `json:"yResolution,omitempty"`
-	OriginalDate            time.Time     `json:"originalDate,omitempty"`
-	ModifyDate              time.Time     `json:"modifyDate,omitempty"`
-	GPSInfo                 *exif.GpsInfo `json:"gpsInfo,omitempty"`
-	City                    string        `json:"city,omitempty"`
-	Country                 string        `json:"country,omitempty"`
-	State                   string        `json:"state,omitempty"`
+	Title                   string            `json:"title,omitempty"`
+	Description             string            `json:"description,omitempty"`
+	Titles                  map[string]string `json:"titles,omitempty"`
+	Descriptions            map[string]string `json:"descriptions,omitempty"`
+	Keywords                []string          `json:"keywords,omitempty"`
+	HierarchicalKeywords    []string          `json:"hierarchicalKeywords,omitempty"`
+	Software                string            `json:"software,omitempty"`
+	Rating                  uint16            `json:"rating,omitempty"`
+	CameraMake              string            `json:"cameraMake,omitempty"`
+	CameraModel             string            `json:"cameraModel,omitempty"`
+	LensInfo                LensInfo          `json:"lensInfo,omitempty"`
+	LensModel               string            `json:"lensModel,omitempty"`
+	LensMake                string            `json:"lensMake,omitempty"`
+	FocalLength             URat              `json:"focalLength,omitempty"`
+	FocalLengthIn35mmFormat uint16            `json:"focalLengthIn35mmFormat,omitempty"`
+	MaxApertureValue        URat              `json:"maxApertureValue,omitempty"`
+	FlashMode               uint16            `json:"flashMode,omitempty"`
+	ExposureTime            URat              `json:"exposureTime,omitempty"`
+	ExposureCompensation    Rat               `json:"exposureCompensation,omitempty"`
+	ExposureProgram         uint16            `json:"exposureProgram,omitempty"`
+	FNumber                 URat              `json:"fNumber,omitempty"`
+	ISO                     uint16            `json:"ISO,omitempty"`
+	ColorSpace              uint16            `json:"colorSpace,omitempty"`
+	XResolution             URat              `json:"xResolution,omitempty"`
+	YResolution             URat              `json:"yResolution,omitempty"`
+	OriginalDate            time.Time         `json:"originalDate,omitempty"`
+	OriginalDateZone        ZoneSource        `json:"originalDateZone,omitempty"`
+	ModifyDate              time.Time         `json:"modifyDate,omitempty"`
+	GPSInfo                 *exif.GpsInfo     `json:"gpsInfo,omitempty"`
+	City                    string            `json:"city,omitempty"`
+	Country                 string            `json:"country,omitempty"`
+	State                   string            `json:"state,omitempty"`
 }
 
 func (ec Summary) String() string {
 	sb := &strings.Builder{}
 	sb.WriteString("Summary:{\n")
 	sb.WriteString(fmt.Sprintf("  Title: %v\n", ec.Title))
+	sb.WriteString(fmt.Sprintf("  Description: %v\n", ec.Description))
 	sb.WriteString(fmt.Sprintf("  Keywords: %v\n", strings.Join(ec.Keywords, ", ")))
+	sb.WriteString(fmt.Sprintf("  Hierarchical Keywords: %v\n", strings.Join(ec.HierarchicalKeywords, ", ")))
 	sb.WriteString(fmt.Sprintf("  Software: %v\n", ec.Software))
 	sb.WriteString(fmt.Sprintf("  Rating: %v\n", ec.Rating))
 	sb.WriteString(fmt.Sprintf("  Camera Make: %v\n", ec.CameraMake))
@@ -71,7 +78,7 @@ func (ec Summary) String() string {
 	sb.WriteString(fmt.Sprintf("  ColorSpace: %v\n", ExifValueString(ExifIFD, ExifIFD_ColorSpace, ec.ColorSpace)))
 	sb.WriteString(fmt.Sprintf("  XResolution: %v\n", ec.XResolution))
 	sb.WriteString(fmt.Sprintf("  YResolution: %v\n", ec.YResolution))
-	sb.WriteString(fmt.Sprintf("  OriginalDate: %v\n", ec.OriginalDate))
+	sb.WriteString(fmt.Sprintf("  OriginalDate: %v (zone: %v)\n", ec.OriginalDate, ec.OriginalDateZone))
 	sb.WriteString(fmt.Sprintf("  ModifyDate: %v\n", ec.ModifyDate))
 	sb.WriteString(fmt.Sprintf("  GPSInfo:%v\n", ec.GPSInfo))
 	sb.WriteString(fmt.Sprintf("  City: %v\n", ec.City))
@@ -88,6 +95,8 @@ type MetaData struct {
 	exifData    *ExifData
 	summary     *Summary
 	summaryErr  error
+	conflicts   []Conflict
+	parseErrors []ParseError
 	ImageWidth  uint
 	ImageHeight uint
 }
@@ -113,8 +122,23 @@ func NewMetaDataFromFile(filename string) (*MetaData, error) {
 
 // NewMetaData reads a jpeg image byte slice
 func NewMetaData(data []byte) (*MetaData, error) {
+	return newMetaData(data, ReadOptions{})
+}
+
+// newMetaData reads data using opts. In lenient mode broken segments and metadata are skipped and recorded as
+// ParseErrors instead of failing
+func newMetaData(data []byte, opts ReadOptions) (*MetaData, error) {
+	lenient := opts.Lenient
+	iptcFallback, err := iptcEncoding(opts.IptcCharset)
+	if err != nil {
+		return nil, err
+	}
 	ret := MetaData{}
 	segments, err := parseJpegBytes(data)
+	if err != nil && lenient {
+		data, ret.parseErrors = salvageJpeg(data)
+		segments, err = parseJpegBytes(data)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -123,31 +147,52 @@ func NewMetaData(data []byte) (*MetaData, error) {
 
 	ret.exifData, exifErr = NewExifData(segments)
 	if exifErr != nil && exifErr != ErrExifNoData {
-		return nil, exifErr
+		if !lenient {
+			return nil, exifErr
+		}
+		rawExif, _ := findRawExif(segments)
+		var errs []ParseError
+		ret.exifData, errs = newExifDataLenient(rawExif)
+		ret.parseErrors = append(append(ret.parseErrors, ParseError{Source: SourceExif, Err: exifErr}), errs...)
 	}
 
-	ret.iptcData, iptcErr = NewIptcData(segments)
+	ret.iptcData, iptcErr = newIptcData(segments, iptcFallback)
 	if iptcErr != nil && iptcErr != ErrNoIptc {
-		return nil, iptcErr
+		if !lenient {
+			return nil, iptcErr
+		}
+		ret.parseErrors = append(ret.parseErrors, ParseError{Source: SourceIptc, Err: iptcErr})
 	}
 
 	ret.xmpData, xmpErr = NewXmpData(segments)
 	if xmpErr != nil && xmpErr != ErrNoXmp {
-		return nil, xmpErr
+		if !lenient {
+			return nil, xmpErr
+		}
+		ret.parseErrors = append(ret.parseErrors, ParseError{Source: SourceXmp, Err: xmpErr})
 	}
 
 	//Extract ImageWidth/Height
-	img, err := jpeg.Decode(bytes.NewReader(data))
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
 	if err != nil {
+		if lenient {
+			ret.parseErrors = append(ret.parseErrors, ParseError{Source: SourceJpeg, Err: err})
+			return &ret, nil
+		}
 		return &ret, err
 	}
 
-	ret.ImageWidth = uint(img.Bounds().Dx())
-	ret.ImageHeight = uint(img.Bounds().Dy())
+	ret.ImageWidth = uint(cfg.Width)
+	ret.ImageHeight = uint(cfg.Height)
 
 	return &ret, nil
 }
 
+// ParseErrors returns the problems that were skipped when the metadata was read in lenient mode
+func (md *MetaData) ParseErrors() []ParseError {
+	return md.parseErrors
+}
+
 // Exif returens the exif portion of the MetaData. Can be nil
 func (md *MetaData) Exif() *ExifData {
 	return md.exifData
@@ -165,20 +210,16 @@ func (md *MetaData) Summary() *Summary {
 		return md.summary
 	}
 	md.summary = &Summary{}
-	var exifErr, iptcErr, xmpErr error
+	var exifErr, xmpErr error
 	if !md.exifData.IsEmpty() {
 		exifErr = md.extractExifTags()
 	}
 	if !md.xmpData.IsEmpty() {
 		xmpErr = md.extractXmp()
 	}
-	if !md.iptcData.IsEmpty() {
-		iptcErr = md.extractIPTC()
-	}
+	md.reconcileSummary()
 	if exifErr != nil {
 		md.summaryErr = exifErr
-	} else if iptcErr != nil {
-		md.summaryErr = iptcErr
 	} else if xmpErr != nil {
 		md.summaryErr = xmpErr
 	}
@@ -208,11 +249,21 @@ func (md *MetaData) String() string {
 	return sb.String()
 }
 
-func (md *MetaData) extractIPTC() error {
-	var err error
-	md.summary.Title = md.iptcData.GetTitle()
-	md.summary.Keywords = md.iptcData.GetKeywords()
-	return err
+// reconcileSummary sets the fields that exists in several of exif, iptc and xmp using the MWG precedence rules.
+// Exif UserComment is used as a last resort for the description
+func (md *MetaData) reconcileSummary() {
+	r := newMwgReconciler(md)
+	exifDescription := ""
+	if !md.exifData.IsEmpty() {
+		exifDescription = md.exifData.GetImageDescription()
+	}
+	md.summary.Title = r.str("Title", "", md.iptcData.GetTitle(), md.xmpData.GetTitle())
+	md.summary.Description = r.str("Description", exifDescription, md.iptcData.GetDescription(), md.xmpData.GetDescription())
+	if md.summary.Description == "" && !md.exifData.IsEmpty() {
+		md.summary.Description = strings.TrimSpace(md.exifData.GetUserComment())
+	}
+	md.summary.Keywords = r.strs("Keywords", nil, md.iptcData.GetKeywords(), md.xmpData.GetKeywords())
+	md.conflicts = r.conflicts
 }
 
 func (md *MetaData) extractExifTags() error {
@@ -238,6 +289,11 @@ func (md *MetaData) extractExifTags() error {
 		scanR(IFD_DNGLensInfo, &md.summary.LensInfo)
 	}
 	scanE(ExifIFD_LensModel, &md.summary.LensModel)
+	if md.summary.LensModel == "" { //older bodies only identify the lens in the maker note
+		if mn, e := md.exifData.MakerNote(); e == nil {
+			md.summary.LensModel = mn.LensModel
+		}
+	}
 	scanE(ExifIFD_LensMake, &md.summary.LensMake)
 	scanE(ExifIFD_FocalLength, &md.summary.FocalLength)
 	scanE(ExifIFD_FocalLengthIn35mmFormat, &md.summary.FocalLengthIn35mmFormat)
@@ -253,7 +309,11 @@ func (md *MetaData) extractExifTags() error {
 	scanR(IFD_YResolution, &md.summary.YResolution)
 	scanR(IFD_Software, &md.summary.Software)
 
-	_ = md.exifData.ScanExifDate(OriginalDate, &md.summary.OriginalDate)
+	original := ExifTimestamp{}
+	if md.exifData.ScanExifTimestamp(OriginalDate, &original) == nil {
+		md.summary.OriginalDate = original.Time
+		md.summary.OriginalDateZone = original.Zone
+	}
 	_ = md.exifData.ScanExifDate(ModifyDate, &md.summary.ModifyDate)
 
 	//GPSInfo
@@ -264,12 +324,13 @@ func (md *MetaData) extractExifTags() error {
 }
 
 func (md *MetaData) extractXmp() error {
-
-	if md.summary.Title == "" {
-		md.summary.Title = md.xmpData.GetTitle()
+	md.summary.HierarchicalKeywords = md.xmpData.GetHierarchicalKeywords()
+	//only keep the language alternatives if there is more than the default language
+	if titles := md.xmpData.Titles(); len(titles) > 1 {
+		md.summary.Titles = titles
 	}
-	if len(md.summary.Keywords) == 0 {
-		md.summary.Keywords = md.xmpData.GetKeywords()
+	if descriptions := md.xmpData.Descriptions(); len(descriptions) > 1 {
+		md.summary.Descriptions = descriptions
 	}
 	if md.summary.Rating == 0 {
 		md.summary.Rating = md.xmpData.GetRating()
@@ -14,6 +14,9 @@ const GPSImg = AssetPath + "gps.jpg"
 const NonImageFile = AssetPath + "exiftool-leica-g1.json"
 const XmpFile = AssetPath + "xmp.xml"
 
+// LeicaLargeResources are the leica photoshop resources with extra thumbnails so that they do not fit in one segment
+const LeicaLargeResources = AssetPath + "leicaPhotoshopResourcesLarge.data"
+
 func getAssetBytes(fname string, t *testing.T) []byte {
 	b, err := os.ReadFile(fname)
 	if err != nil {
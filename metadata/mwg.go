@@ -0,0 +1,196 @@
+package metadata
+
+/*
+Reconciliation of exif, iptc and xmp values according to the Metadata Working Group (MWG) guidelines.
+See https://web.archive.org/web/20180919181934/http://www.metadataworkinggroup.org/pdf/mwg_guidance.pdf
+*/
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const xmpPsLegacyIptcDigest = "photoshop:LegacyIPTCDigest"
+
+// IptcDigestState describes how a stored IPTC digest relates to the IPTC data
+type IptcDigestState int
+
+// The different digest states
+const (
+	// IptcDigestMissing no digest was stored along with the IPTC data
+	IptcDigestMissing IptcDigestState = iota
+	// IptcDigestMatch the IPTC data has not changed since xmp was last synchronized with it
+	IptcDigestMatch
+	// IptcDigestMismatch the IPTC data was changed by an application that did not update xmp
+	IptcDigestMismatch
+)
+
+func (s IptcDigestState) String() string {
+	switch s {
+	case IptcDigestMatch:
+		return "match"
+	case IptcDigestMismatch:
+		return "mismatch"
+	default:
+		return "missing"
+	}
+}
+
+// MetadataSource identifies the metadata block a value was read from
+type MetadataSource string
+
+// The different metadata sources
+const (
+	SourceExif MetadataSource = "exif"
+	SourceIptc MetadataSource = "iptc"
+	SourceXmp  MetadataSource = "xmp"
+)
+
+var mwgSources = [3]MetadataSource{SourceExif, SourceIptc, SourceXmp}
+
+// Conflict describes a field where exif, iptc and xmp hold different values. Used is the source
+// that was picked according to the MWG precedence rules
+type Conflict struct {
+	Field  string                    `json:"field"`
+	Values map[MetadataSource]string `json:"values"`
+	Used   MetadataSource            `json:"used"`
+}
+
+func (c Conflict) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%s (using %s):", c.Field, c.Used))
+	for _, s := range mwgSources {
+		if v, ok := c.Values[s]; ok {
+			sb.WriteString(fmt.Sprintf(" %s=%q", s, v))
+		}
+	}
+	return sb.String()
+}
+
+// IptcDigest computes the md5 digest of an IPTC IIM block as stored in the photoshop digest resource
+func IptcDigest(iim []byte) []byte {
+	d := md5.Sum(iim)
+	return d[:]
+}
+
+func iptcDigestString(digest []byte) string {
+	return strings.ToUpper(hex.EncodeToString(digest))
+}
+
+// matchesDigest checks digest against the IIM block. Some writers pad the block so it is also
+// checked without trailing NULs
+func (ipd *IptcData) matchesDigest(digest []byte) bool {
+	if bytes.Equal(IptcDigest(ipd.iim), digest) {
+		return true
+	}
+	trimmed := bytes.TrimRight(ipd.iim, "\x00")
+	return len(trimmed) != len(ipd.iim) && bytes.Equal(IptcDigest(trimmed), digest)
+}
+
+// DigestState compares the photoshop digest resource with the IPTC data
+func (ipd *IptcData) DigestState() IptcDigestState {
+	if len(ipd.digest) == 0 {
+		return IptcDigestMissing
+	}
+	if ipd.matchesDigest(ipd.digest) {
+		return IptcDigestMatch
+	}
+	return IptcDigestMismatch
+}
+
+// IptcDigestState returns the state of the IPTC digest. The photoshop digest resource is used if it exists,
+// otherwise photoshop:LegacyIPTCDigest from xmp
+func (md *MetaData) IptcDigestState() IptcDigestState {
+	if md.iptcData.IsEmpty() {
+		return IptcDigestMissing
+	}
+	if s := md.iptcData.DigestState(); s != IptcDigestMissing {
+		return s
+	}
+	hexDigest := md.xmpData.getPathString(xmpPsLegacyIptcDigest)
+	if hexDigest == "" {
+		return IptcDigestMissing
+	}
+	if d, err := hex.DecodeString(hexDigest); err == nil && md.iptcData.matchesDigest(d) {
+		return IptcDigestMatch
+	}
+	return IptcDigestMismatch
+}
+
+// Conflicts returns all fields (of the Summary and Rights) where exif, iptc and xmp disagree
+func (md *MetaData) Conflicts() []Conflict {
+	md.Summary()
+	ret := append([]Conflict{}, md.conflicts...)
+	_, rightsConflicts := md.rights()
+	return append(ret, rightsConflicts...)
+}
+
+// mwgReconciler picks values in MWG order: exif first and then iptc or xmp depending on the iptc digest
+type mwgReconciler struct {
+	//iptcInSync is true if the iptc digest matches. Xmp is then preferred and iptc only used as a fallback
+	//since its values are already reflected in xmp (differing iptc values are not conflicts)
+	iptcInSync bool
+	conflicts  []Conflict
+}
+
+func newMwgReconciler(md *MetaData) *mwgReconciler {
+	return &mwgReconciler{iptcInSync: md.IptcDigestState() == IptcDigestMatch}
+}
+
+// pick returns the index of the value to use from values (in exif, iptc, xmp order) or -1 if all are empty
+func (r *mwgReconciler) pick(field string, values [3]string) int {
+	order := []int{0, 1, 2}
+	if r.iptcInSync {
+		order = []int{0, 2, 1}
+	}
+	used := -1
+	for _, i := range order {
+		if values[i] != "" {
+			used = i
+			break
+		}
+	}
+	if used == -1 {
+		return used
+	}
+	conflict := false
+	for i, v := range values {
+		if v == "" || v == values[used] || (i == 1 && r.iptcInSync) {
+			continue
+		}
+		conflict = true
+	}
+	if conflict {
+		c := Conflict{Field: field, Values: map[MetadataSource]string{}, Used: mwgSources[used]}
+		for i, v := range values {
+			if v != "" {
+				c.Values[mwgSources[i]] = v
+			}
+		}
+		r.conflicts = append(r.conflicts, c)
+	}
+	return used
+}
+
+func (r *mwgReconciler) str(field string, exif, iptc, xmp string) string {
+	values := [3]string{strings.TrimSpace(exif), iptc, xmp}
+	if i := r.pick(field, values); i >= 0 {
+		return values[i]
+	}
+	return ""
+}
+
+func (r *mwgReconciler) strs(field string, exif, iptc, xmp []string) []string {
+	values := [3][]string{exif, iptc, xmp}
+	joined := [3]string{}
+	for i, v := range values {
+		joined[i] = strings.Join(v, ", ")
+	}
+	if i := r.pick(field, joined); i >= 0 {
+		return values[i]
+	}
+	return []string{}
+}
@@ -0,0 +1,147 @@
+package metadata
+
+import (
+	"github.com/msvens/mimage/photoshop"
+	"testing"
+)
+
+// titleEditor returns an editor where iptc and xmp holds different titles
+func titleEditor(t *testing.T) *JpegEditor {
+	je := getJpegEditor(LeicaImg, t)
+	je.Xmp().SetTitle("xmp title")
+	if err := je.Iptc().SetTitle("iptc title"); err != nil {
+		t.Fatalf("Could not set iptc title: %v", err)
+	}
+	return reloadJpegEditor(je, true, t)
+}
+
+// setDigest writes digest (or removes it if nil) without recomputing it
+func setDigest(je *JpegEditor, digest []byte, t *testing.T) *JpegEditor {
+	if digest == nil {
+		je.ie.resources.Delete(photoshop.DigestId)
+	} else {
+		je.ie.resources.Set(photoshop.NewPhotoshopImageResource(photoshop.DigestId, digest))
+	}
+	if err := je.setIptc(); err != nil {
+		t.Fatalf("Could not write iptc: %v", err)
+	}
+	return reloadJpegEditor(je, true, t)
+}
+
+// syncedTitleEditor returns an editor where iptc and xmp were synced by JpegEditor.SetTitle before xmp was
+// given a different title
+func syncedTitleEditor(t *testing.T) *JpegEditor {
+	je := getJpegEditor(LeicaImg, t)
+	if err := je.SetTitle("synced title"); err != nil {
+		t.Fatalf("Could not set title: %v", err)
+	}
+	je.Xmp().SetTitle("xmp title")
+	return reloadJpegEditor(je, true, t)
+}
+
+func TestJpegEditor_IptcDigest(t *testing.T) {
+	je := syncedTitleEditor(t)
+	md := jpegEditorMD(je, t)
+	if md.IptcDigestState() != IptcDigestMatch {
+		t.Errorf("Expected %v got %v", IptcDigestMatch, md.IptcDigestState())
+	}
+	if md.Iptc().DigestState() != IptcDigestMatch {
+		t.Errorf("Expected %v got %v", IptcDigestMatch, md.Iptc().DigestState())
+	}
+	expDigest := je.Iptc().Digest()
+	if act := md.Xmp().getPathString(xmpPsLegacyIptcDigest); act != expDigest || act == "" {
+		t.Errorf("Expected %v got %v", expDigest, act)
+	}
+	//xmp should take precedence and iptc values already reflected in xmp should not be reported as conflicts
+	if md.Summary().Title != "xmp title" {
+		t.Errorf("Expected %v got %v", "xmp title", md.Summary().Title)
+	}
+	for _, c := range md.Conflicts() {
+		if c.Field == "Title" {
+			t.Errorf("Expected no title conflict got %v", c)
+		}
+	}
+}
+
+func TestJpegEditor_IptcDigestDropped(t *testing.T) {
+	//an iptc only edit is not mirrored in xmp so the digest is removed, also if followed by a synced edit
+	je := syncedTitleEditor(t)
+	if err := je.Iptc().SetTitle("iptc title"); err != nil {
+		t.Fatalf("Could not set iptc title: %v", err)
+	}
+	if err := je.SetDescription("synced description"); err != nil {
+		t.Fatalf("Could not set description: %v", err)
+	}
+	md := jpegEditorMD(je, t)
+	if md.IptcDigestState() != IptcDigestMissing {
+		t.Errorf("Expected %v got %v", IptcDigestMissing, md.IptcDigestState())
+	}
+	if act := md.Xmp().getPathString(xmpPsLegacyIptcDigest); act != "" {
+		t.Errorf("Expected no xmp digest got %v", act)
+	}
+	if md.Summary().Title != "iptc title" {
+		t.Errorf("Expected %v got %v", "iptc title", md.Summary().Title)
+	}
+	//a later synced edit stamps the digest again
+	je = reloadJpegEditor(je, true, t)
+	if err := je.SetTitle("synced again"); err != nil {
+		t.Fatalf("Could not set title: %v", err)
+	}
+	if md = jpegEditorMD(je, t); md.IptcDigestState() != IptcDigestMatch {
+		t.Errorf("Expected %v got %v", IptcDigestMatch, md.IptcDigestState())
+	}
+}
+
+func TestMetaData_IptcDigestMismatch(t *testing.T) {
+	je := setDigest(titleEditor(t), IptcDigest([]byte("changed by someone else")), t)
+	md := jpegEditorMD(je, t)
+	if md.IptcDigestState() != IptcDigestMismatch {
+		t.Errorf("Expected %v got %v", IptcDigestMismatch, md.IptcDigestState())
+	}
+	if md.Summary().Title != "iptc title" {
+		t.Errorf("Expected %v got %v", "iptc title", md.Summary().Title)
+	}
+	found := false
+	for _, c := range md.Conflicts() {
+		if c.Field == "Title" {
+			found = true
+			if c.Used != SourceIptc || c.Values[SourceXmp] != "xmp title" {
+				t.Errorf("Unexpected conflict %v", c)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected title conflict")
+	}
+}
+
+func TestMetaData_IptcDigestMissing(t *testing.T) {
+	je := titleEditor(t)
+	je.xe.Clear(true)
+	je = setDigest(je, nil, t)
+	md := jpegEditorMD(je, t)
+	if md.IptcDigestState() != IptcDigestMissing {
+		t.Errorf("Expected %v got %v", IptcDigestMissing, md.IptcDigestState())
+	}
+	if md.Summary().Title != "iptc title" {
+		t.Errorf("Expected %v got %v", "iptc title", md.Summary().Title)
+	}
+}
+
+func TestMetaData_DescriptionPrecedence(t *testing.T) {
+	je := getJpegEditor(LeicaImg, t)
+	if err := je.Exif().SetImageDescription("exif description"); err != nil {
+		t.Fatalf("Could not set description: %v", err)
+	}
+	if err := je.Iptc().Set(IPTCApplication, IPTCApplication_CaptionAbstract, "iptc description"); err != nil {
+		t.Fatalf("Could not set description: %v", err)
+	}
+	je = setDigest(reloadJpegEditor(je, true, t), IptcDigest([]byte("changed by someone else")), t)
+	md := jpegEditorMD(je, t)
+	if md.Summary().Description != "exif description" {
+		t.Errorf("Expected %v got %v", "exif description", md.Summary().Description)
+	}
+	if len(md.Conflicts()) == 0 {
+		t.Errorf("Expected description conflict")
+	}
+}
@@ -0,0 +1,357 @@
+package metadata
+
+import (
+	"image"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+Regions are stored both according to the Metadata Working Group (mwg-rs:Regions) and the IPTC Extension
+(Iptc4xmpExt:ImageRegion). MWG areas are normalized and given by their center point while IPTC uses
+relative coordinates given by the top left corner. Both are converted to the MWG representation.
+See https://www.iptc.org/std/photometadata/specification/IPTC-PhotoMetadata#image-region and
+http://www.metadataworkinggroup.org/pdf/mwg_guidance.pdf
+*/
+
+const (
+	mwgRegions        = "mwg-rs:Regions"
+	mwgRegionList     = mwgRegions + "/mwg-rs:RegionList"
+	mwgAppliedToDimW  = mwgRegions + "/mwg-rs:AppliedToDimensions/stDim:w"
+	mwgAppliedToDimH  = mwgRegions + "/mwg-rs:AppliedToDimensions/stDim:h"
+	mwgAppliedToUnit  = mwgRegions + "/mwg-rs:AppliedToDimensions/stDim:unit"
+	iptcImageRegion   = "Iptc4xmpExt:ImageRegion"
+	iptcRegionBounds  = "Iptc4xmpExt:RegionBoundary"
+	iptcRegionTypeUri = "http://cv.iptc.org/newscodes/imageregiontype/"
+	iptcRegionRoleUri = "http://cv.iptc.org/newscodes/imageregionrole/"
+)
+
+// RegionType specifies the kind of area a region describes
+type RegionType string
+
+// MWG region types
+const (
+	RegionFace    RegionType = "Face"
+	RegionPet     RegionType = "Pet"
+	RegionFocus   RegionType = "Focus"
+	RegionBarCode RegionType = "BarCode"
+)
+
+// iptcRegionTypes maps RegionType to the IPTC region content type (rCtype) or role (rRole)
+var iptcRegionTypes = map[RegionType]string{
+	RegionFace:    iptcRegionTypeUri + "human",
+	RegionPet:     iptcRegionTypeUri + "animal",
+	RegionBarCode: iptcRegionTypeUri + "visualCode",
+	RegionFocus:   iptcRegionRoleUri + "mainSubjectArea",
+}
+
+// RegionArea is a normalized (0-1) rectangle given by its center point (X,Y), width and height
+type RegionArea struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	W float64 `json:"w"`
+	H float64 `json:"h"`
+}
+
+// Center returns the center point of the area in an image of the given dimensions
+func (ra RegionArea) Center(width, height int) image.Point {
+	return image.Pt(int(math.Round(ra.X*float64(width))), int(math.Round(ra.Y*float64(height))))
+}
+
+// IsZero returns true if the area has no width or height
+func (ra RegionArea) IsZero() bool {
+	return ra.W <= 0 || ra.H <= 0
+}
+
+// Rect returns the area in pixel coordinates of an image of the given dimensions
+func (ra RegionArea) Rect(width, height int) image.Rectangle {
+	x0 := (ra.X - ra.W/2) * float64(width)
+	y0 := (ra.Y - ra.H/2) * float64(height)
+	x1 := (ra.X + ra.W/2) * float64(width)
+	y1 := (ra.Y + ra.H/2) * float64(height)
+	return image.Rect(int(math.Round(x0)), int(math.Round(y0)), int(math.Round(x1)), int(math.Round(y1)))
+}
+
+// Region is a named area of an image, e.g. a face or a focus point
+type Region struct {
+	Name        string     `json:"name,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Type        RegionType `json:"type,omitempty"`
+	Area        RegionArea `json:"area"`
+}
+
+// RegionInfo holds the regions of an image and the image dimensions they were applied to
+type RegionInfo struct {
+	Width   uint     `json:"width,omitempty"`
+	Height  uint     `json:"height,omitempty"`
+	Regions []Region `json:"regions,omitempty"`
+}
+
+// IsEmpty returns true if there are no regions
+func (ri RegionInfo) IsEmpty() bool {
+	return len(ri.Regions) == 0
+}
+
+// Find returns the first region with the given name. If no region matches the name it
+// returns the first region with a type equal to name (e.g. "Focus" or "Face")
+func (ri RegionInfo) Find(name string) (Region, bool) {
+	for _, r := range ri.Regions {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	for _, r := range ri.Regions {
+		if strings.EqualFold(string(r.Type), name) {
+			return r, true
+		}
+	}
+	return Region{}, false
+}
+
+// Crop returns the regions as they would be after cropping an image of the given dimensions to crop.
+// Regions are clipped to the crop and regions outside of the crop are removed. The returned RegionInfo
+// dimensions will be those of the crop
+func (ri RegionInfo) Crop(width, height int, crop image.Rectangle) RegionInfo {
+	ret := RegionInfo{Width: uint(crop.Dx()), Height: uint(crop.Dy())}
+	if width <= 0 || height <= 0 || crop.Empty() {
+		return ret
+	}
+	fw, fh := float64(width), float64(height)
+	cx0, cy0 := float64(crop.Min.X)/fw, float64(crop.Min.Y)/fh
+	cx1, cy1 := float64(crop.Max.X)/fw, float64(crop.Max.Y)/fh
+	cw, ch := cx1-cx0, cy1-cy0
+	for _, r := range ri.Regions {
+		x0 := math.Max(r.Area.X-r.Area.W/2, cx0)
+		y0 := math.Max(r.Area.Y-r.Area.H/2, cy0)
+		x1 := math.Min(r.Area.X+r.Area.W/2, cx1)
+		y1 := math.Min(r.Area.Y+r.Area.H/2, cy1)
+		if x1 <= x0 || y1 <= y0 {
+			continue
+		}
+		r.Area = RegionArea{
+			X: ((x0+x1)/2 - cx0) / cw,
+			Y: ((y0+y1)/2 - cy0) / ch,
+			W: (x1 - x0) / cw,
+			H: (y1 - y0) / ch,
+		}
+		ret.Regions = append(ret.Regions, r)
+	}
+	return ret
+}
+
+// Rotate returns the regions as they would be after rotating an image of the given dimensions angle degrees
+// clockwise (negative is counter clockwise). Multiples of 90 degrees are mapped exactly. For other angles the
+// image is assumed to be enlarged to fit the rotated image and regions are given by the bounds of the rotated
+// areas. The returned RegionInfo dimensions will be those of the rotated image
+func (ri RegionInfo) Rotate(width, height int, angle int) RegionInfo {
+	angle = ((angle % 360) + 360) % 360
+	ret := RegionInfo{Width: uint(width), Height: uint(height)}
+	if angle == 90 || angle == 270 {
+		ret.Width, ret.Height = ret.Height, ret.Width
+	}
+	for _, r := range ri.Regions {
+		a := r.Area
+		switch angle {
+		case 0:
+		case 90:
+			r.Area = RegionArea{X: 1 - a.Y, Y: a.X, W: a.H, H: a.W}
+		case 180:
+			r.Area = RegionArea{X: 1 - a.X, Y: 1 - a.Y, W: a.W, H: a.H}
+		case 270:
+			r.Area = RegionArea{X: a.Y, Y: 1 - a.X, W: a.H, H: a.W}
+		default:
+			r.Area = rotateRegionArea(a, float64(width), float64(height), float64(angle)*math.Pi/180)
+		}
+		ret.Regions = append(ret.Regions, r)
+	}
+	if angle%90 != 0 {
+		sin, cos := math.Abs(math.Sin(float64(angle)*math.Pi/180)), math.Abs(math.Cos(float64(angle)*math.Pi/180))
+		ret.Width = uint(math.Round(float64(width)*cos + float64(height)*sin))
+		ret.Height = uint(math.Round(float64(width)*sin + float64(height)*cos))
+	}
+	return ret
+}
+
+// rotateRegionArea rotates the corners of a around the center of a w*h image theta radians clockwise and returns
+// their bounds in the enlarged image
+func rotateRegionArea(a RegionArea, w, h, theta float64) RegionArea {
+	sin, cos := math.Sin(theta), math.Cos(theta)
+	rw, rh := w*math.Abs(cos)+h*math.Abs(sin), w*math.Abs(sin)+h*math.Abs(cos)
+	x0, y0, x1, y1 := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, c := range [][2]float64{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+		dx := (a.X+c[0]*a.W/2)*w - w/2
+		dy := (a.Y+c[1]*a.H/2)*h - h/2
+		x, y := dx*cos-dy*sin+rw/2, dx*sin+dy*cos+rh/2
+		x0, y0, x1, y1 = math.Min(x0, x), math.Min(y0, y), math.Max(x1, x), math.Max(y1, y)
+	}
+	return RegionArea{X: (x0 + x1) / 2 / rw, Y: (y0 + y1) / 2 / rh, W: (x1 - x0) / rw, H: (y1 - y0) / rh}
+}
+
+func formatRegionFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// GetRegions returns the MWG regions if they exist and otherwise the IPTC image regions
+func (xd XmpData) GetRegions() RegionInfo {
+	if ret := xd.GetMwgRegions(); !ret.IsEmpty() {
+		return ret
+	}
+	return xd.GetIptcRegions()
+}
+
+// GetMwgRegions returns the regions stored in mwg-rs:Regions
+func (xd XmpData) GetMwgRegions() RegionInfo {
+	ret := RegionInfo{}
+	n := xd.countPath(mwgRegionList)
+	if n == 0 {
+		return ret
+	}
+	ret.Width = uint(xd.getPathFloat(mwgAppliedToDimW))
+	ret.Height = uint(xd.getPathFloat(mwgAppliedToDimH))
+	for i := 0; i < n; i++ {
+		item := xmpItemPath(mwgRegionList, i)
+		r := Region{
+			Name:        xd.getPathString(item + "/mwg-rs:Name"),
+			Description: xd.getPathString(item + "/mwg-rs:Description"),
+			Type:        RegionType(xd.getPathString(item + "/mwg-rs:Type")),
+			Area: RegionArea{
+				X: xd.getPathFloat(item + "/mwg-rs:Area/stArea:x"),
+				Y: xd.getPathFloat(item + "/mwg-rs:Area/stArea:y"),
+				W: xd.getPathFloat(item + "/mwg-rs:Area/stArea:w"),
+				H: xd.getPathFloat(item + "/mwg-rs:Area/stArea:h"),
+			},
+		}
+		ret.Regions = append(ret.Regions, r)
+	}
+	return ret
+}
+
+// GetIptcRegions returns the rectangular regions stored in Iptc4xmpExt:ImageRegion. Only
+// relative (rbUnit = relative) rectangles are supported
+func (xd XmpData) GetIptcRegions() RegionInfo {
+	ret := RegionInfo{}
+	n := xd.countPath(iptcImageRegion)
+	for i := 0; i < n; i++ {
+		item := xmpItemPath(iptcImageRegion, i)
+		bounds := item + "/" + iptcRegionBounds
+		if xd.getPathString(bounds+"/Iptc4xmpExt:rbShape") != "rectangle" ||
+			xd.getPathString(bounds+"/Iptc4xmpExt:rbUnit") != "relative" {
+			continue
+		}
+		x := xd.getPathFloat(bounds + "/Iptc4xmpExt:rbX")
+		y := xd.getPathFloat(bounds + "/Iptc4xmpExt:rbY")
+		w := xd.getPathFloat(bounds + "/Iptc4xmpExt:rbW")
+		h := xd.getPathFloat(bounds + "/Iptc4xmpExt:rbH")
+		r := Region{
+			Name: xd.getPathString(xmpLangPath(item+"/Iptc4xmpExt:Name", "")),
+			Area: RegionArea{X: x + w/2, Y: y + h/2, W: w, H: h},
+		}
+		ctype := xd.getPathString(xmpItemPath(xmpItemPath(item+"/Iptc4xmpExt:rCtype", 0)+"/Iptc4xmpExt:Identifier", 0))
+		role := xd.getPathString(xmpItemPath(xmpItemPath(item+"/Iptc4xmpExt:rRole", 0)+"/Iptc4xmpExt:Identifier", 0))
+		for t, uri := range iptcRegionTypes {
+			if uri == ctype || uri == role {
+				r.Type = t
+			}
+		}
+		ret.Regions = append(ret.Regions, r)
+	}
+	return ret
+}
+
+// SetRegions replaces any existing regions with ri. The regions are written both as
+// MWG regions and IPTC image regions
+func (xe *XmpEditor) SetRegions(ri RegionInfo) error {
+	if err := xe.SetMwgRegions(ri); err != nil {
+		return err
+	}
+	return xe.SetIptcRegions(ri)
+}
+
+// SetMwgRegions replaces mwg-rs:Regions with ri
+func (xe *XmpEditor) SetMwgRegions(ri RegionInfo) error {
+	if xe.hasPath(mwgRegions) {
+		if err := xe.deletePath(mwgRegions); err != nil {
+			return err
+		}
+	}
+	if ri.IsEmpty() {
+		return nil
+	}
+	if ri.Width > 0 && ri.Height > 0 {
+		if err := xe.setPath(mwgAppliedToDimW, strconv.Itoa(int(ri.Width))); err != nil {
+			return err
+		}
+		if err := xe.setPath(mwgAppliedToDimH, strconv.Itoa(int(ri.Height))); err != nil {
+			return err
+		}
+		if err := xe.setPath(mwgAppliedToUnit, "pixel"); err != nil {
+			return err
+		}
+	}
+	for i, r := range ri.Regions {
+		item := xmpItemPath(mwgRegionList, i)
+		values := [][2]string{
+			{"/mwg-rs:Area/stArea:x", formatRegionFloat(r.Area.X)},
+			{"/mwg-rs:Area/stArea:y", formatRegionFloat(r.Area.Y)},
+			{"/mwg-rs:Area/stArea:w", formatRegionFloat(r.Area.W)},
+			{"/mwg-rs:Area/stArea:h", formatRegionFloat(r.Area.H)},
+			{"/mwg-rs:Area/stArea:unit", "normalized"},
+			{"/mwg-rs:Name", r.Name},
+			{"/mwg-rs:Type", string(r.Type)},
+			{"/mwg-rs:Description", r.Description},
+		}
+		for _, v := range values {
+			if v[1] == "" {
+				continue
+			}
+			if err := xe.setPath(item+v[0], v[1]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetIptcRegions replaces Iptc4xmpExt:ImageRegion with ri
+func (xe *XmpEditor) SetIptcRegions(ri RegionInfo) error {
+	if xe.hasPath(iptcImageRegion) {
+		if err := xe.deletePath(iptcImageRegion); err != nil {
+			return err
+		}
+	}
+	for i, r := range ri.Regions {
+		item := xmpItemPath(iptcImageRegion, i)
+		bounds := item + "/" + iptcRegionBounds
+		values := [][2]string{
+			{bounds + "/Iptc4xmpExt:rbShape", "rectangle"},
+			{bounds + "/Iptc4xmpExt:rbUnit", "relative"},
+			{bounds + "/Iptc4xmpExt:rbX", formatRegionFloat(r.Area.X - r.Area.W/2)},
+			{bounds + "/Iptc4xmpExt:rbY", formatRegionFloat(r.Area.Y - r.Area.H/2)},
+			{bounds + "/Iptc4xmpExt:rbW", formatRegionFloat(r.Area.W)},
+			{bounds + "/Iptc4xmpExt:rbH", formatRegionFloat(r.Area.H)},
+			{xmpLangPath(item+"/Iptc4xmpExt:Name", ""), r.Name},
+		}
+		if uri, found := iptcRegionTypes[r.Type]; found {
+			field := "/Iptc4xmpExt:rCtype"
+			if r.Type == RegionFocus {
+				field = "/Iptc4xmpExt:rRole"
+			}
+			values = append(values, [2]string{xmpItemPath(xmpItemPath(item+field, 0)+"/Iptc4xmpExt:Identifier", 0), uri})
+		}
+		for _, v := range values {
+			if v[1] == "" {
+				continue
+			}
+			if err := xe.setPath(v[0], v[1]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetRegions sets the image regions in Xmp
+func (je *JpegEditor) SetRegions(ri RegionInfo) error {
+	return je.xe.SetRegions(ri)
+}
@@ -0,0 +1,147 @@
+package metadata
+
+import (
+	"image"
+	"math"
+	"testing"
+	"trimmer.io/go-xmp/xmp"
+)
+
+func cmpRegionArea(a, b RegionArea) bool {
+	const eps = 1e-6
+	return math.Abs(a.X-b.X) < eps && math.Abs(a.Y-b.Y) < eps && math.Abs(a.W-b.W) < eps && math.Abs(a.H-b.H) < eps
+}
+
+func TestRegionArea_Rect(t *testing.T) {
+	ra := RegionArea{X: 0.5, Y: 0.5, W: 0.2, H: 0.4}
+	exp := image.Rect(40, 30, 60, 70)
+	if act := ra.Rect(100, 100); act != exp {
+		t.Errorf("Expected %v got %v", exp, act)
+	}
+	if act := ra.Center(100, 200); act != image.Pt(50, 100) {
+		t.Errorf("Expected %v got %v", image.Pt(50, 100), act)
+	}
+}
+
+func TestRegionInfo_Find(t *testing.T) {
+	ri := RegionInfo{Regions: []Region{
+		{Name: "Anna", Type: RegionFace},
+		{Name: "", Type: RegionFocus},
+	}}
+	if r, found := ri.Find("Anna"); !found || r.Name != "Anna" {
+		t.Errorf("Expected to find region Anna")
+	}
+	if r, found := ri.Find("focus"); !found || r.Type != RegionFocus {
+		t.Errorf("Expected to find focus region")
+	}
+	if _, found := ri.Find("Bertil"); found {
+		t.Errorf("Did not expect to find region Bertil")
+	}
+}
+
+func TestRegionInfo_Crop(t *testing.T) {
+	ri := RegionInfo{Width: 200, Height: 100, Regions: []Region{
+		{Name: "inside", Area: RegionArea{X: 0.25, Y: 0.5, W: 0.1, H: 0.2}},
+		{Name: "clipped", Area: RegionArea{X: 0.5, Y: 0.5, W: 0.2, H: 0.2}},
+		{Name: "outside", Area: RegionArea{X: 0.9, Y: 0.5, W: 0.1, H: 0.1}},
+	}}
+	//crop the left half of the image
+	act := ri.Crop(200, 100, image.Rect(0, 0, 100, 100))
+	if act.Width != 100 || act.Height != 100 {
+		t.Errorf("Expected dimensions 100x100 got %vx%v", act.Width, act.Height)
+	}
+	if len(act.Regions) != 2 {
+		t.Fatalf("Expected 2 regions got %v", len(act.Regions))
+	}
+	if exp := (RegionArea{X: 0.5, Y: 0.5, W: 0.2, H: 0.2}); !cmpRegionArea(exp, act.Regions[0].Area) {
+		t.Errorf("Expected %v got %v", exp, act.Regions[0].Area)
+	}
+	if exp := (RegionArea{X: 0.9, Y: 0.5, W: 0.2, H: 0.2}); !cmpRegionArea(exp, act.Regions[1].Area) {
+		t.Errorf("Expected %v got %v", exp, act.Regions[1].Area)
+	}
+}
+
+func TestRegionInfo_Rotate(t *testing.T) {
+	ri := RegionInfo{Width: 200, Height: 100, Regions: []Region{
+		{Name: "face", Area: RegionArea{X: 0.25, Y: 0.2, W: 0.1, H: 0.2}},
+	}}
+	for _, test := range []struct {
+		angle int
+		exp   RegionArea
+	}{
+		{0, RegionArea{X: 0.25, Y: 0.2, W: 0.1, H: 0.2}},
+		{90, RegionArea{X: 0.8, Y: 0.25, W: 0.2, H: 0.1}},
+		{-270, RegionArea{X: 0.8, Y: 0.25, W: 0.2, H: 0.1}},
+		{180, RegionArea{X: 0.75, Y: 0.8, W: 0.1, H: 0.2}},
+		{270, RegionArea{X: 0.2, Y: 0.75, W: 0.2, H: 0.1}},
+	} {
+		act := ri.Rotate(200, 100, test.angle)
+		if len(act.Regions) != 1 || !cmpRegionArea(test.exp, act.Regions[0].Area) {
+			t.Errorf("Expected %v got %v for angle %v", test.exp, act.Regions, test.angle)
+		}
+		if test.angle%180 != 0 && (act.Width != 100 || act.Height != 200) {
+			t.Errorf("Expected dimensions 100x200 got %vx%v", act.Width, act.Height)
+		}
+	}
+	//the general rotation agrees with the exact one
+	if act := rotateRegionArea(ri.Regions[0].Area, 200, 100, math.Pi/2); !cmpRegionArea(RegionArea{X: 0.8, Y: 0.25, W: 0.2, H: 0.1}, act) {
+		t.Errorf("Expected %v got %v", RegionArea{X: 0.8, Y: 0.25, W: 0.2, H: 0.1}, act)
+	}
+	act := ri.Rotate(200, 100, 45)
+	if act.Width != 212 || act.Height != 212 || len(act.Regions) != 1 {
+		t.Errorf("Expected dimensions 212x212 got %vx%v", act.Width, act.Height)
+	}
+}
+
+func TestXmpEditor_SetRegions(t *testing.T) {
+	exp := RegionInfo{Width: 400, Height: 300, Regions: []Region{
+		{Name: "Anna", Type: RegionFace, Area: RegionArea{X: 0.3, Y: 0.4, W: 0.1, H: 0.2}},
+		{Name: "Focus", Type: RegionFocus, Area: RegionArea{X: 0.6, Y: 0.5, W: 0.05, H: 0.05}},
+	}}
+	xe, _ := NewXmpEditorFromDocument(xmp.NewDocument())
+	if err := xe.SetRegions(exp); err != nil {
+		t.Fatalf("Could not set regions: %v", err)
+	}
+	if !xe.IsDirty() {
+		t.Errorf("Expected dirty xmp editor")
+	}
+	b, err := xe.Bytes(false)
+	if err != nil {
+		t.Fatalf("Could not write xmp: %v", err)
+	}
+	xd, err := NewXmpDataFromBytes(b)
+	if err != nil {
+		t.Fatalf("Could not read xmp: %v", err)
+	}
+	for _, act := range []RegionInfo{xd.GetMwgRegions(), xd.GetIptcRegions()} {
+		if len(act.Regions) != len(exp.Regions) {
+			t.Fatalf("Expected %v regions got %v", len(exp.Regions), len(act.Regions))
+		}
+		for i, r := range exp.Regions {
+			if act.Regions[i].Name != r.Name || act.Regions[i].Type != r.Type {
+				t.Errorf("Expected region %v got %v", r, act.Regions[i])
+			}
+			if !cmpRegionArea(r.Area, act.Regions[i].Area) {
+				t.Errorf("Expected area %v got %v", r.Area, act.Regions[i].Area)
+			}
+		}
+	}
+	if act := xd.GetMwgRegions(); act.Width != exp.Width || act.Height != exp.Height {
+		t.Errorf("Expected dimensions %vx%v got %vx%v", exp.Width, exp.Height, act.Width, act.Height)
+	}
+
+	//removing regions that do not exist is not an edit
+	empty, _ := NewXmpEditorFromDocument(xmp.NewDocument())
+	if err = empty.SetRegions(RegionInfo{}); err != nil || empty.IsDirty() {
+		t.Errorf("Expected clean xmp editor got %v", err)
+	}
+	if xe, err = NewXmpEditorFromBytes(b); err != nil {
+		t.Fatalf("Could not read xmp: %v", err)
+	}
+	if err = xe.SetRegions(RegionInfo{}); err != nil || !xe.IsDirty() {
+		t.Errorf("Expected dirty xmp editor got %v", err)
+	}
+	if !xe.GetMwgRegions().IsEmpty() || !xe.GetIptcRegions().IsEmpty() {
+		t.Errorf("Expected regions to be removed")
+	}
+}
@@ -0,0 +1,503 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	jpegstructure "github.com/dsoprea/go-jpeg-image-structure/v2"
+	"github.com/msvens/mimage/makernote"
+	"github.com/msvens/mimage/photoshop"
+	"io"
+	"os"
+	"sort"
+	"trimmer.io/go-xmp/xmp"
+)
+
+// SourceJpeg is used for ParseErrors in the jpeg structure
+const SourceJpeg MetadataSource = "jpeg"
+
+// ParseError is a problem that was skipped when reading metadata in lenient mode or that was fixed by Repair
+type ParseError struct {
+	Source   MetadataSource
+	Location string
+	Err      error
+}
+
+func (e ParseError) Error() string {
+	if e.Location == "" {
+		return fmt.Sprintf("%s: %v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("%s %s: %v", e.Source, e.Location, e.Err)
+}
+
+func newParseError(source MetadataSource, location string, format string, args ...interface{}) ParseError {
+	return ParseError{Source: source, Location: location, Err: fmt.Errorf(format, args...)}
+}
+
+// rawSegment is a jpeg segment before the image data. data is nil for markers without a length
+type rawSegment struct {
+	marker byte
+	data   []byte
+}
+
+// splitJpeg splits data into the segments up to and including the start of scan and the image data that
+// follows. Parsing stops at the first broken segment
+func splitJpeg(data []byte) ([]rawSegment, []byte, []ParseError) {
+	if len(data) < 2 || data[0] != 0xff || data[1] != markerSOI {
+		return nil, nil, []ParseError{newParseError(SourceJpeg, "", "missing SOI marker")}
+	}
+	segments := []rawSegment{}
+	pos := 2
+	for {
+		if pos >= len(data) || data[pos] != 0xff {
+			return segments, nil, []ParseError{newParseError(SourceJpeg, fmt.Sprintf("offset %d", pos), "expected a marker")}
+		}
+		for pos < len(data) && data[pos] == 0xff {
+			pos++
+		}
+		if pos >= len(data) {
+			return segments, nil, []ParseError{newParseError(SourceJpeg, fmt.Sprintf("offset %d", pos), "unexpected end of data")}
+		}
+		marker := data[pos]
+		pos++
+		if marker == markerEOI {
+			return segments, nil, []ParseError{newParseError(SourceJpeg, fmt.Sprintf("offset %d", pos-2), "EOI before start of scan")}
+		}
+		if marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) {
+			segments = append(segments, rawSegment{marker: marker})
+			continue
+		}
+		length := 0
+		if pos+2 <= len(data) {
+			length = int(data[pos])<<8 | int(data[pos+1])
+		}
+		if length < 2 || pos+length > len(data) {
+			return segments, nil, []ParseError{newParseError(SourceJpeg, fmt.Sprintf("segment 0x%02X at offset %d", marker, pos-2), "truncated segment")}
+		}
+		segments = append(segments, rawSegment{marker: marker, data: data[pos+2 : pos+length]})
+		pos += length
+		if marker == markerSOS {
+			return segments, data[pos:], nil
+		}
+	}
+}
+
+// joinJpeg is the inverse of splitJpeg. An EOI marker is added if the image data is truncated
+func joinJpeg(segments []rawSegment, scan []byte) []byte {
+	buf := bytes.Buffer{}
+	buf.Write([]byte{0xff, markerSOI})
+	for _, s := range segments {
+		buf.Write([]byte{0xff, s.marker})
+		if s.data != nil || (s.marker != 0x01 && (s.marker < 0xd0 || s.marker > 0xd7)) {
+			buf.Write([]byte{byte((len(s.data) + 2) >> 8), byte(len(s.data) + 2)})
+			buf.Write(s.data)
+		}
+	}
+	buf.Write(scan)
+	if !bytes.Contains(scan, []byte{0xff, markerEOI}) {
+		buf.Write([]byte{0xff, markerEOI})
+	}
+	return buf.Bytes()
+}
+
+// salvageJpeg drops everything after the first broken segment and terminates truncated image data. If a segment
+// before the image data is broken the result only holds the readable segments and can be used to read metadata
+// but not as an image
+func salvageJpeg(data []byte) ([]byte, []ParseError) {
+	segments, scan, errs := splitJpeg(data)
+	if segments == nil && len(errs) > 0 {
+		return data, errs
+	}
+	if scan == nil {
+		errs = append(errs, newParseError(SourceJpeg, "", "image data is missing"))
+	} else if !bytes.Contains(scan, []byte{0xff, markerEOI}) {
+		errs = append(errs, newParseError(SourceJpeg, "", "image data is truncated"))
+	}
+	return joinJpeg(segments, scan), errs
+}
+
+// tiffIfd is an ifd read by readTiff
+type tiffIfd struct {
+	index   ExifIndex
+	entries []exifEntry
+	subs    map[ExifTag]*tiffIfd
+	blocks  map[ExifTag][][]byte
+	next    *tiffIfd
+}
+
+// tiffReader reads the ifds of an exif tiff block skipping anything that can not be read
+type tiffReader struct {
+	raw     []byte
+	bo      binary.ByteOrder
+	visited map[uint32]bool
+	errs    []ParseError
+	loop    bool
+	//makerNoteOffset is the offset of the maker note in raw (-1 if there is no maker note)
+	makerNoteOffset int
+}
+
+func (r *tiffReader) fail(index ExifIndex, tag ExifTag, format string, args ...interface{}) {
+	location := IFDPaths[index]
+	if tag != 0 {
+		location = fmt.Sprintf("%s tag 0x%04x", location, tag)
+	}
+	r.errs = append(r.errs, newParseError(SourceExif, location, format, args...))
+}
+
+func (r *tiffReader) read(index ExifIndex, offset uint32) *tiffIfd {
+	if r.visited[offset] {
+		r.loop = true
+		r.fail(index, 0, "ifd offset loop")
+		return nil
+	}
+	r.visited[offset] = true
+	if uint64(offset)+2 > uint64(len(r.raw)) {
+		r.fail(index, 0, "ifd offset %d outside the exif data", offset)
+		return nil
+	}
+	n := uint64(r.bo.Uint16(r.raw[offset:]))
+	if uint64(offset)+2+n*12+4 > uint64(len(r.raw)) {
+		r.fail(index, 0, "ifd with %d entries exceeds the exif data", n)
+		return nil
+	}
+	ret := &tiffIfd{index: index, subs: map[ExifTag]*tiffIfd{}, blocks: map[ExifTag][][]byte{}}
+	for i := uint64(0); i < n; i++ {
+		b := r.raw[uint64(offset)+2+i*12:]
+		e := exifEntry{tag: ExifTag(r.bo.Uint16(b)), typ: r.bo.Uint16(b[2:]), count: r.bo.Uint32(b[4:])}
+		size, ok := tiffTypeSize[e.typ]
+		if !ok {
+			r.fail(index, e.tag, "unknown type %d", e.typ)
+			continue
+		}
+		length := uint64(size) * uint64(e.count)
+		if length <= 4 {
+			e.value = b[8 : 8+length]
+		} else if valueOffset := uint64(r.bo.Uint32(b[8:])); valueOffset+length > uint64(len(r.raw)) {
+			r.fail(index, e.tag, "value offset outside the exif data")
+			continue
+		} else {
+			e.value = r.raw[valueOffset : valueOffset+length]
+			if index == ExifIFD && e.tag == ExifIFD_MakerNote {
+				r.makerNoteOffset = int(valueOffset)
+			}
+		}
+		if sub, ok := exifSubIfds[e.tag]; ok {
+			if len(e.value) != 4 {
+				r.fail(index, e.tag, "invalid ifd pointer")
+				continue
+			}
+			child := r.read(sub, r.bo.Uint32(e.value))
+			if child == nil {
+				continue
+			}
+			ret.subs[e.tag] = child
+		}
+		ret.entries = append(ret.entries, e)
+	}
+	r.readBlocks(ret)
+	if next := r.bo.Uint32(r.raw[uint64(offset)+2+n*12:]); next != 0 && index == RootIFD {
+		ret.next = r.read(ThumbnailIFD, next)
+	}
+	return ret
+}
+
+// readBlocks reads the data referenced by offset tags (e.g. the thumbnail). Offset tags that can not be
+// moved or point outside the exif data are dropped
+func (r *tiffReader) readBlocks(ifd *tiffIfd) {
+	descIndex := ifd.index
+	if ifd.index == ThumbnailIFD {
+		descIndex = RootIFD
+	}
+	byTag := map[ExifTag]exifEntry{}
+	for _, e := range ifd.entries {
+		byTag[e.tag] = e
+	}
+	drop := map[ExifTag]bool{}
+	for _, e := range ifd.entries {
+		desc, found := ExifTagDescriptions[ExifIndexTag{descIndex, e.tag}]
+		if _, sub := exifSubIfds[e.tag]; !found || !desc.Offset || sub {
+			continue
+		}
+		pair := desc.OffsetPair
+		if e.tag == IFD_ThumbnailOffset {
+			pair = IFD_ThumbnailLength
+		}
+		lengths, found := byTag[pair]
+		if pair == 0 || !found {
+			r.fail(ifd.index, e.tag, "offset without a length can not be moved")
+			drop[e.tag] = true
+			continue
+		}
+		offsets, sizes := e.uints(r.bo), lengths.uints(r.bo)
+		if len(offsets) == 0 || len(offsets) != len(sizes) {
+			r.fail(ifd.index, e.tag, "offsets and lengths do not match")
+			drop[e.tag], drop[pair] = true, true
+			continue
+		}
+		blocks := [][]byte{}
+		for i := range offsets {
+			if uint64(offsets[i])+uint64(sizes[i]) > uint64(len(r.raw)) {
+				r.fail(ifd.index, e.tag, "offset outside the exif data")
+				drop[e.tag], drop[pair] = true, true
+				break
+			}
+			blocks = append(blocks, r.raw[offsets[i]:offsets[i]+sizes[i]])
+		}
+		if !drop[e.tag] {
+			ifd.blocks[e.tag] = blocks
+		}
+	}
+	entries := ifd.entries[:0]
+	for _, e := range ifd.entries {
+		if !drop[e.tag] {
+			entries = append(entries, e)
+		}
+	}
+	ifd.entries = entries
+}
+
+// tiffWriter serializes tiffIfds
+type tiffWriter struct {
+	bo  binary.ByteOrder
+	buf []byte
+}
+
+func (w *tiffWriter) align() {
+	if len(w.buf)%2 == 1 {
+		w.buf = append(w.buf, 0)
+	}
+}
+
+func (w *tiffWriter) write(ifd *tiffIfd) uint32 {
+	w.align()
+	offset := len(w.buf)
+	entries := append([]exifEntry{}, ifd.entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+	w.buf = append(w.buf, make([]byte, 2+12*len(entries)+4)...)
+	w.bo.PutUint16(w.buf[offset:], uint16(len(entries)))
+	patches := map[ExifTag]int{}
+	for i, e := range entries {
+		pos := offset + 2 + 12*i
+		if blocks, ok := ifd.blocks[e.tag]; ok {
+			e = exifEntry{tag: e.tag, typ: tiffLong, count: uint32(len(blocks)), value: make([]byte, 4*len(blocks))}
+		}
+		w.bo.PutUint16(w.buf[pos:], uint16(e.tag))
+		w.bo.PutUint16(w.buf[pos+2:], e.typ)
+		w.bo.PutUint32(w.buf[pos+4:], e.count)
+		valuePos := pos + 8
+		if len(e.value) > 4 {
+			w.align()
+			valuePos = len(w.buf)
+			w.bo.PutUint32(w.buf[pos+8:], uint32(valuePos))
+			w.buf = append(w.buf, e.value...)
+		} else {
+			copy(w.buf[pos+8:pos+12], e.value)
+		}
+		patches[e.tag] = valuePos
+	}
+	//entries are sorted by tag so sub ifds and blocks are always written in the same order
+	for _, e := range entries {
+		if child, ok := ifd.subs[e.tag]; ok {
+			childOffset := w.write(child)
+			w.bo.PutUint32(w.buf[patches[e.tag]:], childOffset)
+		}
+	}
+	for _, e := range entries {
+		for i, b := range ifd.blocks[e.tag] {
+			w.align()
+			w.bo.PutUint32(w.buf[patches[e.tag]+4*i:], uint32(len(w.buf)))
+			w.buf = append(w.buf, b...)
+		}
+	}
+	if ifd.next != nil {
+		next := w.write(ifd.next)
+		w.bo.PutUint32(w.buf[offset+2+12*len(entries):], next)
+	}
+	return uint32(offset)
+}
+
+// writeTiff serializes root with the same tiff header as the data it was read from
+func (r *tiffReader) writeTiff(root *tiffIfd) []byte {
+	w := tiffWriter{bo: r.bo, buf: append([]byte{}, r.raw[:4]...)}
+	w.buf = append(w.buf, 0, 0, 0, 8)
+	r.bo.PutUint32(w.buf[4:], 8)
+	w.write(root)
+	return w.buf
+}
+
+// drop removes tag from the ifd with index
+func (ifd *tiffIfd) drop(index ExifIndex, tag ExifTag) {
+	if ifd.index == index {
+		entries := ifd.entries[:0]
+		for _, e := range ifd.entries {
+			if e.tag != tag {
+				entries = append(entries, e)
+			}
+		}
+		ifd.entries = entries
+	}
+	for _, child := range ifd.subs {
+		child.drop(index, tag)
+	}
+}
+
+// readTiff reads all ifds in raw. The returned ifd is nil if the root ifd could not be read
+func readTiff(raw []byte) (*tiffReader, *tiffIfd) {
+	r := &tiffReader{raw: raw, visited: map[uint32]bool{}, makerNoteOffset: -1}
+	if len(raw) < 8 {
+		r.errs = append(r.errs, newParseError(SourceExif, "", "exif data is too short"))
+		return r, nil
+	}
+	switch string(raw[:2]) {
+	case "II":
+		r.bo = binary.LittleEndian
+	case "MM":
+		r.bo = binary.BigEndian
+	default:
+		r.errs = append(r.errs, newParseError(SourceExif, "", "unknown byte order %q", raw[:2]))
+		return r, nil
+	}
+	return r, r.read(RootIFD, r.bo.Uint32(raw[4:]))
+}
+
+// hasIfdLoop checks if any ifd offset in raw points back to an already visited ifd (go-exif does not
+// detect this and never returns)
+func hasIfdLoop(raw []byte) bool {
+	r, _ := readTiff(raw)
+	return r.loop
+}
+
+// repairExif rebuilds an exif tiff block from the ifds and tags that can be read. Offset loops and
+// entries pointing outside the block are dropped as are maker notes that can not be relocated. Returns nil
+// if the root ifd could not be read
+func repairExif(raw []byte) ([]byte, []ParseError) {
+	r, root := readTiff(raw)
+	if root == nil {
+		return nil, r.errs
+	}
+	buf := r.writeTiff(root)
+	if r.makerNoteOffset >= 0 {
+		if _, err := makernote.Relocate(buf, r.makerNoteOffset); err != nil {
+			r.fail(ExifIFD, ExifIFD_MakerNote, "maker note dropped since it could not be relocated: %v", err)
+			root.drop(ExifIFD, ExifIFD_MakerNote)
+			buf = r.writeTiff(root)
+		}
+	}
+	return buf, r.errs
+}
+
+// newExifDataLenient creates ExifData from the ifds and tags in rawExif that can be read
+func newExifDataLenient(rawExif []byte) (*ExifData, []ParseError) {
+	repaired, errs := repairExif(rawExif)
+	if repaired == nil {
+		return &ExifData{}, errs
+	}
+	ed, err := newExifData(repaired)
+	if err != nil {
+		return &ExifData{}, append(errs, ParseError{Source: SourceExif, Err: err})
+	}
+	return ed, errs
+}
+
+// Repair rewrites a jpeg image keeping only the metadata that can be read. Broken segments, duplicate exif and
+// xmp segments and unreadable exif tags are removed and exif ifd offset loops are fixed. Returns the repaired
+// image and the problems that were fixed. The image is returned unchanged if there was nothing to repair. Returns
+// ErrParseImage if a segment before the image data is broken
+func Repair(data []byte) ([]byte, []ParseError, error) {
+	segments, scan, errs := splitJpeg(data)
+	if scan == nil { //a broken segment before the image data can not be repaired without losing the image
+		return nil, errs, ErrParseImage
+	}
+	if !bytes.Contains(scan, []byte{0xff, markerEOI}) {
+		errs = append(errs, newParseError(SourceJpeg, "", "image data is truncated"))
+	}
+	repaired := make([]rawSegment, 0, len(segments))
+	var hasExif, hasXmp bool
+	for i := 0; i < len(segments); i++ {
+		s := segments[i]
+		keep := s
+		switch {
+		case s.marker == jpegstructure.MARKER_APP1 && bytes.HasPrefix(s.data, exifHeader):
+			if hasExif {
+				errs = append(errs, newParseError(SourceExif, "", "duplicate exif segment removed"))
+				continue
+			}
+			hasExif = true
+			rawExif := s.data[len(exifHeader):]
+			tiff, exifErrs := repairExif(rawExif)
+			_, err := newExifData(rawExif)
+			if err == nil && len(exifErrs) == 0 {
+				break
+			} else if err != nil {
+				errs = append(errs, ParseError{Source: SourceExif, Err: err})
+			}
+			errs = append(errs, exifErrs...)
+			if tiff == nil {
+				errs = append(errs, newParseError(SourceExif, "", "unreadable exif segment removed"))
+				continue
+			}
+			keep.data = append(append([]byte{}, exifHeader...), tiff...)
+		case s.marker == jpegstructure.MARKER_APP1 && bytes.HasPrefix(s.data, xmpPrefix):
+			if hasXmp {
+				errs = append(errs, newParseError(SourceXmp, "", "duplicate xmp segment removed"))
+				continue
+			}
+			if err := xmp.Unmarshal(s.data[len(xmpPrefix):], &xmp.Document{}); err != nil {
+				errs = append(errs, newParseError(SourceXmp, "", "unreadable xmp segment removed: %v", err))
+				continue
+			}
+			hasXmp = true
+		case s.marker == jpegstructure.MARKER_APP13:
+			//large photoshop blocks are split over consecutive segments
+			start, blocks := i, [][]byte{s.data}
+			for i+1 < len(segments) && segments[i+1].marker == jpegstructure.MARKER_APP13 &&
+				photoshop.IsPhotoshopBlock(segments[i+1].data) {
+				i++
+				blocks = append(blocks, segments[i].data)
+			}
+			irb, err := photoshop.JoinSegments(blocks...)
+			var res photoshop.Resources
+			if err == nil {
+				res, err = photoshop.UnmarshalResources(irb, false)
+			}
+			if err != nil {
+				errs = append(errs, newParseError(SourceIptc, "", "unreadable photoshop segment removed: %v", err))
+				continue
+			}
+			if r, ok := res.Get(photoshop.IptcId); ok {
+				if _, err := DecodeIptc(bytes.NewReader(r.Data)); err != nil {
+					errs = append(errs, newParseError(SourceIptc, "", "unreadable iptc segment removed: %v", err))
+					continue
+				}
+			}
+			repaired = append(repaired, segments[start:i]...)
+			keep = segments[i]
+		}
+		repaired = append(repaired, keep)
+	}
+	if len(errs) == 0 {
+		return data, nil, nil
+	}
+	return joinJpeg(repaired, scan), errs, nil
+}
+
+// RepairFile repairs filename (see Repair) and writes the result to dest (which can be the same as filename).
+// Nothing is written if there was nothing to repair or if the image could not be repaired
+func RepairFile(filename string, dest string) ([]ParseError, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	repaired, errs, err := Repair(data)
+	if err != nil || len(errs) == 0 {
+		return errs, err
+	}
+	perm := os.FileMode(0644)
+	if fi, err := os.Stat(dest); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	return errs, writeFileAtomic(dest, perm, func(w io.Writer) error {
+		_, err := w.Write(repaired)
+		return err
+	})
+}
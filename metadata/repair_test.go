@@ -0,0 +1,203 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/msvens/mimage/makernote"
+	"os"
+	"testing"
+)
+
+// brokenExif returns the leica image with the exif modified by breakFn
+func brokenExif(breakFn func(bo binary.ByteOrder, tiff []byte, root uint32), t *testing.T) []byte {
+	img := append([]byte{}, getAssetBytes(LeicaImg, t)...)
+	if !bytes.HasPrefix(img[6:], exifHeader) {
+		t.Fatalf("Expected exif as the first segment")
+	}
+	tiff := img[6+len(exifHeader):]
+	var bo binary.ByteOrder = binary.BigEndian
+	if tiff[0] == 'I' {
+		bo = binary.LittleEndian
+	}
+	breakFn(bo, tiff, bo.Uint32(tiff[4:]))
+	return img
+}
+
+// ifdLoop points the next ifd of the root ifd back to itself
+func ifdLoop(bo binary.ByteOrder, tiff []byte, root uint32) {
+	n := uint32(bo.Uint16(tiff[root:]))
+	bo.PutUint32(tiff[root+2+n*12:], root)
+}
+
+// badExifPointer points the exif sub ifd outside the exif data
+func badExifPointer(bo binary.ByteOrder, tiff []byte, root uint32) {
+	n := uint32(bo.Uint16(tiff[root:]))
+	for i := uint32(0); i < n; i++ {
+		if e := tiff[root+2+i*12:]; ExifTag(bo.Uint16(e)) == IFD_ExifOffset {
+			bo.PutUint32(e[8:], 0xffffff)
+		}
+	}
+}
+
+func TestRepairExif(t *testing.T) {
+	md := getMetaData(LeicaImg, t)
+	repaired, errs := repairExif(md.Exif().raw)
+	if len(errs) != 0 {
+		t.Errorf("Expected no errors got %v", errs)
+	}
+	ed, err := newExifData(repaired)
+	if err != nil {
+		t.Fatalf("Could not parse repaired exif: %v", err)
+	}
+	repairedMd := MetaData{exifData: ed, iptcData: &IptcData{}}
+	if exp, act := md.Summary().LensModel, repairedMd.Summary().LensModel; exp != act {
+		t.Errorf("Expected %v got %v", exp, act)
+	}
+	if exp, act := md.Summary().ExposureTime, repairedMd.Summary().ExposureTime; exp != act {
+		t.Errorf("Expected %v got %v", exp, act)
+	}
+}
+
+func TestNewMetaDataLenient(t *testing.T) {
+	for _, breakFn := range []func(binary.ByteOrder, []byte, uint32){ifdLoop, badExifPointer} {
+		img := brokenExif(breakFn, t)
+		if _, err := NewMetaData(img); err == nil {
+			t.Errorf("Expected error got nil")
+		}
+		md, err := newMetaData(img, ReadOptions{Lenient: true})
+		if err != nil {
+			t.Fatalf("Expected lenient read got %v", err)
+		}
+		if len(md.ParseErrors()) == 0 {
+			t.Errorf("Expected parse errors")
+		}
+		if md.Summary().CameraModel != "LEICA Q2" {
+			t.Errorf("Expected %v got %v", "LEICA Q2", md.Summary().CameraModel)
+		}
+		if md.Summary().Title == "" {
+			t.Errorf("Expected iptc/xmp title")
+		}
+	}
+	md, err := newMetaData(brokenExif(ifdLoop, t), ReadOptions{Lenient: true})
+	if err != nil {
+		t.Fatalf("Expected lenient read got %v", err)
+	}
+	if md.Summary().LensModel != "SUMMILUX 1:1.7/28 ASPH." {
+		t.Errorf("Expected %v got %v", "SUMMILUX 1:1.7/28 ASPH.", md.Summary().LensModel)
+	}
+}
+
+func TestNewMetaDataLenient_Truncated(t *testing.T) {
+	img := getAssetBytes(LeicaImg, t)
+	md, err := newMetaData(img[:len(img)/2], ReadOptions{Lenient: true})
+	if err != nil {
+		t.Fatalf("Expected lenient read got %v", err)
+	}
+	if md.Summary().CameraModel != "LEICA Q2" || md.ImageWidth == 0 {
+		t.Errorf("Expected metadata from truncated image got %v %v", md.Summary().CameraModel, md.ImageWidth)
+	}
+	//segment truncated in the middle of the exif
+	if md, err = newMetaData(img[:100], ReadOptions{Lenient: true}); err != nil {
+		t.Fatalf("Expected lenient read got %v", err)
+	}
+	if !md.Exif().IsEmpty() || len(md.ParseErrors()) == 0 {
+		t.Errorf("Expected no exif and parse errors")
+	}
+}
+
+func TestRepair(t *testing.T) {
+	img := getAssetBytes(LeicaImg, t)
+	if out, errs, err := Repair(img); err != nil || len(errs) != 0 || !bytes.Equal(out, img) {
+		t.Errorf("Expected unchanged image got %v %v", errs, err)
+	}
+	for _, broken := range [][]byte{brokenExif(ifdLoop, t), brokenExif(badExifPointer, t), img[:len(img)/2]} {
+		out, errs, err := Repair(broken)
+		if err != nil {
+			t.Fatalf("Could not repair image: %v", err)
+		}
+		if len(errs) == 0 {
+			t.Errorf("Expected repaired errors")
+		}
+		md, err := NewMetaData(out)
+		if err != nil {
+			t.Fatalf("Could not read repaired image: %v", err)
+		}
+		if md.Summary().CameraModel != "LEICA Q2" {
+			t.Errorf("Expected %v got %v", "LEICA Q2", md.Summary().CameraModel)
+		}
+		if _, errs, _ = Repair(out); len(errs) != 0 {
+			t.Errorf("Expected repaired image to be valid got %v", errs)
+		}
+	}
+	//header truncated inside a segment
+	if out, errs, err := Repair(img[:100]); err != ErrParseImage || out != nil || len(errs) == 0 {
+		t.Errorf("Expected %v got %v (%v)", ErrParseImage, err, errs)
+	}
+}
+
+func TestRepairFile(t *testing.T) {
+	fname := copyAsset(LeicaImg, t)
+	if err := os.WriteFile(fname, brokenExif(ifdLoop, t), 0640); err != nil {
+		t.Fatalf("Could not write file: %v", err)
+	}
+	errs, err := RepairFile(fname, fname)
+	if err != nil || len(errs) == 0 {
+		t.Fatalf("Expected repaired errors got %v %v", errs, err)
+	}
+	if _, err = NewMetaDataFromFile(fname); err != nil {
+		t.Errorf("Could not read repaired file: %v", err)
+	}
+	if fi, err := os.Stat(fname); err != nil || fi.Mode().Perm() != 0640 {
+		t.Errorf("Expected mode to be preserved got %v", fi.Mode())
+	}
+	//a truncated header can not be repaired and the original is kept
+	truncated := getAssetBytes(LeicaImg, t)[:100]
+	if err := os.WriteFile(fname, truncated, 0640); err != nil {
+		t.Fatalf("Could not write file: %v", err)
+	}
+	if _, err = RepairFile(fname, fname); err != ErrParseImage {
+		t.Errorf("Expected %v got %v", ErrParseImage, err)
+	}
+	if b, err := os.ReadFile(fname); err != nil || !bytes.Equal(b, truncated) {
+		t.Errorf("Expected original file to be kept")
+	}
+}
+
+func TestRepairExif_Deterministic(t *testing.T) {
+	for _, fname := range []string{LeicaImg, NikonImg, GPSImg} {
+		raw := getMetaData(fname, t).Exif().raw
+		exp, _ := repairExif(raw)
+		for i := 0; i < 10; i++ {
+			if act, _ := repairExif(raw); !bytes.Equal(exp, act) {
+				t.Fatalf("%s: Expected repaired exif to be the same on every run", fname)
+			}
+		}
+	}
+}
+
+func TestRepairExif_MakerNote(t *testing.T) {
+	je := getJpegEditor(LeicaImg, t)
+	if err := je.Exif().SetIfdRootTag(IFD_Make, "Acme Camera Company"); err != nil {
+		t.Fatalf("Could not set make: %v", err)
+	}
+	if err := je.Exif().SetIfdExifTag(ExifIFD_MakerNote, bytes.Repeat([]byte{0xab}, 32)); err != nil {
+		t.Fatalf("Could not set maker note: %v", err)
+	}
+	raw := jpegEditorMD(je, t).Exif().raw
+	repaired, errs := repairExif(raw)
+	found := false
+	for _, e := range errs {
+		found = found || e.Location == fmt.Sprintf("%s tag 0x%04x", IFDPaths[ExifIFD], ExifIFD_MakerNote)
+	}
+	if !found {
+		t.Errorf("Expected maker note parse error got %v", errs)
+	}
+	ed, err := newExifData(repaired)
+	if err != nil {
+		t.Fatalf("Could not parse repaired exif: %v", err)
+	}
+	if _, err = ed.MakerNote(); err != makernote.ErrNoMakerNote {
+		t.Errorf("Expected %v got %v", makernote.ErrNoMakerNote, err)
+	}
+}
@@ -0,0 +1,207 @@
+package metadata
+
+import (
+	"strings"
+)
+
+const (
+	xmpDcCreator          = "dc:creator"
+	xmpDcRights           = "dc:rights"
+	xmpRightsUsageTerms   = "xmpRights:UsageTerms"
+	xmpRightsWebStatement = "xmpRights:WebStatement"
+	xmpRightsMarked       = "xmpRights:Marked"
+	xmpPsCredit           = "photoshop:Credit"
+	xmpPsSource           = "photoshop:Source"
+)
+
+// exifArtistSeparator is used to join multiple creators into the single Exif Artist value (as suggested by MWG)
+const exifArtistSeparator = "; "
+
+// Rights holds the creator, copyright and licensing information of an image
+type Rights struct {
+	Creator      []string `json:"creator,omitempty"`
+	Copyright    string   `json:"copyright,omitempty"`
+	UsageTerms   string   `json:"usageTerms,omitempty"`
+	WebStatement string   `json:"webStatement,omitempty"`
+	CreditLine   string   `json:"creditLine,omitempty"`
+	Source       string   `json:"source,omitempty"`
+}
+
+// IsEmpty returns true if no rights information is set
+func (r Rights) IsEmpty() bool {
+	return len(r.Creator) == 0 && r.Copyright == "" && r.UsageTerms == "" && r.WebStatement == "" &&
+		r.CreditLine == "" && r.Source == ""
+}
+
+func splitExifArtist(artist string) []string {
+	ret := []string{}
+	for _, a := range strings.Split(artist, ";") {
+		if a = strings.TrimSpace(a); a != "" {
+			ret = append(ret, a)
+		}
+	}
+	return ret
+}
+
+// GetRights returns IFD_Artist and IFD_Copyright. Only the photographer part of a copyright
+// notice is returned (exif allows for an additional editor copyright after a NUL)
+func (ed *ExifData) GetRights() Rights {
+	ret := Rights{}
+	if ed.IsEmpty() {
+		return ret
+	}
+	artist := ""
+	if err := ed.ScanIfdRoot(IFD_Artist, &artist); err == nil {
+		ret.Creator = splitExifArtist(artist)
+	}
+	if err := ed.ScanIfdRoot(IFD_Copyright, &ret.Copyright); err == nil {
+		if i := strings.IndexByte(ret.Copyright, 0); i >= 0 {
+			ret.Copyright = ret.Copyright[:i]
+		}
+		ret.Copyright = strings.TrimSpace(ret.Copyright)
+	}
+	return ret
+}
+
+// GetRights returns By-line, CopyrightNotice, Credit and Source
+func (ipd *IptcData) GetRights() Rights {
+	ret := Rights{}
+	_ = ipd.ScanApplication(IPTCApplication_Byline, &ret.Creator)
+	_ = ipd.ScanApplication(IPTCApplication_CopyrightNotice, &ret.Copyright)
+	_ = ipd.ScanApplication(IPTCApplication_Credit, &ret.CreditLine)
+	_ = ipd.ScanApplication(IPTCApplication_Source, &ret.Source)
+	return ret
+}
+
+// GetRights returns dc:creator, dc:rights, xmpRights:UsageTerms, xmpRights:WebStatement,
+// photoshop:Credit and photoshop:Source
+func (xd XmpData) GetRights() Rights {
+	ret := Rights{}
+	if xd.IsEmpty() {
+		return ret
+	}
+	if creators := xd.getPathStrings(xmpDcCreator); len(creators) > 0 {
+		ret.Creator = creators
+	}
+	ret.Copyright = xd.getPathString(xmpLangPath(xmpDcRights, ""))
+	ret.UsageTerms = xd.getPathString(xmpLangPath(xmpRightsUsageTerms, ""))
+	ret.WebStatement = xd.getPathString(xmpRightsWebStatement)
+	ret.CreditLine = xd.getPathString(xmpPsCredit)
+	ret.Source = xd.getPathString(xmpPsSource)
+	return ret
+}
+
+// SetRights sets dc:creator, dc:rights, xmpRights and the photoshop credit and source. Empty fields are left untouched.
+// xmpRights:Marked is set to True whenever a copyright is given
+func (xe *XmpEditor) SetRights(rights Rights) error {
+	if len(rights.Creator) > 0 {
+		if err := xe.setPathStrings(xmpDcCreator, rights.Creator); err != nil {
+			return err
+		}
+	}
+	if rights.Copyright != "" {
+		if err := xe.setPath(xmpLangPath(xmpDcRights, ""), rights.Copyright); err != nil {
+			return err
+		}
+		if err := xe.setPath(xmpRightsMarked, "True"); err != nil {
+			return err
+		}
+	}
+	if rights.UsageTerms != "" {
+		if err := xe.setPath(xmpLangPath(xmpRightsUsageTerms, ""), rights.UsageTerms); err != nil {
+			return err
+		}
+	}
+	fields := []xmpField{
+		{xmpRightsWebStatement, &rights.WebStatement},
+		{xmpPsCredit, &rights.CreditLine},
+		{xmpPsSource, &rights.Source},
+	}
+	for _, f := range fields {
+		if *f.value == "" {
+			continue
+		}
+		if err := xe.setPath(f.name, *f.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetRights sets By-line, CopyrightNotice, Credit and Source. Empty fields are left untouched
+func (ie *IptcEditor) SetRights(rights Rights) error {
+	if len(rights.Creator) > 0 {
+		if err := ie.setApplication(IPTCApplication_Byline, rights.Creator); err != nil {
+			return err
+		}
+	}
+	values := []struct {
+		tag   IptcTag
+		value string
+	}{
+		{IPTCApplication_CopyrightNotice, rights.Copyright},
+		{IPTCApplication_Credit, rights.CreditLine},
+		{IPTCApplication_Source, rights.Source},
+	}
+	for _, v := range values {
+		if v.value == "" {
+			continue
+		}
+		if err := ie.setApplication(v.tag, v.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetRights sets IFD_Artist and IFD_Copyright. Multiple creators are joined with "; ". Empty fields are left untouched
+func (ee *ExifEditor) SetRights(rights Rights) error {
+	if len(rights.Creator) > 0 {
+		if err := ee.SetIfdRootTag(IFD_Artist, strings.Join(rights.Creator, exifArtistSeparator)); err != nil {
+			return err
+		}
+	}
+	if rights.Copyright != "" {
+		return ee.SetIfdRootTag(IFD_Copyright, rights.Copyright)
+	}
+	return nil
+}
+
+// SetRights writes rights consistently to Exif, Iptc and Xmp. Empty fields are left untouched
+// so it is safe to only update parts of the rights information
+func (je *JpegEditor) SetRights(rights Rights) error {
+	if err := je.ee.SetRights(rights); err != nil {
+		return err
+	}
+	if err := je.syncIptc(func(ie *IptcEditor) error { return ie.SetRights(rights) }); err != nil {
+		return err
+	}
+	return je.xe.SetRights(rights)
+}
+
+// Rights returns the rights information of the image. Values are picked using the MWG precedence rules, i.e.
+// Exif first and then Iptc or Xmp depending on the Iptc digest (see IptcDigestState)
+func (md *MetaData) Rights() Rights {
+	ret, _ := md.rights()
+	return ret
+}
+
+func (md *MetaData) rights() (Rights, []Conflict) {
+	r := newMwgReconciler(md)
+	e, i, x := Rights{}, md.iptcData.GetRights(), md.xmpData.GetRights()
+	if !md.exifData.IsEmpty() {
+		e = md.exifData.GetRights()
+	}
+	ret := Rights{
+		Creator:      r.strs("Creator", e.Creator, i.Creator, x.Creator),
+		Copyright:    r.str("Copyright", e.Copyright, i.Copyright, x.Copyright),
+		UsageTerms:   x.UsageTerms,
+		WebStatement: x.WebStatement,
+		CreditLine:   r.str("CreditLine", "", i.CreditLine, x.CreditLine),
+		Source:       r.str("Source", "", i.Source, x.Source),
+	}
+	if len(ret.Creator) == 0 {
+		ret.Creator = nil
+	}
+	return ret, r.conflicts
+}
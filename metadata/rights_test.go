@@ -0,0 +1,75 @@
+package metadata
+
+import (
+	"reflect"
+	"testing"
+	"trimmer.io/go-xmp/xmp"
+)
+
+func TestJpegEditor_SetRights(t *testing.T) {
+	exp := Rights{
+		Creator:    []string{"Anna Andersson", "Bertil Bengtsson"},
+		Copyright:  "Copyright 2022 Anna Andersson",
+		CreditLine: "Anna Andersson",
+		Source:     "mimage",
+	}
+	je := getJpegEditor(LeicaImg, t)
+	if err := je.SetRights(exp); err != nil {
+		t.Fatalf("Could not set rights: %v", err)
+	}
+	md := jpegEditorMD(je, t)
+	if act := md.Rights(); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Expected %v got %v", exp, act)
+	}
+	expExif := Rights{Creator: exp.Creator, Copyright: exp.Copyright}
+	if act := md.Exif().GetRights(); !reflect.DeepEqual(act, expExif) {
+		t.Errorf("Expected %v got %v", expExif, act)
+	}
+	if act := md.Iptc().GetRights(); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Expected %v got %v", exp, act)
+	}
+}
+
+func TestJpegEditor_SetRightsPartial(t *testing.T) {
+	je := getJpegEditor(NoExifImg, t)
+	if err := je.SetRights(Rights{Creator: []string{"Anna Andersson"}, Copyright: "Anna Andersson"}); err != nil {
+		t.Fatalf("Could not set rights: %v", err)
+	}
+	je = reloadJpegEditor(je, true, t)
+	if err := je.SetRights(Rights{Copyright: "Bertil Bengtsson"}); err != nil {
+		t.Fatalf("Could not set rights: %v", err)
+	}
+	exp := Rights{Creator: []string{"Anna Andersson"}, Copyright: "Bertil Bengtsson"}
+	if act := jpegEditorMD(je, t).Rights(); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Expected %v got %v", exp, act)
+	}
+}
+
+func TestXmpEditor_SetRights(t *testing.T) {
+	exp := Rights{
+		Creator:      []string{"Anna Andersson"},
+		Copyright:    "Copyright 2022 Anna Andersson",
+		UsageTerms:   "All rights reserved",
+		WebStatement: "https://example.com/license",
+		CreditLine:   "Anna Andersson",
+		Source:       "mimage",
+	}
+	xe, _ := NewXmpEditorFromDocument(xmp.NewDocument())
+	if err := xe.SetRights(exp); err != nil {
+		t.Fatalf("Could not set rights: %v", err)
+	}
+	xd := reloadXmpEditor(xe, t)
+	if act := xd.GetRights(); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Expected %v got %v", exp, act)
+	}
+	if act := xd.getPathString(xmpRightsMarked); act != "True" {
+		t.Errorf("Expected %v got %v", "True", act)
+	}
+}
+
+func TestSplitExifArtist(t *testing.T) {
+	exp := []string{"Anna", "Bertil"}
+	if act := splitExifArtist("Anna; Bertil;"); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Expected %v got %v", exp, act)
+	}
+}
@@ -0,0 +1,114 @@
+package metadata
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"trimmer.io/go-xmp/xmp"
+)
+
+// ErrNoSidecar when an image has no xmp sidecar file
+var ErrNoSidecar = errors.New("No XMP sidecar file")
+
+// SidecarExt is the file extension of xmp sidecar files
+const SidecarExt = ".xmp"
+
+// ReadOptions controls what NewMetaDataFromFileOpts reads in addition to the image
+type ReadOptions struct {
+	//Sidecar merges the xmp sidecar of the image (if any). Sidecar properties replace embedded properties
+	Sidecar bool
+	//Lenient skips broken segments, exif ifds and tags instead of failing. The problems are available from
+	//MetaData.ParseErrors
+	Lenient bool
+	//IptcCharset is the character set (IANA name, e.g. ISO-8859-1) of iptc strings without a CodedCharacterSet
+	//that are not valid UTF-8. Detected if empty (see NewIptcData)
+	IptcCharset string
+}
+
+// NewMetaDataFromFileOpts reads a jpeg image file using opts
+func NewMetaDataFromFileOpts(filename string, opts ReadOptions) (*MetaData, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	md, err := newMetaData(data, opts)
+	if err != nil || !opts.Sidecar {
+		return md, err
+	}
+	sidecar, err := FindSidecar(filename)
+	if err == ErrNoSidecar {
+		return md, nil
+	} else if err != nil {
+		return md, err
+	}
+	xd, err := NewXmpDataFromFile(sidecar)
+	if err != nil {
+		return md, err
+	}
+	return md, md.mergeXmp(xd)
+}
+
+// SidecarFileNames returns the possible sidecar file names of imageFile, i.e. IMG_1234.xmp (Lightroom) and
+// IMG_1234.jpg.xmp (darktable)
+func SidecarFileNames(imageFile string) []string {
+	return []string{strings.TrimSuffix(imageFile, filepath.Ext(imageFile)) + SidecarExt, imageFile + SidecarExt}
+}
+
+// FindSidecar returns the first existing sidecar file of imageFile
+func FindSidecar(imageFile string) (string, error) {
+	for _, fileName := range SidecarFileNames(imageFile) {
+		if fi, err := os.Stat(fileName); err == nil && !fi.IsDir() {
+			return fileName, nil
+		} else if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return "", ErrNoSidecar
+}
+
+// NewXmpDataFromFile creates an XmpData struct from an xmp (sidecar) file
+func NewXmpDataFromFile(fileName string) (XmpData, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return XmpData{}, err
+	}
+	return NewXmpDataFromBytes(b)
+}
+
+// NewXmpEditorFromFile creates an XmpEditor from an xmp (sidecar) file
+func NewXmpEditorFromFile(fileName string) (*XmpEditor, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return &XmpEditor{}, err
+	}
+	return NewXmpEditorFromBytes(b)
+}
+
+// WriteSidecar commits any changes and writes the xmp document to fileName. The file is written to a temporary
+// file that replaces fileName once it has been completely written
+func (xe *XmpEditor) WriteSidecar(fileName string) error {
+	b, err := xe.Bytes(false)
+	if err != nil {
+		return err
+	}
+	perm := os.FileMode(0644)
+	if fi, err := os.Stat(fileName); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	return writeFileAtomic(fileName, perm, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
+
+// mergeXmp merges xd into the xmp data of this metadata. Properties in xd replaces existing properties
+func (md *MetaData) mergeXmp(xd XmpData) error {
+	md.summary, md.summaryErr, md.conflicts = nil, nil, nil
+	if md.xmpData.rawXmp == nil {
+		md.xmpData = xd
+		return nil
+	}
+	return md.xmpData.rawXmp.Merge(xd.rawXmp, xmp.CREATE|xmp.REPLACE)
+}
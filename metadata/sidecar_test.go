@@ -0,0 +1,67 @@
+package metadata
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSidecarFileNames(t *testing.T) {
+	exp := []string{"dir/IMG_1234.xmp", "dir/IMG_1234.jpg.xmp"}
+	if got := SidecarFileNames("dir/IMG_1234.jpg"); !reflect.DeepEqual(exp, got) {
+		t.Errorf("Expected %v got %v", exp, got)
+	}
+}
+
+func TestFindSidecar(t *testing.T) {
+	img := copyAsset(LeicaImg, t)
+	if _, err := FindSidecar(img); err != ErrNoSidecar {
+		t.Errorf("Expected %v got %v", ErrNoSidecar, err)
+	}
+	xe := getJpegEditor(img, t).Xmp()
+	for _, sidecar := range []string{img + SidecarExt, filepath.Join(filepath.Dir(img), "leica.xmp")} {
+		if err := xe.WriteSidecar(sidecar); err != nil {
+			t.Fatalf("Could not write sidecar: %v", err)
+		}
+		//IMG_1234.xmp takes precedence
+		if got, _ := FindSidecar(img); got != sidecar {
+			t.Errorf("Expected %v got %v", sidecar, got)
+		}
+	}
+}
+
+func TestNewMetaDataFromFileOpts(t *testing.T) {
+	img := copyAsset(LeicaImg, t)
+	xe := getJpegEditor(img, t).Xmp()
+	xe.SetRating(5)
+	if err := xe.WriteSidecar(SidecarFileNames(img)[0]); err != nil {
+		t.Fatalf("Could not write sidecar: %v", err)
+	}
+	md, err := NewMetaDataFromFileOpts(img, ReadOptions{})
+	if err != nil {
+		t.Fatalf("Could not read metadata: %v", err)
+	}
+	if md.Summary().Rating != 2 {
+		t.Errorf("Expected %v got %v", 2, md.Summary().Rating)
+	}
+	if md, err = NewMetaDataFromFileOpts(img, ReadOptions{Sidecar: true}); err != nil {
+		t.Fatalf("Could not read metadata: %v", err)
+	}
+	if md.Summary().Rating != 5 {
+		t.Errorf("Expected %v got %v", 5, md.Summary().Rating)
+	}
+	if md.Summary().CameraModel != getMetaData(LeicaImg, t).Summary().CameraModel {
+		t.Errorf("Expected %v got %v", getMetaData(LeicaImg, t).Summary().CameraModel, md.Summary().CameraModel)
+	}
+	//images without embedded xmp
+	img = copyAsset(NoExifImg, t)
+	if err = xe.WriteSidecar(img + SidecarExt); err != nil {
+		t.Fatalf("Could not write sidecar: %v", err)
+	}
+	if md, err = NewMetaDataFromFileOpts(img, ReadOptions{Sidecar: true}); err != nil {
+		t.Fatalf("Could not read metadata: %v", err)
+	}
+	if md.Summary().Rating != 5 {
+		t.Errorf("Expected %v got %v", 5, md.Summary().Rating)
+	}
+}
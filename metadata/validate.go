@@ -0,0 +1,523 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	jpegstructure "github.com/dsoprea/go-jpeg-image-structure/v2"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Severity of a validation Issue
+type Severity int
+
+// The different severities. Error means that the metadata is broken or will be misread by
+// other tools, Warning that it does not follow the specifications
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the severity as its name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ParseSeverity returns the Severity with the given name
+func ParseSeverity(name string) (Severity, error) {
+	for _, s := range []Severity{SeverityInfo, SeverityWarning, SeverityError} {
+		if strings.EqualFold(s.String(), name) {
+			return s, nil
+		}
+	}
+	return SeverityInfo, fmt.Errorf("Unknown severity: %s", name)
+}
+
+// IssueCode is a machine readable identifier of a validation Issue
+type IssueCode string
+
+// Issue codes reported by Validate
+const (
+	IssueJpegParse            IssueCode = "jpeg.parse"
+	IssueJpegNoSOI            IssueCode = "jpeg.no-soi"
+	IssueJpegNoEOI            IssueCode = "jpeg.no-eoi"
+	IssueJpegTrailingData     IssueCode = "jpeg.trailing-data"
+	IssueJpegNoFrame          IssueCode = "jpeg.no-frame"
+	IssueJpegSegmentOrder     IssueCode = "jpeg.segment-order"
+	IssueJpegSegmentSize      IssueCode = "jpeg.segment-size"
+	IssueJpegDuplicateApp1    IssueCode = "jpeg.duplicate-app1"
+	IssueExifParse            IssueCode = "exif.parse"
+	IssueExifUnknownTag       IssueCode = "exif.unknown-tag"
+	IssueExifWrongIfd         IssueCode = "exif.wrong-ifd"
+	IssueExifTagType          IssueCode = "exif.tag-type"
+	IssueExifTagCount         IssueCode = "exif.tag-count"
+	IssueExifBadOffset        IssueCode = "exif.bad-offset"
+	IssueIptcParse            IssueCode = "iptc.parse"
+	IssueIptcUnknownTag       IssueCode = "iptc.unknown-tag"
+	IssueIptcMaxLength        IssueCode = "iptc.max-length"
+	IssueIptcDuplicate        IssueCode = "iptc.duplicate"
+	IssueIptcMissingMandatory IssueCode = "iptc.missing-mandatory"
+	IssueIptcCharset          IssueCode = "iptc.charset"
+	IssueIptcDigestMismatch   IssueCode = "iptc.digest-mismatch"
+	IssueXmpParse             IssueCode = "xmp.parse"
+	IssueConflict             IssueCode = "metadata.conflict"
+)
+
+// Issue is a problem found by Validate
+type Issue struct {
+	Severity Severity  `json:"severity"`
+	Code     IssueCode `json:"code"`
+	Message  string    `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s [%s] %s", i.Severity, i.Code, i.Message)
+}
+
+// MaxSeverity returns the highest severity in issues or -1 if there are no issues
+func MaxSeverity(issues []Issue) Severity {
+	ret := Severity(-1)
+	for _, i := range issues {
+		if i.Severity > ret {
+			ret = i.Severity
+		}
+	}
+	return ret
+}
+
+type issueList []Issue
+
+func (l *issueList) add(severity Severity, code IssueCode, format string, args ...interface{}) {
+	*l = append(*l, Issue{Severity: severity, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateFile reads filename and validates it (see Validate)
+func ValidateFile(filename string) ([]Issue, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Validate(data), nil
+}
+
+// Validate checks the jpeg structure, the exif ifds, the iptc datasets and that exif, iptc and xmp
+// agree with each other. It returns all issues found in document order
+func Validate(data []byte) []Issue {
+	issues := issueList{}
+	if !validateJpegStructure(data, &issues) {
+		return issues
+	}
+	segments, err := parseJpegBytes(data)
+	if err != nil {
+		issues.add(SeverityError, IssueJpegParse, "could not parse jpeg segments")
+		return issues
+	}
+	if raw, err := findRawExif(segments); err == nil {
+		validateExif(raw, &issues)
+	}
+	md := MetaData{}
+	if md.exifData, err = NewExifData(segments); err != nil && err != ErrExifNoData {
+		issues.add(SeverityError, IssueExifParse, "could not parse exif: %v", err)
+	}
+	if md.iptcData, err = NewIptcData(segments); err != nil && err != ErrNoIptc {
+		issues.add(SeverityError, IssueIptcParse, "could not parse iptc: %v", err)
+	}
+	if md.iptcData != nil && len(md.iptcData.iim) > 0 {
+		validateIptc(md.iptcData.iim, &issues)
+	}
+	if md.xmpData, err = NewXmpData(segments); err != nil && err != ErrNoXmp {
+		issues.add(SeverityError, IssueXmpParse, "could not parse xmp: %v", err)
+	}
+	if md.exifData == nil {
+		md.exifData = &ExifData{}
+	}
+	if md.iptcData == nil {
+		md.iptcData = &IptcData{}
+	}
+	if md.IptcDigestState() == IptcDigestMismatch {
+		issues.add(SeverityWarning, IssueIptcDigestMismatch, "iptc digest does not match the iptc data (iptc was edited without updating xmp)")
+	}
+	for _, c := range md.Conflicts() {
+		issues.add(SeverityWarning, IssueConflict, "%v", c)
+	}
+	return issues
+}
+
+func isSofMarker(marker byte) bool {
+	return marker >= 0xc0 && marker <= 0xcf && marker != 0xc4 && marker != 0xc8 && marker != 0xcc
+}
+
+// validateJpegStructure walks the segments up to the first start of scan. Returns false if the
+// structure is too broken to parse the metadata
+func validateJpegStructure(data []byte, issues *issueList) bool {
+	if len(data) < 2 || data[0] != 0xff || data[1] != markerSOI {
+		issues.add(SeverityError, IssueJpegNoSOI, "data does not start with a jpeg SOI marker")
+		return false
+	}
+	pos, index := 2, 0
+	exifCount, xmpCount := 0, 0
+	var frame, jfif bool
+	for {
+		if pos >= len(data) || data[pos] != 0xff {
+			issues.add(SeverityError, IssueJpegParse, "expected a marker at offset %d", pos)
+			return false
+		}
+		for pos < len(data) && data[pos] == 0xff { //fill bytes
+			pos++
+		}
+		if pos >= len(data) {
+			issues.add(SeverityError, IssueJpegParse, "unexpected end of data at offset %d", pos)
+			return false
+		}
+		marker := data[pos]
+		pos++
+		if marker == markerEOI {
+			issues.add(SeverityError, IssueJpegParse, "EOI marker before start of scan")
+			return false
+		}
+		if marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) {
+			continue
+		}
+		if pos+2 > len(data) {
+			issues.add(SeverityError, IssueJpegSegmentSize, "segment 0x%02X at offset %d is truncated", marker, pos-2)
+			return false
+		}
+		length := int(data[pos])<<8 | int(data[pos+1])
+		if length < 2 || pos+length > len(data) {
+			issues.add(SeverityError, IssueJpegSegmentSize, "segment 0x%02X at offset %d has length %d which exceeds the data", marker, pos-2, length)
+			return false
+		}
+		payload := data[pos+2 : pos+length]
+		switch {
+		case marker == jpegstructure.MARKER_APP0 && bytes.HasPrefix(payload, []byte("JFIF\000")):
+			if index != 0 {
+				issues.add(SeverityWarning, IssueJpegSegmentOrder, "JFIF APP0 segment is not the first segment")
+			} else {
+				jfif = true
+			}
+		case marker == jpegstructure.MARKER_APP1 && bytes.HasPrefix(payload, exifHeader):
+			exifCount++
+			if exifCount > 1 {
+				issues.add(SeverityError, IssueJpegDuplicateApp1, "more than one exif APP1 segment")
+			} else if index > 1 || (index == 1 && !jfif) {
+				issues.add(SeverityWarning, IssueJpegSegmentOrder, "exif APP1 segment does not follow SOI (or JFIF APP0)")
+			}
+		case marker == jpegstructure.MARKER_APP1 && bytes.HasPrefix(payload, xmpPrefix):
+			xmpCount++
+			if xmpCount > 1 {
+				issues.add(SeverityError, IssueJpegDuplicateApp1, "more than one standard xmp APP1 segment")
+			}
+		}
+		if marker >= jpegstructure.MARKER_APP0 && marker <= 0xef && frame {
+			issues.add(SeverityWarning, IssueJpegSegmentOrder, "APP%d segment after the frame header", marker-jpegstructure.MARKER_APP0)
+		}
+		if isSofMarker(marker) {
+			frame = true
+		}
+		pos += length
+		index++
+		if marker == markerSOS {
+			break
+		}
+	}
+	if !frame {
+		issues.add(SeverityError, IssueJpegNoFrame, "start of scan without a frame header")
+	}
+	eoi := bytes.LastIndex(data[pos:], []byte{0xff, markerEOI})
+	if eoi < 0 {
+		issues.add(SeverityWarning, IssueJpegNoEOI, "missing EOI marker")
+	} else if trailing := len(data) - pos - eoi - 2; trailing > 0 {
+		issues.add(SeverityInfo, IssueJpegTrailingData, "%d bytes after the EOI marker", trailing)
+	}
+	return true
+}
+
+// tiff field types
+const (
+	tiffByte      = 1
+	tiffAscii     = 2
+	tiffShort     = 3
+	tiffLong      = 4
+	tiffRational  = 5
+	tiffSByte     = 6
+	tiffUndefined = 7
+	tiffSShort    = 8
+	tiffSLong     = 9
+	tiffSRational = 10
+	tiffFloat     = 11
+	tiffDouble    = 12
+	tiffIfdType   = 13
+)
+
+var tiffTypeSize = map[uint16]int{
+	tiffByte: 1, tiffAscii: 1, tiffShort: 2, tiffLong: 4, tiffRational: 8, tiffSByte: 1,
+	tiffUndefined: 1, tiffSShort: 2, tiffSLong: 4, tiffSRational: 8, tiffFloat: 4, tiffDouble: 8, tiffIfdType: 4,
+}
+
+// exifTypeMatches checks a stored tiff type against the type of the tag description. Integer tags
+// are allowed to use a wider type
+func exifTypeMatches(t ExifTagType, tt uint16) bool {
+	switch t {
+	case ExifString:
+		return tt == tiffAscii
+	case ExifUint8:
+		return tt == tiffByte
+	case ExifUint16, ExifUint32:
+		return tt == tiffShort || tt == tiffLong
+	case ExifInt16, ExifInt32:
+		return tt == tiffSShort || tt == tiffSLong
+	case ExifRational:
+		return tt == tiffSRational
+	case ExifUrational:
+		return tt == tiffRational
+	case ExifFloat:
+		return tt == tiffFloat
+	case ExifDouble:
+		return tt == tiffDouble
+	case ExifUndef:
+		return tt == tiffUndefined || tt == tiffByte
+	default:
+		return false
+	}
+}
+
+// exifEntry is a raw ifd entry
+type exifEntry struct {
+	tag   ExifTag
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// uints returns the values of a short or long entry
+func (e exifEntry) uints(bo binary.ByteOrder) []uint32 {
+	ret := []uint32{}
+	for i := uint32(0); i < e.count; i++ {
+		switch {
+		case e.typ == tiffShort && int(i*2+2) <= len(e.value):
+			ret = append(ret, uint32(bo.Uint16(e.value[i*2:])))
+		case e.typ == tiffLong && int(i*4+4) <= len(e.value):
+			ret = append(ret, bo.Uint32(e.value[i*4:]))
+		}
+	}
+	return ret
+}
+
+var exifIndexes = []ExifIndex{RootIFD, ExifIFD, GpsIFD, InteropIFD, ThumbnailIFD}
+
+// exifSubIfds are the pointer tags to the sub ifds
+var exifSubIfds = map[ExifTag]ExifIndex{IFD_ExifOffset: ExifIFD, IFD_GPSInfo: GpsIFD, ExifIFD_InteropOffset: InteropIFD}
+
+// validateExif walks the raw tiff structure. go-exif silently drops tags that are unknown in their
+// ifd or have an unexpected type so its index cannot be used here
+func validateExif(raw []byte, issues *issueList) {
+	if len(raw) < 8 {
+		issues.add(SeverityError, IssueExifParse, "exif data is too short")
+		return
+	}
+	var bo binary.ByteOrder
+	switch string(raw[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		issues.add(SeverityError, IssueExifParse, "unknown byte order %q", raw[:2])
+		return
+	}
+	visited := map[uint32]bool{}
+	var walk func(index ExifIndex, offset uint32)
+	walk = func(index ExifIndex, offset uint32) {
+		if visited[offset] {
+			issues.add(SeverityError, IssueExifBadOffset, "%s points to an already visited ifd", IFDPaths[index])
+			return
+		}
+		visited[offset] = true
+		if uint64(offset)+2 > uint64(len(raw)) {
+			issues.add(SeverityError, IssueExifBadOffset, "%s offset %d is outside the exif data", IFDPaths[index], offset)
+			return
+		}
+		n := uint64(bo.Uint16(raw[offset:]))
+		if uint64(offset)+2+n*12+4 > uint64(len(raw)) {
+			issues.add(SeverityError, IssueExifBadOffset, "%s with %d entries exceeds the exif data", IFDPaths[index], n)
+			return
+		}
+		entries := []exifEntry{}
+		for i := uint64(0); i < n; i++ {
+			b := raw[uint64(offset)+2+i*12:]
+			e := exifEntry{tag: ExifTag(bo.Uint16(b)), typ: bo.Uint16(b[2:]), count: bo.Uint32(b[4:])}
+			size, ok := tiffTypeSize[e.typ]
+			if !ok {
+				issues.add(SeverityError, IssueExifTagType, "tag 0x%04x in %s has unknown type %d", e.tag, IFDPaths[index], e.typ)
+				continue
+			}
+			length := uint64(size) * uint64(e.count)
+			if length <= 4 {
+				e.value = b[8 : 8+length]
+			} else if valueOffset := uint64(bo.Uint32(b[8:])); valueOffset+length > uint64(len(raw)) {
+				issues.add(SeverityError, IssueExifBadOffset, "tag 0x%04x in %s points outside the exif data", e.tag, IFDPaths[index])
+				continue
+			} else {
+				e.value = raw[valueOffset : valueOffset+length]
+			}
+			entries = append(entries, e)
+			validateExifEntry(index, e, issues)
+			if sub, ok := exifSubIfds[e.tag]; ok && (e.typ == tiffLong || e.typ == tiffIfdType) && len(e.value) == 4 {
+				walk(sub, bo.Uint32(e.value))
+			}
+		}
+		validateExifOffsets(index, entries, bo, len(raw), issues)
+		if next := bo.Uint32(raw[uint64(offset)+2+n*12:]); next != 0 && index == RootIFD {
+			walk(ThumbnailIFD, next)
+		}
+	}
+	walk(RootIFD, bo.Uint32(raw[4:]))
+}
+
+func validateExifEntry(index ExifIndex, e exifEntry, issues *issueList) {
+	if _, ok := exifSubIfds[e.tag]; ok {
+		return
+	}
+	descIndex := index
+	if index == ThumbnailIFD {
+		descIndex = RootIFD
+	}
+	desc, found := ExifTagDescriptions[ExifIndexTag{descIndex, e.tag}]
+	if !found {
+		if other, ok := exifTagIfd(e.tag); ok {
+			issues.add(SeverityWarning, IssueExifWrongIfd, "%s (0x%04x) is in %s but belongs in %s", ExifTagName(other, e.tag), e.tag, IFDPaths[index], IFDPaths[other])
+		} else {
+			issues.add(SeverityInfo, IssueExifUnknownTag, "unknown tag 0x%04x in %s", e.tag, IFDPaths[index])
+		}
+		return
+	}
+	if !exifTypeMatches(desc.Type, e.typ) {
+		issues.add(SeverityWarning, IssueExifTagType, "%s in %s has type %d", desc.Name, IFDPaths[index], e.typ)
+	} else if desc.Count > 0 && desc.Type != ExifString && int(e.count) != desc.Count {
+		issues.add(SeverityWarning, IssueExifTagCount, "%s in %s has count %d, expected %d", desc.Name, IFDPaths[index], e.count, desc.Count)
+	}
+}
+
+// validateExifOffsets checks that offset tags (e.g. strips and the thumbnail) together with their
+// length tags are within the exif data
+func validateExifOffsets(index ExifIndex, entries []exifEntry, bo binary.ByteOrder, size int, issues *issueList) {
+	descIndex := index
+	if index == ThumbnailIFD {
+		descIndex = RootIFD
+	}
+	byTag := map[ExifTag]exifEntry{}
+	for _, e := range entries {
+		byTag[e.tag] = e
+	}
+	for _, e := range entries {
+		tag := e.tag
+		desc, found := ExifTagDescriptions[ExifIndexTag{descIndex, tag}]
+		if !found || !desc.Offset {
+			continue
+		}
+		pair := desc.OffsetPair
+		if tag == IFD_ThumbnailOffset {
+			pair = IFD_ThumbnailLength
+		}
+		lengths, found := byTag[pair]
+		if !found {
+			continue
+		}
+		offsets, sizes := e.uints(bo), lengths.uints(bo)
+		for i := 0; i < len(offsets) && i < len(sizes); i++ {
+			if uint64(offsets[i])+uint64(sizes[i]) > uint64(size) {
+				issues.add(SeverityError, IssueExifBadOffset, "%s in %s points outside the exif data", desc.Name, IFDPaths[index])
+				break
+			}
+		}
+	}
+}
+
+// exifTagIfd returns the ifd where tag is defined
+func exifTagIfd(tag ExifTag) (ExifIndex, bool) {
+	for _, index := range exifIndexes {
+		if _, found := ExifTagDescriptions[ExifIndexTag{index, tag}]; found {
+			return index, true
+		}
+	}
+	return RootIFD, false
+}
+
+func validateIptc(iim []byte, issues *issueList) {
+	counts := map[IptcRecordTag]int{}
+	records := map[IptcRecord]bool{}
+	var charset []byte
+	var nonAscii, invalidUtf8 bool
+	r := bytes.NewReader(bytes.TrimRight(iim, "\x00"))
+	for {
+		rt, data, err := decodeIptcRecordData(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			issues.add(SeverityError, IssueIptcParse, "could not read iptc dataset: %v", err)
+			break
+		}
+		counts[rt]++
+		records[rt.Record] = true
+		desc, found := IptcTagDescriptions[rt]
+		if !found {
+			issues.add(SeverityInfo, IssueIptcUnknownTag, "unknown dataset %d:%d", rt.Record, rt.Tag)
+			continue
+		}
+		if counts[rt] == 2 && !desc.Repeatable {
+			issues.add(SeverityWarning, IssueIptcDuplicate, "%s (%d:%d) is not repeatable but occurs more than once", desc.Name, rt.Record, rt.Tag)
+		}
+		if desc.MaxLength > 0 && len(data) > desc.MaxLength {
+			issues.add(SeverityWarning, IssueIptcMaxLength, "%s (%d:%d) is %d bytes, max is %d", desc.Name, rt.Record, rt.Tag, len(data), desc.MaxLength)
+		}
+		if rt.Record == IPTCEnvelope && rt.Tag == IPTCEnvelope_CodedCharacterSet {
+			charset = data
+		} else if desc.Type == IptcString || desc.Type == IptcDigits {
+			for _, b := range data {
+				if b >= utf8.RuneSelf {
+					nonAscii = true
+					break
+				}
+			}
+			invalidUtf8 = invalidUtf8 || !utf8.Valid(data)
+		}
+	}
+	missing := []IptcRecordTag{}
+	for rt, desc := range IptcTagDescriptions {
+		if desc.Mandatory && records[rt.Record] && counts[rt] == 0 {
+			missing = append(missing, rt)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].Record < missing[j].Record || (missing[i].Record == missing[j].Record && missing[i].Tag < missing[j].Tag)
+	})
+	for _, rt := range missing {
+		issues.add(SeverityWarning, IssueIptcMissingMandatory, "missing mandatory dataset %s (%d:%d)", IptcTagName(rt.Record, rt.Tag), rt.Record, rt.Tag)
+	}
+	switch {
+	case string(charset) == iptcUtfCharSet && invalidUtf8:
+		issues.add(SeverityError, IssueIptcCharset, "CodedCharacterSet is UTF-8 but the data is not valid UTF-8")
+	case charset == nil && nonAscii:
+		issues.add(SeverityWarning, IssueIptcCharset, "non ascii data without a CodedCharacterSet")
+	case charset != nil && string(charset) != iptcUtfCharSet:
+		issues.add(SeverityInfo, IssueIptcCharset, "CodedCharacterSet %q is not UTF-8", charset)
+	}
+}
@@ -0,0 +1,120 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func hasIssue(issues []Issue, code IssueCode) bool {
+	for _, i := range issues {
+		if i.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidate(t *testing.T) {
+	for _, fname := range []string{LeicaImg, NikonImg, NoExifImg, GPSImg} {
+		issues := Validate(getAssetBytes(fname, t))
+		if MaxSeverity(issues) >= SeverityError {
+			t.Errorf("Expected no errors for %s got %v", fname, issues)
+		}
+	}
+}
+
+func TestValidate_JpegStructure(t *testing.T) {
+	img := getAssetBytes(LeicaImg, t)
+	if issues := Validate([]byte("not an image")); !hasIssue(issues, IssueJpegNoSOI) {
+		t.Errorf("Expected %v got %v", IssueJpegNoSOI, issues)
+	}
+	if issues := Validate(img[:200]); !hasIssue(issues, IssueJpegSegmentSize) {
+		t.Errorf("Expected %v got %v", IssueJpegSegmentSize, issues)
+	}
+	trailing := append(append([]byte{}, img...), "trailer"...)
+	if issues := Validate(trailing); !hasIssue(issues, IssueJpegTrailingData) {
+		t.Errorf("Expected %v got %v", IssueJpegTrailingData, issues)
+	}
+	//duplicate the exif segment
+	length := int(img[4])<<8 | int(img[5])
+	exifSegment := img[2 : 4+length]
+	if !bytes.HasPrefix(exifSegment[4:], exifHeader) {
+		t.Fatalf("Expected exif as the first segment")
+	}
+	dup := append(append(append([]byte{}, img[:4+length]...), exifSegment...), img[4+length:]...)
+	if issues := Validate(dup); !hasIssue(issues, IssueJpegDuplicateApp1) {
+		t.Errorf("Expected %v got %v", IssueJpegDuplicateApp1, issues)
+	}
+}
+
+func TestValidate_Exif(t *testing.T) {
+	je := getJpegEditor(LeicaImg, t)
+	if err := setIbTag(je.Exif().rootIb, ExifIFD_LensModel, []byte("lens")); err != nil {
+		t.Fatalf("Could not set tag: %v", err)
+	}
+	je.Exif().SetDirty()
+	b, err := je.Bytes()
+	if err != nil {
+		t.Fatalf("Could not get bytes: %v", err)
+	}
+	if issues := Validate(b); !hasIssue(issues, IssueExifWrongIfd) {
+		t.Errorf("Expected %v got %v", IssueExifWrongIfd, issues)
+	}
+	issues := issueList{}
+	validateExif([]byte("MM\x00\x2a\x00\x00\xff\xff"), &issues)
+	if !hasIssue(issues, IssueExifBadOffset) {
+		t.Errorf("Expected %v got %v", IssueExifBadOffset, issues)
+	}
+}
+
+func TestValidate_Iptc(t *testing.T) {
+	buf := bytes.Buffer{}
+	bw := bufio.NewWriter(&buf)
+	_ = encodeIptcRecordData(bw, IPTCEnvelope, IPTCEnvelope_CodedCharacterSet, iptcUtfCharSet)
+	_ = encodeIptcRecordData(bw, IPTCApplication, IPTCApplication_ObjectName, strings.Repeat("a", 100))
+	_ = encodeIptcRecordData(bw, IPTCApplication, IPTCApplication_ObjectName, "title")
+	_ = encodeIptcRecordData(bw, IPTCApplication, IPTCApplication_Headline, "\xff\xfe")
+	_ = bw.Flush()
+	issues := issueList{}
+	validateIptc(buf.Bytes(), &issues)
+	for _, code := range []IssueCode{IssueIptcMaxLength, IssueIptcDuplicate, IssueIptcMissingMandatory, IssueIptcCharset} {
+		if !hasIssue(issues, code) {
+			t.Errorf("Expected %v got %v", code, issues)
+		}
+	}
+	if MaxSeverity(issues) != SeverityError {
+		t.Errorf("Expected %v got %v", SeverityError, MaxSeverity(issues))
+	}
+}
+
+func TestValidate_Conflicts(t *testing.T) {
+	je := setDigest(titleEditor(t), IptcDigest([]byte("changed by someone else")), t)
+	b, err := je.Bytes()
+	if err != nil {
+		t.Fatalf("Could not get bytes: %v", err)
+	}
+	issues := Validate(b)
+	if !hasIssue(issues, IssueIptcDigestMismatch) {
+		t.Errorf("Expected %v got %v", IssueIptcDigestMismatch, issues)
+	}
+	if !hasIssue(issues, IssueConflict) {
+		t.Errorf("Expected %v got %v", IssueConflict, issues)
+	}
+}
+
+func TestIssue_MarshalJSON(t *testing.T) {
+	b, err := json.Marshal(Issue{Severity: SeverityWarning, Code: IssueIptcDuplicate, Message: "msg"})
+	if err != nil {
+		t.Fatalf("Could not marshal issue: %v", err)
+	}
+	exp := `{"severity":"warning","code":"iptc.duplicate","message":"msg"}`
+	if string(b) != exp {
+		t.Errorf("Expected %v got %v", exp, string(b))
+	}
+	if s, err := ParseSeverity("Error"); err != nil || s != SeverityError {
+		t.Errorf("Expected %v got %v", SeverityError, s)
+	}
+}
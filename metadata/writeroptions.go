@@ -0,0 +1,42 @@
+package metadata
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// WriterOptions controls the software attribution and history written when edits are committed
+type WriterOptions struct {
+	//Agent is written as Exif Software, xmp:CreatorTool and history software agent. If empty the editor defaults
+	//are used
+	Agent string
+	//KeepSoftware keeps the existing Exif Software and xmp:CreatorTool, e.g. the camera firmware or raw converter
+	KeepSoftware bool
+	//MaxHistory caps the number of xmpMM:History events by dropping the oldest. 0 means no limit and a negative
+	//value disables new history events
+	MaxHistory int
+	//CoalesceHistory updates the last history event instead of adding a new one if it was saved by the same agent
+	CoalesceHistory bool
+}
+
+func (wo WriterOptions) agent(defaultAgent string) string {
+	if wo.Agent == "" {
+		return defaultAgent
+	}
+	return wo.Agent
+}
+
+// newXmpID returns a random (version 4) uuid with prefix, e.g. xmp.iid:5c4d9f5e-...
+func newXmpID(prefix string) string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%s:%x-%x-%x-%x-%x", prefix, b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// SetWriterOptions sets the writer options of the exif and xmp editors
+func (je *JpegEditor) SetWriterOptions(opts WriterOptions) {
+	je.ee.SetWriterOptions(opts)
+	je.xe.SetWriterOptions(opts)
+}
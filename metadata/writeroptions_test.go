@@ -0,0 +1,85 @@
+package metadata
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJpegEditor_SetWriterOptions(t *testing.T) {
+	software := getMetaData(LeicaImg, t).Summary().Software
+	je := getJpegEditor(LeicaImg, t)
+	je.SetWriterOptions(WriterOptions{KeepSoftware: true})
+	_ = je.SetDescription("keep software")
+	je = reloadJpegEditor(je, true, t)
+	if got := jpegEditorMD(je, t).Summary().Software; got != software {
+		t.Errorf("Expected %v got %v", software, got)
+	}
+
+	je.SetWriterOptions(WriterOptions{Agent: "my agent"})
+	_ = je.SetDescription("agent")
+	je = reloadJpegEditor(je, true, t)
+	if got := jpegEditorMD(je, t).Summary().Software; got != "my agent" {
+		t.Errorf("Expected %v got %v", "my agent", got)
+	}
+	if got := je.Xmp().Base().CreatorTool.String(); got != "my agent" {
+		t.Errorf("Expected %v got %v", "my agent", got)
+	}
+}
+
+func TestXmpEditor_History(t *testing.T) {
+	tests := []struct {
+		opts WriterOptions
+		exp  int
+	}{
+		{WriterOptions{}, 3},
+		{WriterOptions{MaxHistory: 2}, 2},
+		{WriterOptions{MaxHistory: -1}, 0},
+		{WriterOptions{CoalesceHistory: true}, 1},
+	}
+	for _, test := range tests {
+		je := getJpegEditor(NoExifImg, t)
+		je.SetWriterOptions(test.opts)
+		documentID, instanceID := "", ""
+		for i := 0; i < 3; i++ {
+			je.Xmp().SetTitle("title")
+			je = reloadJpegEditor(je, true, t)
+			je.SetWriterOptions(test.opts)
+			if i == 0 {
+				documentID = je.Xmp().getPathString(xmpMMDocumentID)
+			}
+			if got := je.Xmp().getPathString(xmpMMDocumentID); got != documentID || !strings.HasPrefix(got, "xmp.did:") {
+				t.Errorf("Expected %v got %v", documentID, got)
+			}
+			if got := je.Xmp().getPathString(xmpMMInstanceID); got == instanceID || !strings.HasPrefix(got, "xmp.iid:") {
+				t.Errorf("Expected new instance id got %v", got)
+			} else {
+				instanceID = got
+			}
+		}
+		n := 0
+		if mm := je.Xmp().MM(); mm != nil {
+			n = len(mm.History)
+		}
+		if n != test.exp {
+			t.Errorf("Expected %v got %v", test.exp, n)
+		}
+		if got := je.Xmp().getPathString(xmpMMOriginalDocumentID); got != documentID {
+			t.Errorf("Expected %v got %v", documentID, got)
+		}
+	}
+}
+
+func TestXmpEditor_HistoryCoalesceCapped(t *testing.T) {
+	je := getJpegEditor(NoExifImg, t)
+	for i := 0; i < 3; i++ {
+		je.Xmp().SetTitle("title")
+		je = reloadJpegEditor(je, true, t)
+	}
+	//an existing history over the cap is trimmed also when the last event is coalesced
+	je.SetWriterOptions(WriterOptions{MaxHistory: 2, CoalesceHistory: true})
+	je.Xmp().SetTitle("coalesced")
+	je = reloadJpegEditor(je, true, t)
+	if mm := je.Xmp().MM(); mm == nil || len(mm.History) != 2 {
+		t.Errorf("Expected %v history events", 2)
+	}
+}
@@ -3,7 +3,11 @@ package metadata
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	jpegstructure "github.com/dsoprea/go-jpeg-image-structure/v2"
+	"strconv"
+	"strings"
+	_ "trimmer.io/go-xmp/models" //register all known xmp namespaces
 	"trimmer.io/go-xmp/models/dc"
 	"trimmer.io/go-xmp/models/ps"
 	xmpbase "trimmer.io/go-xmp/models/xmp_base"
@@ -19,7 +23,13 @@ type XmpData struct {
 // ErrNoXmp when a jpeg image does not contain any xmp data
 var ErrNoXmp = errors.New("No XMP data")
 
-// NewXmpData creates an XmpData struct from a jpeg segment list
+// ErrXmpPath when a path has no namespace prefix or uses a namespace that is not registered
+var ErrXmpPath = errors.New("Invalid XMP path")
+
+// ErrXmpNamespace when a namespace prefix or uri is invalid or the prefix is already registered with another uri
+var ErrXmpNamespace = errors.New("Invalid XMP namespace")
+
+// NewXmpData creates an XmpData struct from a jpeg segment list. Any extended xmp is merged into the document
 func NewXmpData(segments *jpegstructure.SegmentList) (XmpData, error) {
 	_, s, err := segments.FindXmp()
 	if err != nil {
@@ -30,7 +40,12 @@ func NewXmpData(segments *jpegstructure.SegmentList) (XmpData, error) {
 		//We should log errors
 		return XmpData{}, ErrNoXmp
 	}
-	return NewXmpDataFromBytes([]byte(str))
+	xd, err := NewXmpDataFromBytes([]byte(str))
+	if err != nil {
+		return xd, err
+	}
+	xd.mergeExtendedXmp(segments)
+	return xd, nil
 }
 
 // NewXmpDataFromBytes creates an XmpData struct from marshalled xmp.Document
@@ -59,6 +74,173 @@ func (xd XmpData) DublinCore() *dc.DublinCore {
 	return nil
 }
 
+// RegisterXmpNamespace registers a (custom) namespace so its properties can be used in xmp paths. Registering an
+// already known prefix with the same uri is a no-op
+func RegisterXmpNamespace(prefix, uri string) error {
+	if prefix == "" || uri == "" || strings.ContainsAny(prefix, ":/[]") {
+		return ErrXmpNamespace
+	}
+	if ns, err := xmp.GetNamespace(prefix); err == nil {
+		if ns.GetURI() != uri {
+			return fmt.Errorf("%w: %s is already registered as %s", ErrXmpNamespace, prefix, ns.GetURI())
+		}
+		return nil
+	}
+	xmp.Register(xmp.NewNamespace(prefix, uri, nil), xmp.XmpMetadata)
+	return nil
+}
+
+// registerXmpNamespace registers prefix (unless already known) so it can be used in xmp paths
+func registerXmpNamespace(prefix, uri string) {
+	if _, err := xmp.GetNamespace(prefix); err == nil {
+		return
+	}
+	xmp.Register(xmp.NewNamespace(prefix, uri, nil), xmp.XmpMetadata)
+}
+
+// xmpPathNamespace returns an error if the namespace prefix of path is not registered
+func xmpPathNamespace(path string) error {
+	i := strings.IndexByte(path, ':')
+	if i < 1 {
+		return fmt.Errorf("%w: %s has no namespace prefix", ErrXmpPath, path)
+	}
+	if _, err := xmp.GetNamespace(path[:i]); err != nil {
+		return fmt.Errorf("%w: unknown namespace %s", ErrXmpPath, path[:i])
+	}
+	return nil
+}
+
+func xmpItemPath(path string, idx int) string {
+	return fmt.Sprintf("%s[%d]", path, idx)
+}
+
+func xmpLangPath(path string, lang string) string {
+	if lang == "" {
+		lang = "x-default"
+	}
+	return fmt.Sprintf("%s[%s]", path, lang)
+}
+
+// countPath returns the number of items in the array at path
+func (xd XmpData) countPath(path string) int {
+	if xd.IsEmpty() {
+		return 0
+	}
+	pvs, err := xd.rawXmp.ListPaths()
+	if err != nil {
+		return 0
+	}
+	prefix := path + "["
+	count := 0
+	for _, pv := range pvs {
+		p := string(pv.Path)
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		end := strings.IndexByte(p[len(prefix):], ']')
+		if end < 0 {
+			continue
+		}
+		if idx, err := strconv.Atoi(p[len(prefix) : len(prefix)+end]); err == nil && idx >= count {
+			count = idx + 1
+		}
+	}
+	return count
+}
+
+// hasPath returns true if path or any of its array items or struct fields has a value
+func (xd XmpData) hasPath(path string) bool {
+	if xd.IsEmpty() {
+		return false
+	}
+	pvs, err := xd.rawXmp.ListPaths()
+	if err != nil {
+		return false
+	}
+	for _, pv := range pvs {
+		p := string(pv.Path)
+		if p == path || strings.HasPrefix(p, path+"/") || strings.HasPrefix(p, path+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the value at path. Paths are qualified with the namespace prefix, array items are addressed by a zero
+// based index or language, and struct fields are separated by /, e.g. dc:title[x-default], dc:subject[2] or
+// Iptc4xmpCore:CreatorContactInfo/CiEmailWork. Custom namespaces need to be registered with RegisterXmpNamespace
+func (xd XmpData) Get(path string) (string, error) {
+	if err := xmpPathNamespace(path); err != nil {
+		return "", err
+	}
+	return xd.getPath(path)
+}
+
+// GetArray returns the items of the array at path, e.g. dc:subject
+func (xd XmpData) GetArray(path string) ([]string, error) {
+	if err := xmpPathNamespace(path); err != nil {
+		return nil, err
+	}
+	return xd.getPathStrings(path), nil
+}
+
+// getPath returns the value at path or an error if the path does not exist
+func (xd XmpData) getPath(path string) (string, error) {
+	if xd.IsEmpty() {
+		return "", ErrNoXmp
+	}
+	return xd.rawXmp.GetPath(xmp.Path(path))
+}
+
+// getPathFloat returns the value at path as a float64. Returns 0 in case of an error
+func (xd XmpData) getPathFloat(path string) float64 {
+	v, err := xd.getPath(path)
+	if err != nil {
+		return 0
+	}
+	f, _ := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	return f
+}
+
+// getPathString returns the value at path. Returns "" in case of an error
+func (xd XmpData) getPathString(path string) string {
+	v, _ := xd.getPath(path)
+	return v
+}
+
+// getPathStrings returns the items of the array at path
+func (xd XmpData) getPathStrings(path string) []string {
+	ret := []string{}
+	n := xd.countPath(path)
+	for i := 0; i < n; i++ {
+		if v, err := xd.getPath(xmpItemPath(path, i)); err == nil {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// xmpField maps a struct field to its xmp property name
+type xmpField struct {
+	name  string
+	value *string
+}
+
+// scanStruct reads the properties of the struct at path into fields
+func (xd XmpData) scanStruct(path string, fields []xmpField) {
+	for _, f := range fields {
+		*f.value = xd.getPathString(path + "/" + f.name)
+	}
+}
+
+// GetDescription returns the DublinCore description if it exists
+func (xd XmpData) GetDescription() string {
+	if dcore := xd.DublinCore(); dcore != nil {
+		return dcore.Description.Default()
+	}
+	return ""
+}
+
 // GetKeywords returns the keywords from DublinCore
 func (xd XmpData) GetKeywords() []string {
 	if dcore := xd.DublinCore(); dcore != nil {
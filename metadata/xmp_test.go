@@ -1,6 +1,7 @@
 package metadata
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -30,3 +31,31 @@ func TestXmpData_IsEmpty(t *testing.T) {
 func TestXmpData_String(t *testing.T) {
 
 }
+
+func TestRegisterXmpNamespace(t *testing.T) {
+	if err := RegisterXmpNamespace("mimageWorkflow", "http://github.com/msvens/mimage/workflow/1.0/"); err != nil {
+		t.Errorf("Expected %v got %v", nil, err)
+	}
+	if err := RegisterXmpNamespace("mimageWorkflow", "http://github.com/msvens/mimage/workflow/1.0/"); err != nil {
+		t.Errorf("Expected %v got %v", nil, err)
+	}
+	if err := RegisterXmpNamespace("mimageWorkflow", "http://example.com/other/"); !errors.Is(err, ErrXmpNamespace) {
+		t.Errorf("Expected %v got %v", ErrXmpNamespace, err)
+	}
+	if err := RegisterXmpNamespace("bad:prefix", "http://example.com/bad/"); !errors.Is(err, ErrXmpNamespace) {
+		t.Errorf("Expected %v got %v", ErrXmpNamespace, err)
+	}
+}
+
+func TestXmpData_Get(t *testing.T) {
+	xd := getXmpData(LeicaImg, t)
+	if got, err := xd.Get("dc:title[x-default]"); err != nil || got != "Morning Fog" {
+		t.Errorf("Expected %v got %v (%v)", "Morning Fog", got, err)
+	}
+	if _, err := xd.Get("title"); !errors.Is(err, ErrXmpPath) {
+		t.Errorf("Expected %v got %v", ErrXmpPath, err)
+	}
+	if _, err := xd.Get("unknownNs:title"); !errors.Is(err, ErrXmpPath) {
+		t.Errorf("Expected %v got %v", ErrXmpPath, err)
+	}
+}
@@ -13,12 +13,22 @@ import (
 
 const xmpEditorSoftware = "github.com/msvens/mimage (go-XmpEditor)"
 
+const (
+	xmpMMDocumentID         = "xmpMM:DocumentID"
+	xmpMMInstanceID         = "xmpMM:InstanceID"
+	xmpMMOriginalDocumentID = "xmpMM:OriginalDocumentID"
+)
+
 var xmpPrefix = []byte("http://ns.adobe.com/xap/1.0/\000")
 
 // XmpEditor add a dirty field to the XmpData
 type XmpEditor struct {
 	XmpData
 	dirty bool
+	//size of the existing xpacket wrapped packet, used to reuse its padding
+	packetSize    int
+	packetOptions XmpPacketOptions
+	writerOptions WriterOptions
 }
 
 // NewXmpEditor from a jpeg segment list
@@ -32,13 +42,11 @@ func NewXmpEditor(sl *jpegstructure.SegmentList) (*XmpEditor, error) {
 		ret.Clear(false)
 		return &ret, nil
 	}
-	return &XmpEditor{
-		XmpData{
-			xmpData.rawXmp,
-		},
-		false,
-	}, nil
-
+	ret := XmpEditor{XmpData: XmpData{xmpData.rawXmp}}
+	if _, s, err := sl.FindXmp(); err == nil {
+		ret.packetSize = xmpPacketSize(s.Data[len(xmpPrefix):])
+	}
+	return &ret, nil
 }
 
 // NewXmpEditorFromDocument from an xmp.document
@@ -46,12 +54,7 @@ func NewXmpEditorFromDocument(doc *xmp.Document) (*XmpEditor, error) {
 	if doc == nil {
 		return nil, fmt.Errorf("Document is nil")
 	}
-	return &XmpEditor{
-		XmpData{
-			doc,
-		},
-		false,
-	}, nil
+	return &XmpEditor{XmpData: XmpData{doc}}, nil
 }
 
 // NewXmpEditorFromBytes from a marshalled xmp.Document
@@ -60,17 +63,12 @@ func NewXmpEditorFromBytes(data []byte) (*XmpEditor, error) {
 	if err != nil {
 		return &XmpEditor{}, err
 	}
-	return &XmpEditor{
-		XmpData{
-			xmpData.rawXmp,
-		},
-		false,
-	}, nil
+	return &XmpEditor{XmpData: XmpData{xmpData.rawXmp}}, nil
 
 }
 
 // Bytes commits any changes and writes the xmpDocument to bytes. If prefix
-// is adds "http://ns.adobe.com/xap/1.0/\000" so it can be added to a jpeg segment list
+// is adds "http://ns.adobe.com/xap/1.0/\000" and the xpacket wrapper so it can be added to a jpeg segment list
 func (xe *XmpEditor) Bytes(prefix bool) ([]byte, error) {
 	xe.setSoftware()
 	if !prefix {
@@ -82,10 +80,29 @@ func (xe *XmpEditor) Bytes(prefix bool) ([]byte, error) {
 		return b, err
 	}
 	buff.Write(xmpPrefix)
-	buff.Write(b)
+	buff.Write(wrapXmpPacket(b, xe.packetOptions, xe.packetSize, maxSegmentData-len(xmpPrefix)))
 	return buff.Bytes(), nil
 }
 
+// packets commits any changes and writes the xmpDocument to a standard (xpacket wrapped) packet that fits in a
+// single jpeg segment and, if needed, an extended packet
+func (xe *XmpEditor) packets() (standard []byte, extended []byte, err error) {
+	xe.setSoftware()
+	maxSize := maxSegmentData - len(xmpPrefix)
+	overhead := len(xmpPrefix) + len(xpacketBegin) + len(xe.packetOptions.end())
+	if standard, extended, err = splitXmp(xe.rawXmp, overhead); err != nil {
+		return nil, nil, err
+	}
+	return wrapXmpPacket(standard, xe.packetOptions, xe.packetSize, maxSize), extended, nil
+}
+
+// SetPacketOptions sets the xpacket wrapper options used when the xmp is embedded in an image. Marks the editor as
+// dirty
+func (xe *XmpEditor) SetPacketOptions(opts XmpPacketOptions) {
+	xe.packetOptions = opts
+	xe.dirty = true
+}
+
 // Document commits any changes and returns the xmp.Document
 func (xe *XmpEditor) Document() *xmp.Document {
 	xe.setSoftware()
@@ -114,6 +131,13 @@ func (xe *XmpEditor) SetDocument(doc *xmp.Document, dirty bool) {
 	xe.dirty = dirty
 }
 
+// SetDescription sets the Dublin Core description
+func (xe *XmpEditor) SetDescription(description string) {
+	dcore := xe.dcOrCreate()
+	dcore.Description.Set("", description)
+	xe.dirty = true
+}
+
 // SetKeywords sets the DublicCore keywords
 func (xe *XmpEditor) SetKeywords(keywords []string) {
 	dcore := xe.dcOrCreate()
@@ -121,6 +145,11 @@ func (xe *XmpEditor) SetKeywords(keywords []string) {
 	xe.dirty = true
 }
 
+// SetLegacyIptcDigest sets photoshop:LegacyIPTCDigest to the hex encoded md5 digest of the iptc data
+func (xe *XmpEditor) SetLegacyIptcDigest(digest string) error {
+	return xe.setPath(xmpPsLegacyIptcDigest, digest)
+}
+
 // SetRating sets the Base rating
 func (xe *XmpEditor) SetRating(rating uint16) {
 	base := xe.baseOrCreate()
@@ -135,25 +164,134 @@ func (xe *XmpEditor) SetTitle(title string) {
 	xe.dirty = true
 }
 
+// Set sets (and possibly creates) the value at path. See XmpData.Get for the path syntax
+func (xe *XmpEditor) Set(path string, value string) error {
+	if err := xmpPathNamespace(path); err != nil {
+		return err
+	}
+	return xe.setPath(path, value)
+}
+
+// SetArray replaces the array at path with values
+func (xe *XmpEditor) SetArray(path string, values []string) error {
+	if err := xmpPathNamespace(path); err != nil {
+		return err
+	}
+	return xe.setPathStrings(path, values)
+}
+
+// Delete removes the value (or array/struct) at path
+func (xe *XmpEditor) Delete(path string) error {
+	if err := xmpPathNamespace(path); err != nil {
+		return err
+	}
+	return xe.deletePath(path)
+}
+
+// setPath sets (and possibly creates) the value at path
+func (xe *XmpEditor) setPath(path string, value string) error {
+	err := xe.rawXmp.SetPath(xmp.PathValue{Path: xmp.Path(path), Value: value, Flags: xmp.CREATE | xmp.REPLACE})
+	if err != nil {
+		return err
+	}
+	xe.dirty = true
+	return nil
+}
+
+// deletePath removes the value (or array/struct) at path
+func (xe *XmpEditor) deletePath(path string) error {
+	err := xe.rawXmp.SetPath(xmp.PathValue{Path: xmp.Path(path), Flags: xmp.DELETE})
+	if err != nil {
+		return err
+	}
+	xe.dirty = true
+	return nil
+}
+
+// setPathStrings replaces the array at path with values
+func (xe *XmpEditor) setPathStrings(path string, values []string) error {
+	_ = xe.deletePath(path)
+	xe.dirty = true
+	for i, v := range values {
+		if err := xe.setPath(xmpItemPath(path, i), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setStruct writes all non empty fields of the struct at path
+func (xe *XmpEditor) setStruct(path string, fields []xmpField) error {
+	for _, f := range fields {
+		if *f.value == "" {
+			continue
+		}
+		if err := xe.setPath(path+"/"+f.name, *f.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (xe *XmpEditor) setSoftware() {
 	if !xe.IsDirty() {
 		return
 	}
 	t := time.Now()
+	agent := xe.writerOptions.agent(xmpEditorSoftware)
 	base := xe.baseOrCreate()
 	if base != nil {
-		base.CreatorTool = xmpEditorSoftware
+		if !xe.writerOptions.KeepSoftware {
+			base.CreatorTool = xmp.AgentName(agent)
+		}
 		base.ModifyDate = xmp.NewDate(t)
 	}
 	mm := xe.mmOrCreate()
 	if mm != nil {
+		xe.addHistory(mm, agent, t)
+	}
+	xe.dirty = false
+}
+
+// addHistory sets a new xmpMM:InstanceID (and a DocumentID if missing) and adds a saved event to xmpMM:History
+func (xe *XmpEditor) addHistory(mm *xmpmm.XmpMM, agent string, t time.Time) {
+	_ = xe.setPath(xmpMMInstanceID, newXmpID("xmp.iid"))
+	documentID := xe.getPathString(xmpMMDocumentID)
+	if documentID == "" {
+		documentID = newXmpID("xmp.did")
+		_ = xe.setPath(xmpMMDocumentID, documentID)
+	}
+	if xe.getPathString(xmpMMOriginalDocumentID) == "" {
+		_ = xe.setPath(xmpMMOriginalDocumentID, documentID)
+	}
+	maxHistory := xe.writerOptions.MaxHistory
+	if maxHistory < 0 {
+		return
+	}
+	coalesced := false
+	if n := len(mm.History); xe.writerOptions.CoalesceHistory && n > 0 {
+		last := mm.History[n-1]
+		if last.Action == xmpmm.ActionSaved && string(last.SoftwareAgent) == agent {
+			last.When = xmp.NewDate(t)
+			coalesced = true
+		}
+	}
+	if !coalesced {
 		re := xmpmm.ResourceEvent{}
 		re.Action = xmpmm.ActionSaved
-		re.SoftwareAgent = xmpEditorSoftware
+		re.SoftwareAgent = xmp.AgentName(agent)
 		re.When = xmp.NewDate(t)
 		mm.AddHistory(&re)
 	}
-	xe.dirty = false
+	//existing history is also capped
+	if maxHistory > 0 && len(mm.History) > maxHistory {
+		mm.History = mm.History[len(mm.History)-maxHistory:]
+	}
+}
+
+// SetWriterOptions sets the options used when edits are committed
+func (xe *XmpEditor) SetWriterOptions(opts WriterOptions) {
+	xe.writerOptions = opts
 }
 
 func (xe *XmpEditor) dcOrCreate() *dc.DublinCore {
@@ -1,6 +1,7 @@
 package metadata
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 	"trimmer.io/go-xmp/xmp"
@@ -241,3 +242,37 @@ func TestXmpEditor_SetTitle(t *testing.T) {
 		}
 	}
 }
+
+func TestXmpEditor_Set(t *testing.T) {
+	_ = RegisterXmpNamespace("mimageWorkflow", "http://github.com/msvens/mimage/workflow/1.0/")
+	je := getJpegEditor(LeicaImg, t)
+	xe := je.Xmp()
+	if err := xe.Set("mimageWorkflow:Status", "approved"); err != nil {
+		t.Fatalf("Could not set path: %v", err)
+	}
+	if err := xe.SetArray("mimageWorkflow:Reviewers", []string{"anna", "bob"}); err != nil {
+		t.Fatalf("Could not set path: %v", err)
+	}
+	if err := xe.Set("Iptc4xmpCore:CreatorContactInfo/CiEmailWork", "anna@example.com"); err != nil {
+		t.Fatalf("Could not set path: %v", err)
+	}
+	if err := xe.Set("status", "approved"); !errors.Is(err, ErrXmpPath) {
+		t.Errorf("Expected %v got %v", ErrXmpPath, err)
+	}
+	xe = reloadJpegEditor(je, true, t).Xmp()
+	if got, _ := xe.Get("mimageWorkflow:Status"); got != "approved" {
+		t.Errorf("Expected %v got %v", "approved", got)
+	}
+	if got, _ := xe.GetArray("mimageWorkflow:Reviewers"); !reflect.DeepEqual(got, []string{"anna", "bob"}) {
+		t.Errorf("Expected %v got %v", []string{"anna", "bob"}, got)
+	}
+	if got, _ := xe.Get("Iptc4xmpCore:CreatorContactInfo/CiEmailWork"); got != "anna@example.com" {
+		t.Errorf("Expected %v got %v", "anna@example.com", got)
+	}
+	if err := xe.Delete("mimageWorkflow:Status"); err != nil {
+		t.Fatalf("Could not delete path: %v", err)
+	}
+	if _, err := xe.Get("mimageWorkflow:Status"); err == nil {
+		t.Errorf("Expected error got nil")
+	}
+}
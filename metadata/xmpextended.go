@@ -0,0 +1,222 @@
+package metadata
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	jpegstructure "github.com/dsoprea/go-jpeg-image-structure/v2"
+	"sort"
+	"strings"
+	"trimmer.io/go-xmp/xmp"
+)
+
+/*
+Extended XMP, see part 3 of the XMP specification (https://github.com/adobe/XMP-Toolkit-SDK/tree/main/docs).
+Packets that do not fit in a single APP1 segment are split in a standard packet that points to an extended packet
+(xmpNote:HasExtendedXMP) that is stored in chunks in one or more "http://ns.adobe.com/xmp/extension/" segments
+*/
+
+// ErrXmpTooLarge when an xmp document can not be split in a standard and an extended packet
+var ErrXmpTooLarge = errors.New("XMP too large")
+
+var xmpExtensionPrefix = []byte("http://ns.adobe.com/xmp/extension/\000")
+
+const (
+	xmpNoteHasExtendedXmp = "xmpNote:HasExtendedXMP"
+	//maxSegmentData is the largest payload of a jpeg segment
+	maxSegmentData = 65533
+	//xmpGuidLen is the length of the hex encoded MD5 digest of the extended packet
+	xmpGuidLen = 32
+	//xmpExtensionHeaderLen is the prefix, guid, full length and offset of an extension segment
+	xmpExtensionHeaderLen = 35 + xmpGuidLen + 4 + 4
+)
+
+func init() {
+	registerXmpNamespace("xmpNote", "http://ns.adobe.com/xmp/note/")
+}
+
+func isXmpExtension(s *jpegstructure.Segment) bool {
+	return s.MarkerId == jpegstructure.MARKER_APP1 && bytes.HasPrefix(s.Data, xmpExtensionPrefix)
+}
+
+// xmpGuid returns the guid of an extended packet, i.e. its MD5 digest as upper case hex
+func xmpGuid(extended []byte) string {
+	sum := md5.Sum(extended)
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// readExtendedXmp reassembles the extended packet with guid. Chunks can be stored in any order. The full length
+// in the chunk headers is only trusted (and allocated) if it equals the size of the chunks that are present
+func readExtendedXmp(segments *jpegstructure.SegmentList, guid string) ([]byte, bool) {
+	var fullLength uint32
+	offsets := []uint32{}
+	chunks := [][]byte{}
+	received := 0
+	for _, s := range segments.Segments() {
+		if !isXmpExtension(s) || len(s.Data) < xmpExtensionHeaderLen {
+			continue
+		}
+		header := s.Data[len(xmpExtensionPrefix):]
+		if string(header[:xmpGuidLen]) != guid {
+			continue
+		}
+		length := binary.BigEndian.Uint32(header[xmpGuidLen:])
+		offset := binary.BigEndian.Uint32(header[xmpGuidLen+4:])
+		chunk := s.Data[xmpExtensionHeaderLen:]
+		if len(chunks) > 0 && length != fullLength {
+			return nil, false
+		}
+		if uint64(offset)+uint64(len(chunk)) > uint64(length) {
+			return nil, false
+		}
+		fullLength = length
+		offsets = append(offsets, offset)
+		chunks = append(chunks, chunk)
+		received += len(chunk)
+	}
+	if len(chunks) == 0 || uint64(received) != uint64(fullLength) {
+		return nil, false
+	}
+	ret := make([]byte, fullLength)
+	for i, chunk := range chunks {
+		copy(ret[offsets[i]:], chunk)
+	}
+	if xmpGuid(ret) != guid {
+		return nil, false
+	}
+	return ret, true
+}
+
+// mergeExtendedXmp merges the extended packet (if any) into the standard xmp document
+func (xd XmpData) mergeExtendedXmp(segments *jpegstructure.SegmentList) {
+	guid := xd.getPathString(xmpNoteHasExtendedXmp)
+	if guid == "" {
+		return
+	}
+	//the document should hold all properties so the guid is only kept if the extended packet is missing
+	extended, found := readExtendedXmp(segments, guid)
+	if !found {
+		return
+	}
+	doc := &xmp.Document{}
+	if err := xmp.Unmarshal(extended, doc); err != nil {
+		return
+	}
+	if err := xd.rawXmp.Merge(doc, xmp.CREATE|xmp.REPLACE); err != nil {
+		return
+	}
+	_ = xd.rawXmp.SetPath(xmp.PathValue{Path: xmpNoteHasExtendedXmp, Flags: xmp.DELETE})
+}
+
+// xmpTopProperty returns the top level property of path, e.g. dc:subject for dc:subject[0]
+func xmpTopProperty(path string) string {
+	if i := strings.IndexAny(path, "/["); i > 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// splitXmp splits doc in a standard packet that fits in a single segment (together with overhead bytes) and an
+// extended packet. The largest top level properties are moved to the extended packet. If doc fits in one segment
+// extended is nil. doc is not modified
+func splitXmp(doc *xmp.Document, overhead int) (standard []byte, extended []byte, err error) {
+	//a stale guid is dropped from a copy so that the caller's document is left as is
+	if standard, err = xmp.Marshal(doc); err != nil {
+		return nil, nil, err
+	}
+	copyDoc := &xmp.Document{}
+	if err = xmp.Unmarshal(standard, copyDoc); err != nil {
+		return nil, nil, err
+	}
+	_ = copyDoc.SetPath(xmp.PathValue{Path: xmpNoteHasExtendedXmp, Flags: xmp.DELETE})
+	if standard, err = xmp.Marshal(copyDoc); err != nil || overhead+len(standard) <= maxSegmentData {
+		return standard, nil, err
+	}
+	pvs, err := copyDoc.ListPaths()
+	if err != nil {
+		return nil, nil, err
+	}
+	sizes := map[string]int{}
+	for _, pv := range pvs {
+		sizes[xmpTopProperty(pv.Path.String())] += len(pv.Path) + len(pv.Value)
+	}
+	props := make([]string, 0, len(sizes))
+	for p := range sizes {
+		props = append(props, p)
+	}
+	sort.Slice(props, func(i, j int) bool {
+		if sizes[props[i]] != sizes[props[j]] {
+			return sizes[props[i]] > sizes[props[j]]
+		}
+		return props[i] < props[j]
+	})
+	for n := 1; n <= len(props); n++ {
+		moved := map[string]bool{}
+		for _, p := range props[:n] {
+			moved[p] = true
+		}
+		extDoc := xmp.NewDocument()
+		for _, pv := range pvs {
+			if moved[xmpTopProperty(pv.Path.String())] {
+				if err = extDoc.SetPath(xmp.PathValue{Path: pv.Path, Value: pv.Value, Flags: xmp.CREATE | xmp.REPLACE}); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+		if extended, err = xmp.Marshal(extDoc); err != nil {
+			return nil, nil, err
+		}
+		stdDoc := &xmp.Document{}
+		if err = xmp.Unmarshal(standard, stdDoc); err != nil {
+			return nil, nil, err
+		}
+		for p := range moved {
+			_ = stdDoc.SetPath(xmp.PathValue{Path: xmp.Path(p), Flags: xmp.DELETE})
+		}
+		if err = stdDoc.SetPath(xmp.PathValue{Path: xmpNoteHasExtendedXmp, Value: xmpGuid(extended), Flags: xmp.CREATE | xmp.REPLACE}); err != nil {
+			return nil, nil, err
+		}
+		std, err := xmp.Marshal(stdDoc)
+		if err != nil {
+			return nil, nil, err
+		}
+		if overhead+len(std) <= maxSegmentData {
+			return std, extended, nil
+		}
+	}
+	return nil, nil, ErrXmpTooLarge
+}
+
+// xmpExtensionSegments splits the extended packet in APP1 segments
+func xmpExtensionSegments(extended []byte) []*jpegstructure.Segment {
+	guid := xmpGuid(extended)
+	ret := []*jpegstructure.Segment{}
+	maxChunk := maxSegmentData - xmpExtensionHeaderLen
+	for offset := 0; offset < len(extended); offset += maxChunk {
+		end := offset + maxChunk
+		if end > len(extended) {
+			end = len(extended)
+		}
+		data := make([]byte, 0, xmpExtensionHeaderLen+end-offset)
+		data = append(data, xmpExtensionPrefix...)
+		data = append(data, guid...)
+		data = binary.BigEndian.AppendUint32(data, uint32(len(extended)))
+		data = binary.BigEndian.AppendUint32(data, uint32(offset))
+		data = append(data, extended[offset:end]...)
+		ret = append(ret, &jpegstructure.Segment{MarkerId: jpegstructure.MARKER_APP1, Data: data})
+	}
+	return ret
+}
+
+// dropXmpExtensions removes all extended xmp segments
+func dropXmpExtensions(sl *jpegstructure.SegmentList) *jpegstructure.SegmentList {
+	segments := []*jpegstructure.Segment{}
+	for _, s := range sl.Segments() {
+		if !isXmpExtension(s) {
+			segments = append(segments, s)
+		}
+	}
+	return jpegstructure.NewSegmentList(segments)
+}
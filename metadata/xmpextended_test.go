@@ -0,0 +1,112 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	jpegstructure "github.com/dsoprea/go-jpeg-image-structure/v2"
+	"strings"
+	"testing"
+)
+
+func init() {
+	registerXmpNamespace("mimageTest", "http://github.com/msvens/mimage/test/1.0/")
+}
+
+func countXmpExtensions(sl *jpegstructure.SegmentList) int {
+	ret := 0
+	for _, s := range sl.Segments() {
+		if isXmpExtension(s) {
+			ret++
+		}
+	}
+	return ret
+}
+
+func TestJpegEditor_ExtendedXmp(t *testing.T) {
+	je := getJpegEditor(LeicaImg, t)
+	history := strings.Repeat("0123456789", 15000)
+	if err := je.Xmp().setPath("mimageTest:History", history); err != nil {
+		t.Fatalf("Could not set path: %v", err)
+	}
+	je.Xmp().SetTitle("extended")
+	je = reloadJpegEditor(je, true, t)
+
+	if n := countXmpExtensions(je.sl); n != 3 {
+		t.Errorf("Expected %v got %v", 3, n)
+	}
+	_, s, err := je.sl.FindXmp()
+	if err != nil {
+		t.Fatalf("Could not find xmp: %v", err)
+	}
+	if len(s.Data) > maxSegmentData || bytes.Contains(s.Data, []byte(history)) {
+		t.Errorf("Expected History to be moved to the extended xmp")
+	}
+	if got := je.Xmp().getPathString("mimageTest:History"); got != history {
+		t.Errorf("Expected %v got %v", len(history), len(got))
+	}
+	if got := je.Xmp().GetTitle(); got != "extended" {
+		t.Errorf("Expected %v got %v", "extended", got)
+	}
+	if got := je.Xmp().getPathString(xmpNoteHasExtendedXmp); got != "" {
+		t.Errorf("Expected %v got %v", "", got)
+	}
+
+	//rewriting should replace the extension segments
+	je.Xmp().SetDirty()
+	je = reloadJpegEditor(je, true, t)
+	if n := countXmpExtensions(je.sl); n != 3 {
+		t.Errorf("Expected %v got %v", 3, n)
+	}
+	//and they should be removed when no longer needed
+	_ = je.Xmp().deletePath("mimageTest:History")
+	je = reloadJpegEditor(je, true, t)
+	if n := countXmpExtensions(je.sl); n != 0 {
+		t.Errorf("Expected %v got %v", 0, n)
+	}
+}
+
+func TestReadExtendedXmp(t *testing.T) {
+	extended := []byte(strings.Repeat("extended xmp ", 10000))
+	guid := xmpGuid(extended)
+	segments := xmpExtensionSegments(extended)
+	if len(segments) != 2 {
+		t.Fatalf("Expected %v got %v", 2, len(segments))
+	}
+	//chunks can be in any order
+	sl := jpegstructure.NewSegmentList([]*jpegstructure.Segment{segments[1], segments[0]})
+	if got, found := readExtendedXmp(sl, guid); !found || !bytes.Equal(extended, got) {
+		t.Errorf("Expected extended xmp to be reassembled")
+	}
+	if _, found := readExtendedXmp(sl, xmpGuid([]byte("other"))); found {
+		t.Errorf("Expected %v got %v", false, found)
+	}
+	//missing chunk
+	sl = jpegstructure.NewSegmentList(segments[:1])
+	if _, found := readExtendedXmp(sl, guid); found {
+		t.Errorf("Expected %v got %v", false, found)
+	}
+	//a full length larger than the chunks present should be rejected (and not allocated)
+	huge := append([]byte{}, segments[0].Data...)
+	binary.BigEndian.PutUint32(huge[len(xmpExtensionPrefix)+xmpGuidLen:], 0xffffffff)
+	sl = jpegstructure.NewSegmentList([]*jpegstructure.Segment{{MarkerId: jpegstructure.MARKER_APP1, Data: huge}})
+	if _, found := readExtendedXmp(sl, guid); found {
+		t.Errorf("Expected %v got %v", false, found)
+	}
+}
+
+func TestSplitXmp_KeepsDocument(t *testing.T) {
+	xe := getJpegEditor(LeicaImg, t).Xmp()
+	if err := xe.setPath(xmpNoteHasExtendedXmp, xmpGuid([]byte("stale"))); err != nil {
+		t.Fatalf("Could not set path: %v", err)
+	}
+	standard, extended, err := splitXmp(xe.rawXmp, 0)
+	if err != nil || extended != nil {
+		t.Fatalf("Expected a single packet got %v", err)
+	}
+	if bytes.Contains(standard, []byte("HasExtendedXMP")) {
+		t.Errorf("Expected the stale guid to be dropped from the packet")
+	}
+	if got := xe.getPathString(xmpNoteHasExtendedXmp); got != xmpGuid([]byte("stale")) {
+		t.Errorf("Expected %v got %v", xmpGuid([]byte("stale")), got)
+	}
+}
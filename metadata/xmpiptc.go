@@ -0,0 +1,256 @@
+package metadata
+
+/*
+Typed access to the IPTC Core (Iptc4xmpCore) and IPTC Extension (Iptc4xmpExt) xmp schemas as well as
+the PLUS licensor. See https://www.iptc.org/std/photometadata/specification/IPTC-PhotoMetadata
+*/
+
+const (
+	iptcCoreContactInfo    = "Iptc4xmpCore:CreatorContactInfo"
+	iptcCoreLocation       = "Iptc4xmpCore:Location"
+	iptcCoreScene          = "Iptc4xmpCore:Scene"
+	iptcCoreSubjectCode    = "Iptc4xmpCore:SubjectCode"
+	iptcExtPersonInImage   = "Iptc4xmpExt:PersonInImage"
+	iptcExtLocationShown   = "Iptc4xmpExt:LocationShown"
+	iptcExtLocationCreated = "Iptc4xmpExt:LocationCreated"
+	iptcExtArtworkOrObject = "Iptc4xmpExt:ArtworkOrObject"
+	iptcExtAOCreator       = "Iptc4xmpExt:AOCreator"
+	plusLicensor           = "plus:Licensor"
+)
+
+// ContactInfo holds the IPTC Core creator contact info
+type ContactInfo struct {
+	Address    string `json:"address,omitempty"`
+	City       string `json:"city,omitempty"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
+	Country    string `json:"country,omitempty"`
+	Phone      string `json:"phone,omitempty"`
+	Email      string `json:"email,omitempty"`
+	URL        string `json:"url,omitempty"`
+}
+
+func (ci *ContactInfo) xmpFields() []xmpField {
+	return []xmpField{
+		{"Iptc4xmpCore:CiAdrExtadr", &ci.Address},
+		{"Iptc4xmpCore:CiAdrCity", &ci.City},
+		{"Iptc4xmpCore:CiAdrRegion", &ci.Region},
+		{"Iptc4xmpCore:CiAdrPcode", &ci.PostalCode},
+		{"Iptc4xmpCore:CiAdrCtry", &ci.Country},
+		{"Iptc4xmpCore:CiTelWork", &ci.Phone},
+		{"Iptc4xmpCore:CiEmailWork", &ci.Email},
+		{"Iptc4xmpCore:CiUrlWork", &ci.URL},
+	}
+}
+
+// Location holds an IPTC Extension location (used by LocationShown and LocationCreated)
+type Location struct {
+	Sublocation   string `json:"sublocation,omitempty"`
+	City          string `json:"city,omitempty"`
+	ProvinceState string `json:"provinceState,omitempty"`
+	CountryName   string `json:"countryName,omitempty"`
+	CountryCode   string `json:"countryCode,omitempty"`
+	WorldRegion   string `json:"worldRegion,omitempty"`
+}
+
+func (l *Location) xmpFields() []xmpField {
+	return []xmpField{
+		{"Iptc4xmpExt:Sublocation", &l.Sublocation},
+		{"Iptc4xmpExt:City", &l.City},
+		{"Iptc4xmpExt:ProvinceState", &l.ProvinceState},
+		{"Iptc4xmpExt:CountryName", &l.CountryName},
+		{"Iptc4xmpExt:CountryCode", &l.CountryCode},
+		{"Iptc4xmpExt:WorldRegion", &l.WorldRegion},
+	}
+}
+
+// ArtworkOrObject holds an IPTC Extension artwork or object in the image
+type ArtworkOrObject struct {
+	Title           string   `json:"title,omitempty"`
+	Creator         []string `json:"creator,omitempty"`
+	DateCreated     string   `json:"dateCreated,omitempty"`
+	Source          string   `json:"source,omitempty"`
+	SourceInvNo     string   `json:"sourceInvNo,omitempty"`
+	CopyrightNotice string   `json:"copyrightNotice,omitempty"`
+}
+
+func (ao *ArtworkOrObject) xmpFields() []xmpField {
+	return []xmpField{
+		{xmpLangPath("Iptc4xmpExt:AOTitle", ""), &ao.Title},
+		{"Iptc4xmpExt:AODateCreated", &ao.DateCreated},
+		{"Iptc4xmpExt:AOSource", &ao.Source},
+		{"Iptc4xmpExt:AOSourceInvNo", &ao.SourceInvNo},
+		{"Iptc4xmpExt:AOCopyrightNotice", &ao.CopyrightNotice},
+	}
+}
+
+// Licensor holds a PLUS licensor
+type Licensor struct {
+	ID    string `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+func (l *Licensor) xmpFields() []xmpField {
+	return []xmpField{
+		{"plus:LicensorID", &l.ID},
+		{"plus:LicensorName", &l.Name},
+		{"plus:LicensorEmail", &l.Email},
+		{"plus:LicensorURL", &l.URL},
+		{"plus:LicensorTelephone1", &l.Phone},
+	}
+}
+
+func (xd XmpData) scanLocations(path string) []Location {
+	ret := []Location{}
+	n := xd.countPath(path)
+	for i := 0; i < n; i++ {
+		l := Location{}
+		xd.scanStruct(xmpItemPath(path, i), l.xmpFields())
+		ret = append(ret, l)
+	}
+	return ret
+}
+
+func (xe *XmpEditor) setLocations(path string, locations []Location) error {
+	_ = xe.deletePath(path)
+	xe.dirty = true
+	for i := range locations {
+		if err := xe.setStruct(xmpItemPath(path, i), locations[i].xmpFields()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCreatorContactInfo returns Iptc4xmpCore:CreatorContactInfo
+func (xd XmpData) GetCreatorContactInfo() ContactInfo {
+	ret := ContactInfo{}
+	xd.scanStruct(iptcCoreContactInfo, ret.xmpFields())
+	return ret
+}
+
+// GetIptcLocation returns Iptc4xmpCore:Location (the sublocation the image was taken in)
+func (xd XmpData) GetIptcLocation() string {
+	return xd.getPathString(iptcCoreLocation)
+}
+
+// GetScenes returns the Iptc4xmpCore:Scene codes
+func (xd XmpData) GetScenes() []string {
+	return xd.getPathStrings(iptcCoreScene)
+}
+
+// GetSubjectCodes returns the Iptc4xmpCore:SubjectCode codes
+func (xd XmpData) GetSubjectCodes() []string {
+	return xd.getPathStrings(iptcCoreSubjectCode)
+}
+
+// GetPersonsInImage returns Iptc4xmpExt:PersonInImage
+func (xd XmpData) GetPersonsInImage() []string {
+	return xd.getPathStrings(iptcExtPersonInImage)
+}
+
+// GetLocationsShown returns Iptc4xmpExt:LocationShown
+func (xd XmpData) GetLocationsShown() []Location {
+	return xd.scanLocations(iptcExtLocationShown)
+}
+
+// GetLocationsCreated returns Iptc4xmpExt:LocationCreated
+func (xd XmpData) GetLocationsCreated() []Location {
+	return xd.scanLocations(iptcExtLocationCreated)
+}
+
+// GetArtworksOrObjects returns Iptc4xmpExt:ArtworkOrObject
+func (xd XmpData) GetArtworksOrObjects() []ArtworkOrObject {
+	ret := []ArtworkOrObject{}
+	n := xd.countPath(iptcExtArtworkOrObject)
+	for i := 0; i < n; i++ {
+		item := xmpItemPath(iptcExtArtworkOrObject, i)
+		ao := ArtworkOrObject{}
+		xd.scanStruct(item, ao.xmpFields())
+		ao.Creator = xd.getPathStrings(item + "/" + iptcExtAOCreator)
+		ret = append(ret, ao)
+	}
+	return ret
+}
+
+// GetLicensors returns plus:Licensor
+func (xd XmpData) GetLicensors() []Licensor {
+	ret := []Licensor{}
+	n := xd.countPath(plusLicensor)
+	for i := 0; i < n; i++ {
+		l := Licensor{}
+		xd.scanStruct(xmpItemPath(plusLicensor, i), l.xmpFields())
+		ret = append(ret, l)
+	}
+	return ret
+}
+
+// SetCreatorContactInfo replaces Iptc4xmpCore:CreatorContactInfo
+func (xe *XmpEditor) SetCreatorContactInfo(ci ContactInfo) error {
+	_ = xe.deletePath(iptcCoreContactInfo)
+	xe.dirty = true
+	return xe.setStruct(iptcCoreContactInfo, ci.xmpFields())
+}
+
+// SetIptcLocation sets Iptc4xmpCore:Location
+func (xe *XmpEditor) SetIptcLocation(location string) error {
+	return xe.setPath(iptcCoreLocation, location)
+}
+
+// SetScenes replaces the Iptc4xmpCore:Scene codes
+func (xe *XmpEditor) SetScenes(scenes []string) error {
+	return xe.setPathStrings(iptcCoreScene, scenes)
+}
+
+// SetSubjectCodes replaces the Iptc4xmpCore:SubjectCode codes
+func (xe *XmpEditor) SetSubjectCodes(codes []string) error {
+	return xe.setPathStrings(iptcCoreSubjectCode, codes)
+}
+
+// SetPersonsInImage replaces Iptc4xmpExt:PersonInImage
+func (xe *XmpEditor) SetPersonsInImage(persons []string) error {
+	return xe.setPathStrings(iptcExtPersonInImage, persons)
+}
+
+// SetLocationsShown replaces Iptc4xmpExt:LocationShown
+func (xe *XmpEditor) SetLocationsShown(locations []Location) error {
+	return xe.setLocations(iptcExtLocationShown, locations)
+}
+
+// SetLocationsCreated replaces Iptc4xmpExt:LocationCreated
+func (xe *XmpEditor) SetLocationsCreated(locations []Location) error {
+	return xe.setLocations(iptcExtLocationCreated, locations)
+}
+
+// SetArtworksOrObjects replaces Iptc4xmpExt:ArtworkOrObject
+func (xe *XmpEditor) SetArtworksOrObjects(artworks []ArtworkOrObject) error {
+	_ = xe.deletePath(iptcExtArtworkOrObject)
+	xe.dirty = true
+	for i := range artworks {
+		item := xmpItemPath(iptcExtArtworkOrObject, i)
+		if err := xe.setStruct(item, artworks[i].xmpFields()); err != nil {
+			return err
+		}
+		if len(artworks[i].Creator) > 0 {
+			if err := xe.setPathStrings(item+"/"+iptcExtAOCreator, artworks[i].Creator); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetLicensors replaces plus:Licensor
+func (xe *XmpEditor) SetLicensors(licensors []Licensor) error {
+	_ = xe.deletePath(plusLicensor)
+	xe.dirty = true
+	for i := range licensors {
+		if err := xe.setStruct(xmpItemPath(plusLicensor, i), licensors[i].xmpFields()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
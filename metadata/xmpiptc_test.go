@@ -0,0 +1,101 @@
+package metadata
+
+import (
+	"reflect"
+	"testing"
+	"trimmer.io/go-xmp/xmp"
+)
+
+func reloadXmpEditor(xe *XmpEditor, t *testing.T) XmpData {
+	b, err := xe.Bytes(false)
+	if err != nil {
+		t.Fatalf("Could not write xmp: %v", err)
+	}
+	xd, err := NewXmpDataFromBytes(b)
+	if err != nil {
+		t.Fatalf("Could not read xmp: %v", err)
+	}
+	return xd
+}
+
+func TestXmpEditor_SetCreatorContactInfo(t *testing.T) {
+	exp := ContactInfo{City: "Stockholm", Country: "Sweden", Email: "info@example.com", URL: "https://example.com"}
+	xe, _ := NewXmpEditorFromDocument(xmp.NewDocument())
+	if err := xe.SetCreatorContactInfo(exp); err != nil {
+		t.Fatalf("Could not set contact info: %v", err)
+	}
+	if !xe.IsDirty() {
+		t.Errorf("Expected dirty xmp editor")
+	}
+	xd := reloadXmpEditor(xe, t)
+	if act := xd.GetCreatorContactInfo(); act != exp {
+		t.Errorf("Expected %v got %v", exp, act)
+	}
+}
+
+func TestXmpEditor_SetIptcCore(t *testing.T) {
+	expScenes := []string{"011900", "011100"}
+	expSubjects := []string{"15000000"}
+	expLocation := "Gamla Stan"
+	xe, _ := NewXmpEditorFromDocument(xmp.NewDocument())
+	if err := xe.SetScenes(expScenes); err != nil {
+		t.Fatalf("Could not set scenes: %v", err)
+	}
+	if err := xe.SetSubjectCodes(expSubjects); err != nil {
+		t.Fatalf("Could not set subject codes: %v", err)
+	}
+	if err := xe.SetIptcLocation(expLocation); err != nil {
+		t.Fatalf("Could not set location: %v", err)
+	}
+	xd := reloadXmpEditor(xe, t)
+	if act := xd.GetScenes(); !reflect.DeepEqual(act, expScenes) {
+		t.Errorf("Expected %v got %v", expScenes, act)
+	}
+	if act := xd.GetSubjectCodes(); !reflect.DeepEqual(act, expSubjects) {
+		t.Errorf("Expected %v got %v", expSubjects, act)
+	}
+	if act := xd.GetIptcLocation(); act != expLocation {
+		t.Errorf("Expected %v got %v", expLocation, act)
+	}
+}
+
+func TestXmpEditor_SetIptcExt(t *testing.T) {
+	expPersons := []string{"Anna", "Bertil"}
+	expShown := []Location{{City: "Stockholm", CountryName: "Sweden", CountryCode: "SWE"}, {City: "Uppsala"}}
+	expCreated := []Location{{Sublocation: "Södermalm", City: "Stockholm"}}
+	expArtworks := []ArtworkOrObject{{Title: "Mona Lisa", Creator: []string{"Leonardo da Vinci"}, Source: "Louvre"}}
+	expLicensors := []Licensor{{ID: "1234", Name: "Agency", URL: "https://example.com/license"}}
+
+	xe, _ := NewXmpEditorFromDocument(xmp.NewDocument())
+	if err := xe.SetPersonsInImage(expPersons); err != nil {
+		t.Fatalf("Could not set persons in image: %v", err)
+	}
+	if err := xe.SetLocationsShown(expShown); err != nil {
+		t.Fatalf("Could not set locations shown: %v", err)
+	}
+	if err := xe.SetLocationsCreated(expCreated); err != nil {
+		t.Fatalf("Could not set locations created: %v", err)
+	}
+	if err := xe.SetArtworksOrObjects(expArtworks); err != nil {
+		t.Fatalf("Could not set artworks: %v", err)
+	}
+	if err := xe.SetLicensors(expLicensors); err != nil {
+		t.Fatalf("Could not set licensors: %v", err)
+	}
+	xd := reloadXmpEditor(xe, t)
+	if act := xd.GetPersonsInImage(); !reflect.DeepEqual(act, expPersons) {
+		t.Errorf("Expected %v got %v", expPersons, act)
+	}
+	if act := xd.GetLocationsShown(); !reflect.DeepEqual(act, expShown) {
+		t.Errorf("Expected %v got %v", expShown, act)
+	}
+	if act := xd.GetLocationsCreated(); !reflect.DeepEqual(act, expCreated) {
+		t.Errorf("Expected %v got %v", expCreated, act)
+	}
+	if act := xd.GetArtworksOrObjects(); !reflect.DeepEqual(act, expArtworks) {
+		t.Errorf("Expected %v got %v", expArtworks, act)
+	}
+	if act := xd.GetLicensors(); !reflect.DeepEqual(act, expLicensors) {
+		t.Errorf("Expected %v got %v", expLicensors, act)
+	}
+}
@@ -0,0 +1,184 @@
+package metadata
+
+import (
+	"errors"
+	"golang.org/x/text/language"
+	"sort"
+	"trimmer.io/go-xmp/xmp"
+)
+
+// ErrXmpLang when a language is not a valid BCP 47 tag
+var ErrXmpLang = errors.New("Invalid XMP language")
+
+// XmpDefaultLang is the language of the default item in a language alternative
+const XmpDefaultLang = "x-default"
+
+// normalizeLang returns the canonical form of lang or "" for the default language
+func normalizeLang(lang string) (string, error) {
+	if lang == "" || lang == XmpDefaultLang {
+		return "", nil
+	}
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return "", ErrXmpLang
+	}
+	return tag.String(), nil
+}
+
+// altLangs returns the items of a language alternative keyed by language. The default item is keyed by x-default
+// (and its language if it has one)
+func altLangs(alt xmp.AltString) map[string]string {
+	ret := map[string]string{}
+	for _, item := range alt {
+		if item.IsDefault || item.Lang == "" || item.Lang == XmpDefaultLang {
+			ret[XmpDefaultLang] = item.Value
+		}
+		if item.Lang != "" && item.Lang != XmpDefaultLang {
+			ret[item.Lang] = item.Value
+		}
+	}
+	return ret
+}
+
+// setAltLang sets the value of lang in alt. The first value added also becomes the default. An empty value removes
+// lang
+func setAltLang(alt *xmp.AltString, lang, value string) error {
+	lang, err := normalizeLang(lang)
+	if err != nil {
+		return err
+	}
+	switch {
+	case value == "":
+		if lang == "" {
+			lang = XmpDefaultLang
+		}
+		alt.RemoveLang(lang)
+	case lang == "":
+		alt.Set("", value)
+	case len(*alt) == 0:
+		alt.AddDefault(lang, value)
+	default:
+		alt.Set(lang, value)
+	}
+	return nil
+}
+
+// matchLang returns the value in alts whose language best matches the preferred languages (BCP 47 tags). Returns
+// fallback if no language matches
+func matchLang(alts map[string]string, fallback string, preferred []string) string {
+	keys := []string{}
+	for k := range alts {
+		if k != XmpDefaultLang {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	supported := []language.Tag{}
+	langs := []string{}
+	for _, k := range keys {
+		if tag, err := language.Parse(k); err == nil {
+			supported = append(supported, tag)
+			langs = append(langs, k)
+		}
+	}
+	prefs := []language.Tag{}
+	for _, p := range preferred {
+		if tag, err := language.Parse(p); err == nil {
+			prefs = append(prefs, tag)
+		}
+	}
+	if len(supported) == 0 || len(prefs) == 0 {
+		return fallback
+	}
+	_, idx, conf := language.NewMatcher(supported).Match(prefs...)
+	if conf == language.No {
+		return fallback
+	}
+	return alts[langs[idx]]
+}
+
+// Titles returns the DublinCore titles keyed by language
+func (xd XmpData) Titles() map[string]string {
+	if dcore := xd.DublinCore(); dcore != nil {
+		return altLangs(dcore.Title)
+	}
+	return map[string]string{}
+}
+
+// Descriptions returns the DublinCore descriptions keyed by language
+func (xd XmpData) Descriptions() map[string]string {
+	if dcore := xd.DublinCore(); dcore != nil {
+		return altLangs(dcore.Description)
+	}
+	return map[string]string{}
+}
+
+// Copyrights returns the DublinCore rights (copyright notices) keyed by language
+func (xd XmpData) Copyrights() map[string]string {
+	if dcore := xd.DublinCore(); dcore != nil {
+		return altLangs(dcore.Rights)
+	}
+	return map[string]string{}
+}
+
+// SetTitleLang sets the DublinCore title in lang (a BCP 47 tag or x-default). An empty title removes lang
+func (xe *XmpEditor) SetTitleLang(lang, title string) error {
+	if err := setAltLang(&xe.dcOrCreate().Title, lang, title); err != nil {
+		return err
+	}
+	xe.dirty = true
+	return nil
+}
+
+// SetDescriptionLang sets the DublinCore description in lang (a BCP 47 tag or x-default). An empty description
+// removes lang
+func (xe *XmpEditor) SetDescriptionLang(lang, description string) error {
+	if err := setAltLang(&xe.dcOrCreate().Description, lang, description); err != nil {
+		return err
+	}
+	xe.dirty = true
+	return nil
+}
+
+// SetCopyrightLang sets the DublinCore rights in lang (a BCP 47 tag or x-default). An empty copyright removes lang
+func (xe *XmpEditor) SetCopyrightLang(lang, copyright string) error {
+	if err := setAltLang(&xe.dcOrCreate().Rights, lang, copyright); err != nil {
+		return err
+	}
+	xe.dirty = true
+	return nil
+}
+
+// TitleLang returns the title in the language that best matches the preferred languages (BCP 47 tags, e.g. "sv" or
+// "en-GB"). Returns Title if no language matches
+func (ec Summary) TitleLang(preferred ...string) string {
+	return matchLang(ec.Titles, ec.Title, preferred)
+}
+
+// DescriptionLang returns the description in the language that best matches the preferred languages (BCP 47 tags).
+// Returns Description if no language matches
+func (ec Summary) DescriptionLang(preferred ...string) string {
+	return matchLang(ec.Descriptions, ec.Description, preferred)
+}
+
+// SetTitleLang sets the title in lang. The default language (x-default or "") is set in both Xmp and Iptc, other
+// languages only in Xmp
+func (je *JpegEditor) SetTitleLang(lang, title string) error {
+	if l, err := normalizeLang(lang); err != nil {
+		return err
+	} else if l == "" {
+		return je.SetTitle(title)
+	}
+	return je.Xmp().SetTitleLang(lang, title)
+}
+
+// SetDescriptionLang sets the description in lang. The default language (x-default or "") is set in Xmp, Iptc and
+// Exif, other languages only in Xmp
+func (je *JpegEditor) SetDescriptionLang(lang, description string) error {
+	if l, err := normalizeLang(lang); err != nil {
+		return err
+	} else if l == "" {
+		return je.SetDescription(description)
+	}
+	return je.Xmp().SetDescriptionLang(lang, description)
+}
@@ -0,0 +1,87 @@
+package metadata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchLang(t *testing.T) {
+	alts := map[string]string{XmpDefaultLang: "default", "sv": "svenska", "en-GB": "english", "pt-BR": "português"}
+	tests := []struct {
+		preferred []string
+		exp       string
+	}{
+		{[]string{"sv-SE"}, "svenska"},
+		{[]string{"en-US"}, "english"},
+		{[]string{"de", "en"}, "english"},
+		{[]string{"pt"}, "português"},
+		{[]string{"de"}, "fallback"},
+		{[]string{"not a language"}, "fallback"},
+		{nil, "fallback"},
+	}
+	for _, test := range tests {
+		if got := matchLang(alts, "fallback", test.preferred); got != test.exp {
+			t.Errorf("Expected %v got %v for %v", test.exp, got, test.preferred)
+		}
+	}
+	if got := matchLang(map[string]string{}, "fallback", []string{"sv"}); got != "fallback" {
+		t.Errorf("Expected %v got %v", "fallback", got)
+	}
+}
+
+func TestJpegEditor_SetTitleLang(t *testing.T) {
+	je := getJpegEditor(LeicaImg, t)
+	if err := je.SetTitleLang("sv", "Morgondimma"); err != nil {
+		t.Fatalf("Could not set title: %v", err)
+	}
+	if err := je.SetTitleLang("en-gb", "Morning mist"); err != nil {
+		t.Fatalf("Could not set title: %v", err)
+	}
+	if err := je.SetDescriptionLang("sv", "Dimma över sjön"); err != nil {
+		t.Fatalf("Could not set description: %v", err)
+	}
+	if err := je.SetTitleLang("not a language", "title"); err != ErrXmpLang {
+		t.Errorf("Expected %v got %v", ErrXmpLang, err)
+	}
+	je = reloadJpegEditor(je, true, t)
+	exp := map[string]string{XmpDefaultLang: "Morning Fog", "sv": "Morgondimma", "en-GB": "Morning mist"}
+	if got := je.Xmp().Titles(); !reflect.DeepEqual(exp, got) {
+		t.Errorf("Expected %v got %v", exp, got)
+	}
+	summary := jpegEditorMD(je, t).Summary()
+	if got := summary.TitleLang("sv-SE"); got != "Morgondimma" {
+		t.Errorf("Expected %v got %v", "Morgondimma", got)
+	}
+	if got := summary.TitleLang("en-US"); got != "Morning mist" {
+		t.Errorf("Expected %v got %v", "Morning mist", got)
+	}
+	if got := summary.TitleLang("de"); got != summary.Title {
+		t.Errorf("Expected %v got %v", summary.Title, got)
+	}
+	if got := summary.DescriptionLang("sv"); got != "Dimma över sjön" {
+		t.Errorf("Expected %v got %v", "Dimma över sjön", got)
+	}
+	//empty values remove the language
+	if err := je.Xmp().SetTitleLang("sv", ""); err != nil {
+		t.Fatalf("Could not remove title: %v", err)
+	}
+	if _, found := je.Xmp().Titles()["sv"]; found {
+		t.Errorf("Expected sv title to be removed")
+	}
+}
+
+func TestXmpEditor_SetCopyrightLang(t *testing.T) {
+	je := getJpegEditor(NoExifImg, t)
+	if err := je.Xmp().SetCopyrightLang("sv", "© Fotograf"); err != nil {
+		t.Fatalf("Could not set copyright: %v", err)
+	}
+	if err := je.Xmp().SetCopyrightLang("en", "© Photographer"); err != nil {
+		t.Fatalf("Could not set copyright: %v", err)
+	}
+	je = reloadJpegEditor(je, true, t)
+	//the first language is also the default
+	exp := map[string]string{XmpDefaultLang: "© Fotograf", "sv": "© Fotograf", "en": "© Photographer"}
+	if got := je.Xmp().Copyrights(); !reflect.DeepEqual(exp, got) {
+		t.Errorf("Expected %v got %v", exp, got)
+	}
+}
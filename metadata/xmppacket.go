@@ -0,0 +1,94 @@
+package metadata
+
+import (
+	"bytes"
+)
+
+/*
+The xpacket wrapper and padding, see part 1 of the XMP specification. Padding allows tools to edit the packet in
+place without moving the rest of the file
+*/
+
+// DefaultXmpPadding is the amount of padding (in bytes) added to embedded xmp packets
+const DefaultXmpPadding = 2048
+
+const (
+	xpacketBegin       = "<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n"
+	xpacketEndWritable = "<?xpacket end=\"w\"?>"
+	xpacketEndReadOnly = "<?xpacket end=\"r\"?>"
+	xpacketPaddingLine = 100
+)
+
+// XmpPacketOptions controls the xpacket wrapper written around embedded xmp
+type XmpPacketOptions struct {
+	//Padding is the amount of whitespace added to the packet. 0 uses DefaultXmpPadding and a negative value disables
+	//padding. If the edited xmp fits in the existing packet its padding is reused instead
+	Padding int
+	//ReadOnly marks the packet as read only (end="r") so other tools will not edit it in place
+	ReadOnly bool
+}
+
+func (o XmpPacketOptions) padding() int {
+	switch {
+	case o.Padding == 0:
+		return DefaultXmpPadding
+	case o.Padding < 0:
+		return 0
+	default:
+		return o.Padding
+	}
+}
+
+func (o XmpPacketOptions) end() string {
+	if o.ReadOnly {
+		return xpacketEndReadOnly
+	}
+	return xpacketEndWritable
+}
+
+// xmpPacketSize returns the size of the xpacket wrapped packet at the start of data or 0 if data is not wrapped
+func xmpPacketSize(data []byte) int {
+	if !bytes.HasPrefix(data, []byte("<?xpacket begin=")) {
+		return 0
+	}
+	end := bytes.LastIndex(data, []byte("<?xpacket end="))
+	if end == -1 {
+		return 0
+	}
+	closing := bytes.Index(data[end:], []byte("?>"))
+	if closing == -1 {
+		return 0
+	}
+	return end + closing + 2
+}
+
+// xmpPadding returns padding bytes of whitespace with a newline every xpacketPaddingLine bytes
+func xmpPadding(padding int) []byte {
+	ret := bytes.Repeat([]byte{' '}, padding)
+	for i := 0; i < padding; i += xpacketPaddingLine {
+		ret[i] = '\n'
+	}
+	return ret
+}
+
+// wrapXmpPacket adds the xpacket wrapper to packet. It is padded to packetSize if the packet fits, otherwise with
+// the padding in opts. The wrapped packet is never longer than maxSize
+func wrapXmpPacket(packet []byte, opts XmpPacketOptions, packetSize, maxSize int) []byte {
+	end := opts.end()
+	size := len(xpacketBegin) + len(packet) + len(end)
+	padding := opts.padding()
+	if padding > 0 && packetSize >= size {
+		padding = packetSize - size
+	}
+	if padding > maxSize-size {
+		padding = maxSize - size
+	}
+	if padding < 0 {
+		padding = 0
+	}
+	ret := make([]byte, 0, size+padding)
+	ret = append(ret, xpacketBegin...)
+	ret = append(ret, packet...)
+	ret = append(ret, xmpPadding(padding)...)
+	return append(ret, end...)
+}
@@ -0,0 +1,75 @@
+package metadata
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWrapXmpPacket(t *testing.T) {
+	packet := []byte("<x:xmpmeta></x:xmpmeta>")
+	overhead := len(xpacketBegin) + len(packet) + len(xpacketEndWritable)
+	tests := []struct {
+		opts       XmpPacketOptions
+		packetSize int
+		maxSize    int
+		exp        int
+	}{
+		{XmpPacketOptions{}, 0, maxSegmentData, overhead + DefaultXmpPadding},
+		{XmpPacketOptions{Padding: 10}, 0, maxSegmentData, overhead + 10},
+		{XmpPacketOptions{Padding: -1}, 0, maxSegmentData, overhead},
+		{XmpPacketOptions{}, overhead + 100, maxSegmentData, overhead + 100},
+		{XmpPacketOptions{}, overhead - 1, maxSegmentData, overhead + DefaultXmpPadding},
+		{XmpPacketOptions{Padding: -1}, overhead + 100, maxSegmentData, overhead},
+		{XmpPacketOptions{}, 0, overhead + 5, overhead + 5},
+	}
+	for _, test := range tests {
+		got := wrapXmpPacket(packet, test.opts, test.packetSize, test.maxSize)
+		if len(got) != test.exp {
+			t.Errorf("Expected %v got %v", test.exp, len(got))
+		}
+		if xmpPacketSize(got) != len(got) {
+			t.Errorf("Expected %v got %v", len(got), xmpPacketSize(got))
+		}
+		if !bytes.Contains(got, packet) || !bytes.HasSuffix(got, []byte(xpacketEndWritable)) {
+			t.Errorf("Expected wrapped packet got %s", got)
+		}
+	}
+	if got := wrapXmpPacket(packet, XmpPacketOptions{ReadOnly: true}, 0, maxSegmentData); !bytes.HasSuffix(got, []byte(xpacketEndReadOnly)) {
+		t.Errorf("Expected %v got %s", xpacketEndReadOnly, got[len(got)-len(xpacketEndReadOnly):])
+	}
+	if got := xmpPacketSize(packet); got != 0 {
+		t.Errorf("Expected %v got %v", 0, got)
+	}
+}
+
+func TestJpegEditor_XmpPadding(t *testing.T) {
+	je := getJpegEditor(LeicaImg, t)
+	je.Xmp().SetTitle("title")
+	je = reloadJpegEditor(je, true, t)
+	_, s, _ := je.sl.FindXmp()
+	size := len(s.Data)
+	if !bytes.Contains(s.Data, xmpPadding(DefaultXmpPadding)) {
+		t.Errorf("Expected packet to be padded")
+	}
+	//a small edit should reuse the existing padding
+	je.Xmp().SetTitle("a slightly longer title")
+	je = reloadJpegEditor(je, true, t)
+	_, s, _ = je.sl.FindXmp()
+	if len(s.Data) != size {
+		t.Errorf("Expected %v got %v", size, len(s.Data))
+	}
+	if got := je.Xmp().GetTitle(); got != "a slightly longer title" {
+		t.Errorf("Expected %v got %v", "a slightly longer title", got)
+	}
+	je.Xmp().SetPacketOptions(XmpPacketOptions{Padding: -1, ReadOnly: true})
+	b, err := je.Bytes()
+	if err != nil {
+		t.Fatalf("Could not get bytes: %v", err)
+	}
+	if _, s, _ = je.sl.FindXmp(); len(s.Data) >= size-DefaultXmpPadding || !bytes.HasSuffix(s.Data, []byte(xpacketEndReadOnly)) {
+		t.Errorf("Expected unpadded read only packet")
+	}
+	if _, err = NewJpegEditor(b); err != nil {
+		t.Errorf("Could not parse image: %v", err)
+	}
+}
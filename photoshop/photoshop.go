@@ -168,35 +168,11 @@ func encodeImageResource(bw *bufio.Writer, r ImageResource) error {
 	return nil
 }
 
-// Decode photoshop image resources according to https://www.adobe.com/devnet-apps/photoshop/fileformatashtml/#50577409_pgfId-1037504
+// Decode photoshop image resources according to https://www.adobe.com/devnet-apps/photoshop/fileformatashtml/#50577409_pgfId-1037504.
+// If there are several resources with the same id the last one is kept. Use DecodeResources to keep all of them
 func Decode(r io.Reader, checkPrefix bool) (map[uint16]ImageResource, error) {
-	ret := map[uint16]ImageResource{}
-	var err error
-	br := bufio.NewReader(r)
-	if checkPrefix {
-		b := make([]byte, len(photoshopBlockPrefix))
-		if _, err = io.ReadFull(br, b); err != nil {
-			return ret, ErrNoPrefix
-		}
-		if photoshopBlockPrefix != string(b) {
-			return ret, ErrNoPrefix
-		}
-	}
-	for err == nil {
-		if ph, e := decodeImageResource(br); e != nil {
-			err = e
-		} else {
-			ret[ph.ResourceId] = ph
-		}
-	}
-	if err != io.EOF {
-		return ret, err
-	} else if len(ret) == 0 {
-		return ret, ErrNoData
-	} else {
-		return ret, nil
-	}
-
+	resources, err := DecodeResources(r, checkPrefix)
+	return resources.Map(), err
 }
 
 // Encode according to https://www.adobe.com/devnet-apps/photoshop/fileformatashtml/#50577409_pgfId-1037504
@@ -258,14 +234,6 @@ func Unmarshal(data []byte, hasPrefix bool, dest *map[uint16]ImageResource) erro
 
 // ParseJpeg reads a jpeg segement list and extract the photoshop resources (if they exist)
 func ParseJpeg(sl *jpegstructure.SegmentList) (int, map[uint16]ImageResource, error) {
-	ret := map[uint16]ImageResource{}
-	for idx, segment := range sl.Segments() {
-		//Try parse:
-		if err := Unmarshal(segment.Data, true, &ret); err == nil {
-			return idx, ret, nil
-		} else if err == ErrNoData {
-			return idx, ret, ErrNoData
-		}
-	}
-	return -1, ret, ErrNoPhotoshopBlock
+	idx, ret, err := ParseJpegResources(sl)
+	return idx, ret.Map(), err
 }
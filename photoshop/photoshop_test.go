@@ -10,6 +10,9 @@ import (
 const AssetPath = "../assets/"
 const LeicaData = AssetPath + "leicaPhotoshopResources.data"
 const LeicaDataWithPrefix = AssetPath + "leicaPhotoshopResourcesWithPrefix.data"
+
+// LeicaLargeData is LeicaData with 7 extra copies of the thumbnail so that it does not fit in one jpeg segment
+const LeicaLargeData = AssetPath + "leicaPhotoshopResourcesLarge.data"
 const Leica = AssetPath + "leica.jpg"
 const NoExif = AssetPath + "noexif.jpg"
 
@@ -0,0 +1,146 @@
+package photoshop
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	jpegstructure "github.com/dsoprea/go-jpeg-image-structure/v2"
+	"io"
+)
+
+// Resources is an ordered list of image resources. Unlike the map returned by Decode it keeps the
+// original order as well as resources with duplicate ids
+type Resources []ImageResource
+
+// Get returns the last resource with id (the same resource that Decode would return)
+func (r Resources) Get(id uint16) (ImageResource, bool) {
+	for i := len(r) - 1; i >= 0; i-- {
+		if r[i].ResourceId == id {
+			return r[i], true
+		}
+	}
+	return ImageResource{}, false
+}
+
+// GetAll returns all resources with id in order
+func (r Resources) GetAll(id uint16) []ImageResource {
+	var ret []ImageResource
+	for _, res := range r {
+		if res.ResourceId == id {
+			ret = append(ret, res)
+		}
+	}
+	return ret
+}
+
+// Set replaces all resources with the same id as res. The new resource is stored at the position
+// of the first replaced resource or last if there was none
+func (r *Resources) Set(res ImageResource) {
+	idx := -1
+	ret := (*r)[:0]
+	for _, old := range *r {
+		if old.ResourceId != res.ResourceId {
+			ret = append(ret, old)
+		} else if idx == -1 {
+			idx = len(ret)
+			ret = append(ret, res)
+		}
+	}
+	if idx == -1 {
+		ret = append(ret, res)
+	}
+	*r = ret
+}
+
+// Delete removes all resources with id
+func (r *Resources) Delete(id uint16) {
+	ret := (*r)[:0]
+	for _, old := range *r {
+		if old.ResourceId != id {
+			ret = append(ret, old)
+		}
+	}
+	*r = ret
+}
+
+// Map returns the resources by id. If there are duplicate ids the last resource is kept
+func (r Resources) Map() map[uint16]ImageResource {
+	ret := map[uint16]ImageResource{}
+	for _, res := range r {
+		ret[res.ResourceId] = res
+	}
+	return ret
+}
+
+// DecodeResources decodes photoshop image resources in the order they are stored
+func DecodeResources(r io.Reader, checkPrefix bool) (Resources, error) {
+	ret := Resources{}
+	var err error
+	br := bufio.NewReader(r)
+	if checkPrefix {
+		b := make([]byte, len(photoshopBlockPrefix))
+		if _, err = io.ReadFull(br, b); err != nil {
+			return ret, ErrNoPrefix
+		}
+		if photoshopBlockPrefix != string(b) {
+			return ret, ErrNoPrefix
+		}
+	}
+	for err == nil {
+		if ph, e := decodeImageResource(br); e != nil {
+			err = e
+		} else {
+			ret = append(ret, ph)
+		}
+	}
+	if err != io.EOF {
+		return ret, err
+	} else if len(ret) == 0 {
+		return ret, ErrNoData
+	}
+	return ret, nil
+}
+
+// EncodeResources writes photoshop image resources in order
+func EncodeResources(w io.Writer, resources Resources, addPrefix bool) error {
+	if len(resources) == 0 {
+		return fmt.Errorf("No photoshop resources to write")
+	}
+	bw := bufio.NewWriter(w)
+	if addPrefix {
+		if _, err := bw.WriteString(photoshopBlockPrefix); err != nil {
+			return err
+		}
+	}
+	for _, r := range resources {
+		if err := encodeImageResource(bw, r); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// MarshalResources writes photoshop image resources in order. If addPrefix adds the photoshop
+// block prefix ("Photoshop 3.0\000")
+func MarshalResources(resources Resources, addPrefix bool) ([]byte, error) {
+	out := &bytes.Buffer{}
+	if err := EncodeResources(out, resources, addPrefix); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// UnmarshalResources reads photoshop image resources in order
+func UnmarshalResources(data []byte, hasPrefix bool) (Resources, error) {
+	return DecodeResources(bytes.NewReader(data), hasPrefix)
+}
+
+// ParseJpegResources reads a jpeg segment list and extracts the photoshop resources in order. Returns the index
+// of the first segment the resources were read from (see ParseJpegSegments)
+func ParseJpegResources(sl *jpegstructure.SegmentList) (int, Resources, error) {
+	indexes, ret, err := ParseJpegSegments(sl)
+	if len(indexes) == 0 {
+		return -1, ret, err
+	}
+	return indexes[0], ret, err
+}
@@ -0,0 +1,213 @@
+package photoshop
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+func orderedResources() Resources {
+	return Resources{
+		NewPhotoshopImageResource(DigestId, []byte{1, 2, 3}),
+		NewPhotoshopImageResource(ResolutionInfoId, []byte{0, 0x48, 0, 0, 0, 1, 0, 1, 0, 0x48, 0, 0, 0, 1, 0, 1}),
+		{Signature: photoshopResourceSignature, ResourceId: PathInfoFirstId, Name: "Path 1", Data: []byte{}},
+		NewPhotoshopImageResource(IptcId, []byte{1}),
+		NewPhotoshopImageResource(IptcId, []byte{2}),
+	}
+}
+
+// pathRecord encodes a path record with selector followed by points (vertical, horizontal)
+func pathRecord(selector uint16, values ...float64) []byte {
+	rec := make([]byte, 26)
+	binary.BigEndian.PutUint16(rec, selector)
+	for i, v := range values {
+		binary.BigEndian.PutUint32(rec[2+i*4:], uint32(int32(v*(1<<24))))
+	}
+	return rec
+}
+
+func unicodeString(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	ret := binary.BigEndian.AppendUint32(nil, uint32(len(units)))
+	for _, u := range units {
+		ret = binary.BigEndian.AppendUint16(ret, u)
+	}
+	return ret
+}
+
+func TestResources_RoundTrip(t *testing.T) {
+	for prefix, data := range getTestData(t) {
+		resources, err := UnmarshalResources(data, prefix)
+		if err != nil {
+			t.Fatalf("Could not unmarshal resources: %v", err)
+		}
+		if out, err := MarshalResources(resources, prefix); err != nil {
+			t.Fatalf("Could not marshal resources: %v", err)
+		} else if !bytes.Equal(out, data) {
+			t.Errorf("Expected marshaled resources to equal original data")
+		}
+	}
+	expected := orderedResources()
+	b, err := MarshalResources(expected, true)
+	if err != nil {
+		t.Fatalf("Could not marshal resources: %v", err)
+	}
+	actual, err := UnmarshalResources(b, true)
+	if err != nil {
+		t.Fatalf("Could not unmarshal resources: %v", err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected %v got %v", expected, actual)
+	}
+	if m, _ := Decode(bytes.NewReader(b), true); len(m) != 4 || m[IptcId].Data[0] != 2 {
+		t.Errorf("Expected the last duplicate got %v", m[IptcId])
+	}
+	if _, err = MarshalResources(Resources{}, false); err == nil {
+		t.Errorf("Expected error when marshaling empty resources")
+	}
+}
+
+func TestResources_SetDelete(t *testing.T) {
+	resources := orderedResources()
+	if r, _ := resources.Get(IptcId); r.Data[0] != 2 {
+		t.Errorf("Expected %v got %v", 2, r.Data[0])
+	}
+	if len(resources.GetAll(IptcId)) != 2 {
+		t.Errorf("Expected %v got %v", 2, len(resources.GetAll(IptcId)))
+	}
+	resources.Set(NewPhotoshopImageResource(IptcId, []byte{3}))
+	if len(resources) != 4 || resources[3].ResourceId != IptcId || resources[3].Data[0] != 3 {
+		t.Errorf("Expected iptc to be replaced in place got %v", resources)
+	}
+	resources.Set(NewPhotoshopImageResource(CopyrightFlagId, []byte{1}))
+	if resources[4].ResourceId != CopyrightFlagId {
+		t.Errorf("Expected %v got %v", CopyrightFlagId, resources[4].ResourceId)
+	}
+	resources.Delete(DigestId)
+	if _, found := resources.Get(DigestId); found || resources[0].ResourceId != ResolutionInfoId {
+		t.Errorf("Expected digest to be deleted got %v", resources)
+	}
+}
+
+func TestResources_Leica(t *testing.T) {
+	resources, err := UnmarshalResources(getAssetBytes(LeicaData, t), false)
+	if err != nil {
+		t.Fatalf("Could not unmarshal resources: %v", err)
+	}
+	ri, err := resources.ResolutionInfo()
+	if err != nil {
+		t.Fatalf("Could not decode resolution info: %v", err)
+	}
+	if exp := (ResolutionInfo{144, PixelsPerInch, 1, 144, PixelsPerInch, 1}); ri != exp {
+		t.Errorf("Expected %v got %v", exp, ri)
+	}
+	thumb, err := resources.Thumbnail()
+	if err != nil {
+		t.Fatalf("Could not decode thumbnail: %v", err)
+	}
+	if thumb.Format != 1 || thumb.Width != 256 || thumb.Height != 171 || thumb.BGR {
+		t.Errorf("Unexpected thumbnail %v %vx%v", thumb.Format, thumb.Width, thumb.Height)
+	}
+	if !bytes.HasPrefix(thumb.Data, []byte{0xff, 0xd8}) {
+		t.Errorf("Expected jpeg thumbnail data")
+	}
+	if _, err = resources.CopyrightFlag(); err != ErrNoResource {
+		t.Errorf("Expected %v got %v", ErrNoResource, err)
+	}
+}
+
+func TestResources_Typed(t *testing.T) {
+	path := bytes.Join([][]byte{
+		pathRecord(6),
+		pathRecord(8, 0),
+		pathRecord(0, 0),
+		pathRecord(2, 0.25, 0.5, 0.25, 0.5, 0.25, 0.5),
+		pathRecord(1, 0.75, 0.5, 0.75, 0.5, 0.75, 0.5),
+	}, nil)
+	binary.BigEndian.PutUint16(path[2*26+2:], 2)
+	binary.BigEndian.PutUint16(path[26+2:], 1)
+	resources := Resources{
+		NewPhotoshopImageResource(CopyrightFlagId, []byte{1}),
+		NewPhotoshopImageResource(URLId, []byte("https://example.com\000")),
+		NewPhotoshopImageResource(JpegQualityId, []byte{0, 4, 1, 1, 0, 1, 0}),
+		{Signature: photoshopResourceSignature, ResourceId: PathInfoFirstId, Name: "Other", Data: path[:26]},
+		{Signature: photoshopResourceSignature, ResourceId: PathInfoFirstId + 1, Name: "Product", Data: path},
+		NewPhotoshopImageResource(ClippingPathNameId, append([]byte("\007Product"), 0, 0)),
+	}
+	if flag, err := resources.CopyrightFlag(); err != nil || !flag {
+		t.Errorf("Expected copyright flag got %v %v", flag, err)
+	}
+	if url, err := resources.URL(); err != nil || url != "https://example.com" {
+		t.Errorf("Expected %v got %v", "https://example.com", url)
+	}
+	if q, err := resources.JpegQuality(); err != nil || q != (JpegQuality{8, JpegProgressive, 3}) {
+		t.Errorf("Expected %v got %v %v", JpegQuality{8, JpegProgressive, 3}, q, err)
+	}
+	if paths, err := resources.Paths(); err != nil || len(paths) != 2 {
+		t.Fatalf("Expected 2 paths got %v %v", paths, err)
+	}
+	cp, err := resources.ClippingPath()
+	if err != nil {
+		t.Fatalf("Could not get clipping path: %v", err)
+	}
+	if cp.Name != "Product" || cp.Id != PathInfoFirstId+1 || !cp.FillAllPixels || len(cp.Subpaths) != 1 {
+		t.Fatalf("Unexpected clipping path %v", cp)
+	}
+	sp := cp.Subpaths[0]
+	if !sp.Closed || len(sp.Knots) != 2 || sp.Knots[0].Linked || !sp.Knots[1].Linked {
+		t.Fatalf("Unexpected subpath %v", sp)
+	}
+	if exp := (Point{X: 0.5, Y: 0.75}); sp.Knots[1].Anchor != exp {
+		t.Errorf("Expected %v got %v", exp, sp.Knots[1].Anchor)
+	}
+	if _, err = DecodePath(path[:30]); err != ErrResourceSize {
+		t.Errorf("Expected %v got %v", ErrResourceSize, err)
+	}
+}
+
+func TestDecodeSlices(t *testing.T) {
+	be := binary.BigEndian
+	data := be.AppendUint32(nil, 6)
+	for _, v := range []uint32{0, 0, 100, 200} {
+		data = be.AppendUint32(data, v)
+	}
+	data = append(data, unicodeString("shot")...)
+	data = be.AppendUint32(data, 1)
+	for _, v := range []uint32{1, 0, 1, 7} { //id, group, origin (layer) and layer id
+		data = be.AppendUint32(data, v)
+	}
+	data = append(data, unicodeString("slice")...)
+	data = be.AppendUint32(data, 1)
+	for _, v := range []uint32{10, 20, 30, 40} {
+		data = be.AppendUint32(data, v)
+	}
+	for _, s := range []string{"https://example.com", "_blank", "", ""} {
+		data = append(data, unicodeString(s)...)
+	}
+	data = append(data, 0)
+	data = append(data, unicodeString("")...)
+	data = be.AppendUint32(data, 0)
+	data = be.AppendUint32(data, 0)
+	data = append(data, 255, 1, 2, 3)
+
+	slices, err := DecodeSlices(data)
+	if err != nil {
+		t.Fatalf("Could not decode slices: %v", err)
+	}
+	if slices.Name != "shot" || slices.Bounds != (Rect{0, 0, 100, 200}) || len(slices.Slices) != 1 {
+		t.Fatalf("Unexpected slices %v", slices)
+	}
+	s := slices.Slices[0]
+	if s.LayerId != 7 || s.Name != "slice" || s.Bounds != (Rect{Left: 10, Top: 20, Right: 30, Bottom: 40}) ||
+		s.URL != "https://example.com" || s.Target != "_blank" || s.Color != [4]byte{255, 1, 2, 3} {
+		t.Errorf("Unexpected slice %v", s)
+	}
+	if _, err = DecodeSlices(data[:len(data)-2]); err == nil {
+		t.Errorf("Expected error on truncated slices")
+	}
+	if _, err = DecodeSlices(be.AppendUint32(nil, 8)); err != ErrUnsupportedVersion {
+		t.Errorf("Expected %v got %v", ErrUnsupportedVersion, err)
+	}
+}
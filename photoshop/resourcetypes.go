@@ -0,0 +1,473 @@
+package photoshop
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unicode/utf16"
+)
+
+/*
+Typed decoding of common image resources. Layouts are described in
+https://www.adobe.com/devnet-apps/photoshop/fileformatashtml/#50577409_38034
+*/
+
+const (
+	//ResolutionInfoId id for ResolutionInfo resource
+	ResolutionInfoId uint16 = 0x03ED
+	//JpegQualityId id for the (private) jpeg quality resource
+	JpegQualityId uint16 = 0x0406
+	//ThumbnailPS4Id id for Photoshop 4.0 thumbnail resource (BGR)
+	ThumbnailPS4Id uint16 = 0x0409
+	//CopyrightFlagId id for copyright flag resource
+	CopyrightFlagId uint16 = 0x040A
+	//URLId id for URL resource
+	URLId uint16 = 0x040B
+	//ThumbnailId id for thumbnail resource
+	ThumbnailId uint16 = 0x040C
+	//SlicesId id for slices resource
+	SlicesId uint16 = 0x041A
+	//PathInfoFirstId first id of path information resources
+	PathInfoFirstId uint16 = 0x07D0
+	//PathInfoLastId last id of path information resources
+	PathInfoLastId uint16 = 0x0BB6
+	//ClippingPathNameId id for the name of the clipping path
+	ClippingPathNameId uint16 = 0x0BB7
+)
+
+// Resolution units
+const (
+	PixelsPerInch uint16 = 1
+	PixelsPerCm   uint16 = 2
+)
+
+// Jpeg formats
+const (
+	JpegStandard    uint16 = 0x0000
+	JpegOptimized   uint16 = 0x0001
+	JpegProgressive uint16 = 0x0101
+)
+
+// ErrNoResource the resource was not found
+var ErrNoResource = fmt.Errorf("Resource not found")
+
+// ErrResourceSize the resource data is too short or has the wrong length
+var ErrResourceSize = fmt.Errorf("Resource has wrong data size")
+
+// ErrUnsupportedVersion the resource data has a version that can not be decoded
+var ErrUnsupportedVersion = fmt.Errorf("Unsupported resource version")
+
+// ResolutionInfo holds horizontal and vertical resolution
+type ResolutionInfo struct {
+	//HRes horizontal resolution in HResUnit
+	HRes float64
+	//HResUnit PixelsPerInch or PixelsPerCm
+	HResUnit uint16
+	//WidthUnit display unit. 1=inches, 2=cm, 3=points, 4=picas, 5=columns
+	WidthUnit uint16
+	//VRes vertical resolution in VResUnit
+	VRes float64
+	//VResUnit PixelsPerInch or PixelsPerCm
+	VResUnit uint16
+	//HeightUnit display unit. 1=inches, 2=cm, 3=points, 4=picas, 5=columns
+	HeightUnit uint16
+}
+
+// JpegQuality holds the save for web settings of a jpeg saved by photoshop
+type JpegQuality struct {
+	//Quality between 0 and 12
+	Quality int
+	//Format JpegStandard, JpegOptimized or JpegProgressive
+	Format uint16
+	//Scans number of progressive scans (3 to 5)
+	Scans int
+}
+
+// Thumbnail holds a thumbnail resource
+type Thumbnail struct {
+	//Format 1 = jpeg, 0 = raw rgb
+	Format uint32
+	Width  uint32
+	Height uint32
+	//BitsPerPixel is normally 24
+	BitsPerPixel uint16
+	//BGR is true for Photoshop 4.0 thumbnails that store colors in BGR order
+	BGR bool
+	//Data is the (jpeg) image data
+	Data []byte
+}
+
+// Rect holds the bounds of a slice
+type Rect struct {
+	Top    int32
+	Left   int32
+	Bottom int32
+	Right  int32
+}
+
+// Slice is a single slice in a Slices resource
+type Slice struct {
+	Id             int32
+	GroupId        int32
+	Origin         int32
+	LayerId        int32
+	Name           string
+	Type           int32
+	Bounds         Rect
+	URL            string
+	Target         string
+	Message        string
+	AltTag         string
+	CellTextIsHTML bool
+	CellText       string
+	HAlign         int32
+	VAlign         int32
+	//Color is alpha, red, green and blue
+	Color [4]byte
+}
+
+// Slices holds the slices resource
+type Slices struct {
+	Version int32
+	Bounds  Rect
+	Name    string
+	Slices  []Slice
+}
+
+// Point is a path coordinate relative to the image width (X) and height (Y)
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Knot is a bezier knot of a path
+type Knot struct {
+	Linked    bool
+	Preceding Point
+	Anchor    Point
+	Leaving   Point
+}
+
+// Subpath holds the knots of a closed or open sub path
+type Subpath struct {
+	Closed bool
+	Knots  []Knot
+}
+
+// Path is a decoded path information resource
+type Path struct {
+	Id   uint16
+	Name string
+	//FillAllPixels is true if the fill starts with all pixels
+	FillAllPixels bool
+	Subpaths      []Subpath
+}
+
+// resourceReader reads big endian values and keeps the first error
+type resourceReader struct {
+	r   *bytes.Reader
+	err error
+}
+
+func newResourceReader(data []byte) *resourceReader {
+	return &resourceReader{r: bytes.NewReader(data)}
+}
+
+func (rr *resourceReader) read(v interface{}) {
+	if rr.err == nil {
+		rr.err = binary.Read(rr.r, defaultEncoding, v)
+	}
+}
+
+func (rr *resourceReader) int32() int32 {
+	v := int32(0)
+	rr.read(&v)
+	return v
+}
+
+func (rr *resourceReader) rect() Rect {
+	r := Rect{}
+	rr.read(&r)
+	return r
+}
+
+// unicode reads a 4 byte length (in code units) followed by UTF-16 data
+func (rr *resourceReader) unicode() string {
+	n := uint32(0)
+	rr.read(&n)
+	if rr.err != nil {
+		return ""
+	}
+	if int64(n)*2 > int64(rr.r.Len()) {
+		rr.err = io.ErrUnexpectedEOF
+		return ""
+	}
+	units := make([]uint16, n)
+	rr.read(units)
+	for len(units) > 0 && units[len(units)-1] == 0 {
+		units = units[:len(units)-1]
+	}
+	return string(utf16.Decode(units))
+}
+
+// fixed16 converts a 16.16 fixed point number
+func fixed16(v uint32) float64 {
+	return float64(v) / (1 << 16)
+}
+
+// fixed24 converts a signed 8.24 fixed point number
+func fixed24(v uint32) float64 {
+	return float64(int32(v)) / (1 << 24)
+}
+
+// DecodeResolutionInfo decodes a ResolutionInfoId resource
+func DecodeResolutionInfo(data []byte) (ResolutionInfo, error) {
+	if len(data) < 16 {
+		return ResolutionInfo{}, ErrResourceSize
+	}
+	return ResolutionInfo{
+		HRes:       fixed16(defaultEncoding.Uint32(data)),
+		HResUnit:   defaultEncoding.Uint16(data[4:]),
+		WidthUnit:  defaultEncoding.Uint16(data[6:]),
+		VRes:       fixed16(defaultEncoding.Uint32(data[8:])),
+		VResUnit:   defaultEncoding.Uint16(data[12:]),
+		HeightUnit: defaultEncoding.Uint16(data[14:]),
+	}, nil
+}
+
+// DecodeCopyrightFlag decodes a CopyrightFlagId resource
+func DecodeCopyrightFlag(data []byte) (bool, error) {
+	if len(data) < 1 {
+		return false, ErrResourceSize
+	}
+	return data[0] != 0, nil
+}
+
+// DecodeURL decodes a URLId resource
+func DecodeURL(data []byte) string {
+	return string(bytes.TrimRight(data, "\000"))
+}
+
+// DecodeJpegQuality decodes a JpegQualityId resource
+func DecodeJpegQuality(data []byte) (JpegQuality, error) {
+	if len(data) < 6 {
+		return JpegQuality{}, ErrResourceSize
+	}
+	ret := JpegQuality{
+		Quality: int(int16(defaultEncoding.Uint16(data))) + 4,
+		Format:  defaultEncoding.Uint16(data[2:]),
+	}
+	if ret.Format == JpegProgressive {
+		ret.Scans = int(int16(defaultEncoding.Uint16(data[4:]))) + 2
+	}
+	return ret, nil
+}
+
+// DecodeThumbnail decodes a ThumbnailId or ThumbnailPS4Id resource
+func DecodeThumbnail(data []byte) (Thumbnail, error) {
+	const headerSize = 28
+	if len(data) < headerSize {
+		return Thumbnail{}, ErrResourceSize
+	}
+	ret := Thumbnail{
+		Format:       defaultEncoding.Uint32(data),
+		Width:        defaultEncoding.Uint32(data[4:]),
+		Height:       defaultEncoding.Uint32(data[8:]),
+		BitsPerPixel: defaultEncoding.Uint16(data[24:]),
+	}
+	size := int(defaultEncoding.Uint32(data[20:]))
+	if ret.Format != 1 {
+		size = int(defaultEncoding.Uint32(data[16:]))
+	}
+	if size > len(data)-headerSize {
+		return ret, ErrResourceSize
+	}
+	ret.Data = data[headerSize : headerSize+size]
+	return ret, nil
+}
+
+// DecodeSlices decodes a SlicesId resource. Only version 6 (Photoshop 6.0) is supported. Later versions
+// return ErrUnsupportedVersion
+func DecodeSlices(data []byte) (Slices, error) {
+	rr := newResourceReader(data)
+	ret := Slices{Version: rr.int32()}
+	if rr.err != nil {
+		return ret, ErrResourceSize
+	}
+	if ret.Version != 6 {
+		return ret, ErrUnsupportedVersion
+	}
+	ret.Bounds = rr.rect()
+	ret.Name = rr.unicode()
+	n := rr.int32()
+	for i := int32(0); i < n && rr.err == nil; i++ {
+		s := Slice{Id: rr.int32(), GroupId: rr.int32(), Origin: rr.int32()}
+		if s.Origin == 1 {
+			s.LayerId = rr.int32()
+		}
+		s.Name = rr.unicode()
+		s.Type = rr.int32()
+		s.Bounds.Left, s.Bounds.Top, s.Bounds.Right, s.Bounds.Bottom = rr.int32(), rr.int32(), rr.int32(), rr.int32()
+		s.URL = rr.unicode()
+		s.Target = rr.unicode()
+		s.Message = rr.unicode()
+		s.AltTag = rr.unicode()
+		html := uint8(0)
+		rr.read(&html)
+		s.CellTextIsHTML = html != 0
+		s.CellText = rr.unicode()
+		s.HAlign = rr.int32()
+		s.VAlign = rr.int32()
+		rr.read(&s.Color)
+		if rr.err == nil {
+			ret.Slices = append(ret.Slices, s)
+		}
+	}
+	if rr.err != nil {
+		return ret, fmt.Errorf("Could not decode slices: %v", rr.err)
+	}
+	return ret, nil
+}
+
+// DecodePath decodes a path information resource (PathInfoFirstId to PathInfoLastId)
+func DecodePath(data []byte) (Path, error) {
+	const recordSize = 26
+	ret := Path{}
+	if len(data)%recordSize != 0 {
+		return ret, ErrResourceSize
+	}
+	point := func(b []byte) Point {
+		return Point{Y: fixed24(defaultEncoding.Uint32(b)), X: fixed24(defaultEncoding.Uint32(b[4:]))}
+	}
+	for i := 0; i < len(data); i += recordSize {
+		rec := data[i : i+recordSize]
+		switch selector := defaultEncoding.Uint16(rec); selector {
+		case 0, 3: //closed and open subpath length records
+			ret.Subpaths = append(ret.Subpaths, Subpath{Closed: selector == 0})
+		case 1, 2, 4, 5: //closed and open bezier knots
+			if len(ret.Subpaths) == 0 {
+				return ret, fmt.Errorf("Path knot without subpath at record %d", i/recordSize)
+			}
+			sp := &ret.Subpaths[len(ret.Subpaths)-1]
+			sp.Knots = append(sp.Knots, Knot{Linked: selector == 1 || selector == 4,
+				Preceding: point(rec[2:]), Anchor: point(rec[10:]), Leaving: point(rec[18:])})
+		case 8: //initial fill rule record
+			ret.FillAllPixels = defaultEncoding.Uint16(rec[2:]) == 1
+		case 6, 7: //path fill rule and clipboard records
+		default:
+			return ret, fmt.Errorf("Unknown path record selector %d", selector)
+		}
+	}
+	return ret, nil
+}
+
+// DecodeClippingPathName decodes a ClippingPathNameId resource (a pascal string)
+func DecodeClippingPathName(data []byte) (string, error) {
+	if len(data) < 1 || int(data[0]) > len(data)-1 {
+		return "", ErrResourceSize
+	}
+	return string(data[1 : 1+data[0]]), nil
+}
+
+func (r Resources) data(id uint16) ([]byte, error) {
+	if res, found := r.Get(id); found {
+		return res.Data, nil
+	}
+	return nil, ErrNoResource
+}
+
+// ResolutionInfo returns the decoded ResolutionInfoId resource
+func (r Resources) ResolutionInfo() (ResolutionInfo, error) {
+	data, err := r.data(ResolutionInfoId)
+	if err != nil {
+		return ResolutionInfo{}, err
+	}
+	return DecodeResolutionInfo(data)
+}
+
+// CopyrightFlag returns true if the image is marked as copyrighted
+func (r Resources) CopyrightFlag() (bool, error) {
+	data, err := r.data(CopyrightFlagId)
+	if err != nil {
+		return false, err
+	}
+	return DecodeCopyrightFlag(data)
+}
+
+// URL returns the URLId resource
+func (r Resources) URL() (string, error) {
+	data, err := r.data(URLId)
+	return DecodeURL(data), err
+}
+
+// JpegQuality returns the decoded JpegQualityId resource
+func (r Resources) JpegQuality() (JpegQuality, error) {
+	data, err := r.data(JpegQualityId)
+	if err != nil {
+		return JpegQuality{}, err
+	}
+	return DecodeJpegQuality(data)
+}
+
+// Thumbnail returns the decoded ThumbnailId resource or ThumbnailPS4Id if there is none
+func (r Resources) Thumbnail() (Thumbnail, error) {
+	if data, err := r.data(ThumbnailId); err == nil {
+		return DecodeThumbnail(data)
+	}
+	data, err := r.data(ThumbnailPS4Id)
+	if err != nil {
+		return Thumbnail{}, err
+	}
+	ret, err := DecodeThumbnail(data)
+	ret.BGR = true
+	return ret, err
+}
+
+// Slices returns the decoded SlicesId resource
+func (r Resources) Slices() (Slices, error) {
+	data, err := r.data(SlicesId)
+	if err != nil {
+		return Slices{}, err
+	}
+	return DecodeSlices(data)
+}
+
+// Paths returns all decoded path information resources in order
+func (r Resources) Paths() ([]Path, error) {
+	var ret []Path
+	for _, res := range r {
+		if res.ResourceId < PathInfoFirstId || res.ResourceId > PathInfoLastId {
+			continue
+		}
+		p, err := DecodePath(res.Data)
+		if err != nil {
+			return ret, fmt.Errorf("Could not decode path %#04x: %v", res.ResourceId, err)
+		}
+		p.Id, p.Name = res.ResourceId, res.Name
+		ret = append(ret, p)
+	}
+	return ret, nil
+}
+
+// ClippingPath returns the path named by the ClippingPathNameId resource
+func (r Resources) ClippingPath() (Path, error) {
+	data, err := r.data(ClippingPathNameId)
+	if err != nil {
+		return Path{}, err
+	}
+	name, err := DecodeClippingPathName(data)
+	if err != nil {
+		return Path{}, err
+	}
+	paths, err := r.Paths()
+	if err != nil {
+		return Path{}, err
+	}
+	for _, p := range paths {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Path{}, ErrNoResource
+}
@@ -0,0 +1,96 @@
+package photoshop
+
+import (
+	"bytes"
+	jpegstructure "github.com/dsoprea/go-jpeg-image-structure/v2"
+)
+
+// MaxSegmentSize is the maximum size of the data in a jpeg segment (the segment length is 2 bytes and includes itself)
+const MaxSegmentSize = 0xffff - 2
+
+// IsPhotoshopBlock returns true if data starts with the photoshop block prefix ("Photoshop 3.0\000")
+func IsPhotoshopBlock(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(photoshopBlockPrefix))
+}
+
+// JoinSegments concatenates the image resource data of photoshop blocks that have been split over several
+// APP13 segments. Each block has to start with the photoshop block prefix. The returned data has no prefix
+func JoinSegments(blocks ...[]byte) ([]byte, error) {
+	ret := []byte{}
+	for _, b := range blocks {
+		if !IsPhotoshopBlock(b) {
+			return nil, ErrNoPrefix
+		}
+		ret = append(ret, b[len(photoshopBlockPrefix):]...)
+	}
+	return ret, nil
+}
+
+// ParseJpegSegments reads a jpeg segment list and extracts the photoshop resources in order. Image resources
+// that are larger than a jpeg segment are stored in several consecutive APP13 segments that are reassembled.
+// Returns the indexes of all segments the resources were read from
+func ParseJpegSegments(sl *jpegstructure.SegmentList) ([]int, Resources, error) {
+	segments := sl.Segments()
+	isBlock := func(idx int) bool {
+		return segments[idx].MarkerId == jpegstructure.MARKER_APP13 && IsPhotoshopBlock(segments[idx].Data)
+	}
+	for idx := 0; idx < len(segments); idx++ {
+		if !isBlock(idx) {
+			continue
+		}
+		indexes := []int{idx}
+		blocks := [][]byte{segments[idx].Data}
+		for idx+1 < len(segments) && isBlock(idx+1) {
+			idx++
+			indexes = append(indexes, idx)
+			blocks = append(blocks, segments[idx].Data)
+		}
+		data, _ := JoinSegments(blocks...)
+		if ret, err := UnmarshalResources(data, false); err == nil {
+			return indexes, ret, nil
+		} else if err == ErrNoData {
+			return indexes, ret, ErrNoData
+		}
+	}
+	return nil, Resources{}, ErrNoPhotoshopBlock
+}
+
+// MarshalSegments writes resources as one or more photoshop blocks that each fit in a jpeg APP13 segment
+// (see MaxSegmentSize). Blocks are split between resources unless a single resource is too large
+func MarshalSegments(resources Resources) ([][]byte, error) {
+	return marshalSegments(resources, MaxSegmentSize)
+}
+
+func marshalSegments(resources Resources, segmentSize int) ([][]byte, error) {
+	if len(resources) == 0 {
+		return nil, ErrNoData
+	}
+	size := segmentSize - len(photoshopBlockPrefix)
+	ret := [][]byte{}
+	block := []byte{}
+	flush := func(b []byte) {
+		ret = append(ret, append([]byte(photoshopBlockPrefix), b...))
+	}
+	for _, r := range resources {
+		b, err := MarshalResources(Resources{r}, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(block)+len(b) <= size {
+			block = append(block, b...)
+			continue
+		}
+		if len(block) > 0 {
+			flush(block)
+		}
+		for len(b) > size {
+			flush(b[:size])
+			b = b[size:]
+		}
+		block = b
+	}
+	if len(block) > 0 {
+		flush(block)
+	}
+	return ret, nil
+}
@@ -0,0 +1,99 @@
+package photoshop
+
+import (
+	"bytes"
+	jpegstructure "github.com/dsoprea/go-jpeg-image-structure/v2"
+	"reflect"
+	"testing"
+)
+
+// largeResources returns the leica resources that do not fit in one segment (see LeicaLargeData)
+func largeResources(t *testing.T) Resources {
+	resources, err := UnmarshalResources(getAssetBytes(LeicaLargeData, t), false)
+	if err != nil {
+		t.Fatalf("Could not unmarshal resources: %v", err)
+	}
+	return resources
+}
+
+func TestMarshalSegments(t *testing.T) {
+	data := getAssetBytes(LeicaData, t)
+	resources, _ := UnmarshalResources(data, false)
+	blocks, err := marshalSegments(resources, 4096)
+	if err != nil {
+		t.Fatalf("Could not marshal segments: %v", err)
+	}
+	if len(blocks) != 4 {
+		t.Errorf("Expected %v got %v", 4, len(blocks))
+	}
+	for _, b := range blocks {
+		if len(b) > 4096 || !IsPhotoshopBlock(b) {
+			t.Errorf("Expected photoshop block of at most 4096 bytes got %v", len(b))
+		}
+	}
+	//resources that fit are not split
+	if first, err := UnmarshalResources(blocks[0], true); err != nil || len(first) != 2 {
+		t.Errorf("Expected resolution and iptc in first block got %v %v", first, err)
+	}
+	if joined, err := JoinSegments(blocks...); err != nil || !bytes.Equal(joined, data) {
+		t.Errorf("Expected joined segments to equal original data got %v", err)
+	}
+	if _, err = JoinSegments(blocks[0], data); err != ErrNoPrefix {
+		t.Errorf("Expected %v got %v", ErrNoPrefix, err)
+	}
+	if blocks, err = MarshalSegments(resources); err != nil || len(blocks) != 1 {
+		t.Errorf("Expected 1 block got %v %v", len(blocks), err)
+	}
+	if blocks, err = MarshalSegments(largeResources(t)); err != nil || len(blocks) != 2 {
+		t.Fatalf("Expected 2 blocks got %v %v", len(blocks), err)
+	}
+	for _, b := range blocks {
+		if len(b) > MaxSegmentSize {
+			t.Errorf("Expected at most %v bytes got %v", MaxSegmentSize, len(b))
+		}
+	}
+	if _, err = MarshalSegments(Resources{}); err == nil {
+		t.Errorf("Expected error when marshaling empty resources")
+	}
+}
+
+func TestParseJpegSegments(t *testing.T) {
+	sl := getSegments(Leica, t)
+	indexes, resources, err := ParseJpegSegments(sl)
+	if err != nil || len(indexes) != 1 {
+		t.Fatalf("Expected one photoshop segment got %v %v", indexes, err)
+	}
+	checkExpectedResources(resources.Map(), t)
+
+	//replace with split segments
+	expected := largeResources(t)
+	blocks, err := MarshalSegments(expected)
+	if err != nil {
+		t.Fatalf("Could not marshal segments: %v", err)
+	}
+	segments := append([]*jpegstructure.Segment{}, sl.Segments()[:indexes[0]]...)
+	for _, b := range blocks {
+		segments = append(segments, &jpegstructure.Segment{MarkerId: jpegstructure.MARKER_APP13, Data: b})
+	}
+	segments = append(segments, sl.Segments()[indexes[0]+1:]...)
+	sl = jpegstructure.NewSegmentList(segments)
+
+	indexes, resources, err = ParseJpegSegments(sl)
+	if err != nil {
+		t.Fatalf("Could not parse segments: %v", err)
+	}
+	if len(indexes) != len(blocks) || indexes[1] != indexes[0]+1 {
+		t.Errorf("Expected %v consecutive segments got %v", len(blocks), indexes)
+	}
+	if !reflect.DeepEqual(expected, resources) {
+		t.Errorf("Expected reassembled resources to equal original resources")
+	}
+	if idx, m, err := ParseJpeg(sl); err != nil || idx != indexes[0] {
+		t.Errorf("Expected %v got %v %v", indexes[0], idx, err)
+	} else {
+		checkExpectedResources(m, t)
+	}
+	if _, _, err = ParseJpegSegments(getSegments(NoExif, t)); err != ErrNoPhotoshopBlock {
+		t.Errorf("Expected ErrNoPhotoshopBlock got %v", err)
+	}
+}